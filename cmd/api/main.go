@@ -1,6 +1,8 @@
 package main
 
 import (
+	"log"
+	"os"
 	"titanic-api/internal"
 )
 
@@ -10,5 +12,13 @@ import (
 // @contact.name    Eli Bracha
 // @BasePath       /api/v1
 func main() {
+	// evaluate scores the survival classifiers instead of serving the api
+	if len(os.Args) > 1 && os.Args[1] == "evaluate" {
+		if err := internal.NewEvaluateCommand().Run(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	internal.NewServer().Start()
 }
@@ -12,6 +12,7 @@ type Config struct {
 	storeType       string
 	storePath	string
 	port            int
+	modelPath       string
 }
 
 func (c *Config) GetStoreType() string {
@@ -26,6 +27,10 @@ func (c *Config) GetPort() int {
 	return c.port
 }
 
+func (c *Config) GetModelPath() string {
+	return c.modelPath
+}
+
 func (c *Config) getEnv(key string) (string, error) {
 	value, exists := os.LookupEnv(key)
 	if !exists {
@@ -45,6 +50,7 @@ func NewConfig() *Config {
 		log.Fatal("invalid port number provided in API_PORT")
 	}
 
+	viper.SetDefault("api.model.path", "./data/model/survival.json")
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
@@ -73,6 +79,7 @@ func NewConfig() *Config {
 	config.port = port
 	config.storeType = storeType
 	config.storePath = storePath
+	config.modelPath = viper.GetString("api.model.path")
 
 	return &config
 }
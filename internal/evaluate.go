@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"titanic-api/internal/passenger"
+	"titanic-api/internal/prediction"
+)
+
+// Command is a command line subcommand run instead of the server.
+type Command interface {
+	Run(args []string) error
+}
+
+type evaluateCommand struct {
+	out io.Writer
+}
+
+// Run evaluates the survival classifiers, every classifier is compared
+// unless one is selected.
+func (c *evaluateCommand) Run(args []string) error {
+	flags := flag.NewFlagSet("evaluate", flag.ContinueOnError)
+	storeType := flags.String("store", passenger.StoreTypeCSV, "store type, CSV or SQLITE")
+	storePath := flags.String("path", "./data/csv/titanic.csv", "store path")
+	classifier := flags.String("classifier", "", "classifier evaluated, all classifiers when empty")
+	method := flags.String("method", string(prediction.MethodStratified), "split, kfold or stratified")
+	folds := flags.Int("folds", prediction.DefaultFolds, "number of folds for kfold and stratified")
+	testSize := flags.Float64("test-size", prediction.DefaultTestSize, "held out fraction for split")
+	seed := flags.Int64("seed", prediction.DefaultSeed, "shuffling seed")
+	asJSON := flags.Bool("json", false, "print the full reports as json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := newStore(*storeType, *storePath)
+	if err != nil {
+		return err
+	}
+	service := prediction.NewService(store, "")
+
+	names := prediction.ClassifierNames()
+	if len(*classifier) > 0 {
+		names = []string{*classifier}
+	}
+	var reports []*prediction.Report
+	for _, name := range names {
+		e, err := prediction.NewEvaluation(name, prediction.Method(*method), *folds, *testSize, *seed)
+		if err != nil {
+			return err
+		}
+		report, err := service.Evaluate(context.Background(), e)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+
+	// best classifier first
+	sort.SliceStable(reports, func(i, j int) bool {
+		return value(reports[i].Metrics.AUC) > value(reports[j].Metrics.AUC)
+	})
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "classifier\taccuracy\tprecision\trecall\tf1\tauc")
+	for _, rs := range reports {
+		m := rs.Metrics
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", rs.Classifier, format(m.Accuracy), format(m.Precision),
+			format(m.Recall), format(m.F1), format(m.AUC))
+	}
+	return w.Flush()
+}
+
+func format(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*v, 'f', 4, 64)
+}
+
+func value(v *float64) float64 {
+	if v == nil {
+		return -1
+	}
+	return *v
+}
+
+func NewEvaluateCommand() Command {
+	return &evaluateCommand{out: os.Stdout}
+}
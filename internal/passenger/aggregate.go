@@ -0,0 +1,271 @@
+package passenger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Function string
+
+const (
+	FunctionCount Function = "count"
+	FunctionSum   Function = "sum"
+	FunctionAvg   Function = "avg"
+	FunctionMean  Function = "mean"
+	FunctionMin   Function = "min"
+	FunctionMax   Function = "max"
+)
+
+var functions = map[Function]string{
+	FunctionCount: "COUNT",
+	FunctionSum:   "SUM",
+	FunctionAvg:   "AVG",
+	FunctionMean:  "AVG",
+	FunctionMin:   "MIN",
+	FunctionMax:   "MAX",
+}
+
+// Metric is an aggregate function over a passenger field. A count without a
+// field counts passengers, any other metric skips missing values.
+type Metric struct {
+	Function Function
+	Field    string
+}
+
+// Aggregation groups the passengers matching Filter by the GroupBy fields and
+// computes Metrics for every group.
+type Aggregation struct {
+	Filter  Filter
+	GroupBy []string
+	Metrics []Metric
+}
+
+// Group is a single aggregation row, Keys follow GroupBy and Values follow
+// Metrics. Values are nil when the group has no value to aggregate.
+type Group struct {
+	Keys   []interface{}
+	Values []*float64
+}
+
+// NewMetric parses a metric expression such as count or avg(fare).
+func NewMetric(expr string) (Metric, error) {
+	name, arg, found := strings.Cut(expr, "(")
+	name = strings.TrimSpace(name)
+	if found {
+		if !strings.HasSuffix(arg, ")") {
+			return Metric{}, fmt.Errorf("invalid metric provided '%s' in query", expr)
+		}
+		arg = strings.TrimSpace(strings.TrimSuffix(arg, ")"))
+	}
+
+	m := Metric{Function: Function(name), Field: arg}
+	if _, valid := functions[m.Function]; !valid {
+		return Metric{}, fmt.Errorf("unknown metric function provided '%s' in query", name)
+	}
+	if len(m.Field) == 0 {
+		if m.Function != FunctionCount {
+			return Metric{}, fmt.Errorf("metric function '%s' requires a field in query", name)
+		}
+		return m, nil
+	}
+
+	f, found := fields[m.Field]
+	switch {
+	case !found:
+		return Metric{}, fmt.Errorf("unknown metric field provided '%s' in query", m.Field)
+	case f.number == nil && m.Function != FunctionCount:
+		return Metric{}, fmt.Errorf("metric function '%s' requires a numeric field, '%s' is not", name, m.Field)
+	}
+	return m, nil
+}
+
+func (m Metric) String() string {
+	if len(m.Field) == 0 {
+		return string(m.Function)
+	}
+	return fmt.Sprintf("%s(%s)", m.Function, m.Field)
+}
+
+// SQL returns the aggregate expression computing the metric.
+func (m Metric) SQL() string {
+	if len(m.Field) == 0 {
+		return "COUNT(*)"
+	}
+	// missing text values are empty strings rather than NULL
+	if f := fields[m.Field]; f.text != nil {
+		return fmt.Sprintf("%s(NULLIF(%s, ''))", functions[m.Function], f.sqlExpr())
+	}
+	return fmt.Sprintf("%s(%s)", functions[m.Function], fields[m.Field].sqlValue())
+}
+
+func NewAggregation(filter Filter, groupBy []string, metrics []string) (Aggregation, error) {
+	a := Aggregation{Filter: filter}
+	if err := validateGroupBy(groupBy); err != nil {
+		return Aggregation{}, err
+	}
+	a.GroupBy = groupBy
+
+	visited := make(map[string]bool)
+	for _, expr := range metrics {
+		m, err := NewMetric(expr)
+		if err != nil {
+			return Aggregation{}, err
+		}
+		if visited[m.String()] {
+			return Aggregation{}, fmt.Errorf("metric '%s' provided multiple times in query", m)
+		}
+		visited[m.String()] = true
+		a.Metrics = append(a.Metrics, m)
+	}
+	if len(a.Metrics) == 0 {
+		a.Metrics = []Metric{{Function: FunctionCount}}
+	}
+	return a, nil
+}
+
+func validateGroupBy(groupBy []string) error {
+	visited := make(map[string]bool)
+	for _, name := range groupBy {
+		if _, found := fields[name]; !found {
+			return fmt.Errorf("unknown group by field provided '%s' in query", name)
+		}
+		if visited[name] {
+			return fmt.Errorf("group by field '%s' provided multiple times in query", name)
+		}
+		visited[name] = true
+	}
+	return nil
+}
+
+// accumulator collects the values of one metric within a group.
+type accumulator struct {
+	count         int
+	sum, min, max float64
+}
+
+func (acc *accumulator) add(v float64) {
+	if acc.count == 0 || v < acc.min {
+		acc.min = v
+	}
+	if acc.count == 0 || v > acc.max {
+		acc.max = v
+	}
+	acc.count++
+	acc.sum += v
+}
+
+func (acc *accumulator) result(fn Function) *float64 {
+	var v float64
+	switch {
+	case fn == FunctionCount:
+		v = float64(acc.count)
+	case acc.count == 0:
+		return nil
+	case fn == FunctionSum:
+		v = acc.sum
+	case fn == FunctionAvg, fn == FunctionMean:
+		v = acc.sum / float64(acc.count)
+	case fn == FunctionMin:
+		v = acc.min
+	case fn == FunctionMax:
+		v = acc.max
+	}
+	return &v
+}
+
+// Apply aggregates passengers in memory, groups are ordered by their keys
+// with missing keys first.
+func (a Aggregation) Apply(passengers []*Passenger) []*Group {
+	type bucket struct {
+		keys         []interface{}
+		accumulators []accumulator
+	}
+
+	buckets := make(map[string]*bucket)
+	var order []*bucket
+
+	for _, p := range passengers {
+		if !a.Filter.Match(p) {
+			continue
+		}
+
+		keys := make([]interface{}, len(a.GroupBy))
+		for i, name := range a.GroupBy {
+			keys[i] = fields[name].value(p)
+		}
+		id := fmt.Sprintf("%#v", keys)
+		b, found := buckets[id]
+		if !found {
+			b = &bucket{keys: keys, accumulators: make([]accumulator, len(a.Metrics))}
+			buckets[id] = b
+			order = append(order, b)
+		}
+
+		for i, m := range a.Metrics {
+			if len(m.Field) == 0 {
+				b.accumulators[i].add(0)
+				continue
+			}
+			// text fields are only counted, empty text is missing
+			switch v := fields[m.Field].value(p).(type) {
+			case float64:
+				b.accumulators[i].add(v)
+			case string:
+				if len(v) > 0 {
+					b.accumulators[i].add(0)
+				}
+			}
+		}
+	}
+
+	if len(a.GroupBy) == 0 && len(order) == 0 {
+		// like SQL an aggregation without groups always has a single row
+		order = append(order, &bucket{keys: []interface{}{}, accumulators: make([]accumulator, len(a.Metrics))})
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return compareKeys(order[i].keys, order[j].keys) < 0
+	})
+
+	groups := make([]*Group, 0, len(order))
+	for _, b := range order {
+		g := &Group{Keys: b.keys, Values: make([]*float64, len(a.Metrics))}
+		for i, m := range a.Metrics {
+			g.Values[i] = b.accumulators[i].result(m.Function)
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// compareKeys orders group keys, a missing key sorts before any value.
+func compareKeys(a, b []interface{}) int {
+	for i := range a {
+		var rs int
+		switch v := a[i].(type) {
+		case nil:
+			if b[i] != nil {
+				rs = -1
+			}
+		case float64:
+			switch other, ok := b[i].(float64); {
+			case !ok, v > other:
+				rs = 1
+			case v < other:
+				rs = -1
+			}
+		case string:
+			switch other, ok := b[i].(string); {
+			case !ok:
+				rs = 1
+			default:
+				rs = strings.Compare(v, other)
+			}
+		}
+		if rs != 0 {
+			return rs
+		}
+	}
+	return 0
+}
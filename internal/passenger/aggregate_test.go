@@ -0,0 +1,28 @@
+package passenger
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAggregation_CountText_SkipsMissing(t *testing.T) {
+	Convey("Test aggregation\n", t, func() {
+		aggregation, err := NewAggregation(Filter{}, []string{"class"}, []string{"count", "count(cabin)", "count(embarked)"})
+		So(err, ShouldBeNil)
+
+		cabin, empty, port := "C85", "", PortSouthampton
+		passengers := []*Passenger{
+			{PassengerId: 1, Pclass: 1, Cabin: &cabin, Embarked: &port},
+			{PassengerId: 2, Pclass: 1, Cabin: &empty},
+			{PassengerId: 3, Pclass: 1},
+		}
+		groups := aggregation.Apply(passengers)
+		So(len(groups), ShouldEqual, 1)
+		So(*groups[0].Values[0], ShouldEqual, 3)
+		So(*groups[0].Values[1], ShouldEqual, 1)
+		So(*groups[0].Values[2], ShouldEqual, 1)
+
+		So(aggregation.Metrics[1].SQL(), ShouldEqual, "COUNT(NULLIF(cabin, ''))")
+	})
+}
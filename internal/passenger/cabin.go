@@ -0,0 +1,48 @@
+package passenger
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Cabin is a single cabin of a dataset cabin value, Number is nil for cabins
+// known by their deck only e.g. T.
+type Cabin struct {
+	Cabin  string `json:"cabin"`
+	Deck   string `json:"deck"`
+	Number *int   `json:"number"`
+}
+
+// ParseCabins splits a dataset cabin value into its cabins, e.g. `C23 C25 C27`
+// is three cabins of deck C. A lone deck letter followed by cabins is the deck
+// of those cabins, e.g. `F G73` is cabin G73 of deck F, otherwise it is a cabin
+// without number. Missing cabins are an empty list.
+func ParseCabins(raw string) []Cabin {
+	cabins := []Cabin{}
+	tokens := strings.Fields(raw)
+	var deck string
+	for i, token := range tokens {
+		if len(token) == 1 && i < len(tokens)-1 {
+			deck = strings.ToUpper(token)
+			continue
+		}
+
+		c := Cabin{Cabin: token, Deck: strings.ToUpper(token[:1])}
+		if len(deck) > 0 {
+			c.Deck = deck
+		}
+		if n, err := strconv.Atoi(token[1:]); err == nil {
+			c.Number = &n
+		}
+		cabins = append(cabins, c)
+	}
+	return cabins
+}
+
+// cabinDeck is the deck of the first cabin, empty when the cabin is missing.
+func cabinDeck(raw string) string {
+	if cabins := ParseCabins(raw); len(cabins) > 0 {
+		return cabins[0].Deck
+	}
+	return ""
+}
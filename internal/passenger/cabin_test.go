@@ -0,0 +1,30 @@
+package passenger
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseCabins_DatasetCabins_Cabins(t *testing.T) {
+	Convey("Test parse cabins\n", t, func() {
+		number := func(n int) *int { return &n }
+		tests := map[string][]Cabin{
+			"":    {},
+			"C85": {{Cabin: "C85", Deck: "C", Number: number(85)}},
+			"C23 C25 C27": {{Cabin: "C23", Deck: "C", Number: number(23)}, {Cabin: "C25", Deck: "C", Number: number(25)},
+				{Cabin: "C27", Deck: "C", Number: number(27)}},
+			"F G73": {{Cabin: "G73", Deck: "F", Number: number(73)}},
+			"T":     {{Cabin: "T", Deck: "T"}},
+			"D":     {{Cabin: "D", Deck: "D"}},
+		}
+		for raw, expected := range tests {
+			Convey("Test parse cabins '"+raw+"'\n", func() {
+				So(ParseCabins(raw), ShouldResemble, expected)
+			})
+		}
+		So(cabinDeck("F E69"), ShouldEqual, "F")
+		So(cabinDeck("B58 B60"), ShouldEqual, "B")
+		So(cabinDeck(""), ShouldEqual, "")
+	})
+}
@@ -1,6 +1,9 @@
 package passenger
 
 import (
+	"context"
+	"database/sql"
+	"github.com/mattn/go-sqlite3"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,10 +16,32 @@ import (
 const (
 	maxActiveConnections = 1
 	connectionsKeepAlive = 10 * time.Second
+	// driverName is the sqlite driver with the passenger sql functions registered
+	driverName = "sqlite3_passenger"
 )
 
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// name_part(name, part) returns a part of a parsed name, see nameParts
+			err := conn.RegisterFunc("name_part", func(name, part string) string {
+				if read, found := nameParts[part]; found {
+					return read(ParseName(name))
+				}
+				return ""
+			}, true)
+			if err != nil {
+				return err
+			}
+			// cabin_deck(cabin) returns the deck of the first cabin, see cabinDeck
+			return conn.RegisterFunc("cabin_deck", cabinDeck, true)
+		},
+	})
+}
+
 type Connector interface {
-	Get() (*gorm.DB, error)
+	// Get returns a session bound to ctx, queries are interrupted once ctx is done
+	Get(ctx context.Context) (*gorm.DB, error)
 }
 
 type connector struct {
@@ -25,7 +50,7 @@ type connector struct {
 	mu     sync.Mutex
 }
 
-func (c *connector) Get() (*gorm.DB, error) {
+func (c *connector) Get(ctx context.Context) (*gorm.DB, error) {
 	if c.db == nil {
 		c.mu.Lock()
 		defer c.mu.Unlock()
@@ -41,7 +66,7 @@ func (c *connector) Get() (*gorm.DB, error) {
 			},
 		)
 
-		db, err := gorm.Open(sqlite.Open(c.dbPath), &gorm.Config{Logger: sqliteLogger})
+		db, err := gorm.Open(&sqlite.Dialector{DriverName: driverName, DSN: c.dbPath}, &gorm.Config{Logger: sqliteLogger})
 		if err != nil {
 			return nil, err
 		}
@@ -57,7 +82,7 @@ func (c *connector) Get() (*gorm.DB, error) {
 		c.db = db
 	}
 
-	return c.db, nil
+	return c.db.WithContext(ctx), nil
 }
 
 func NewConnector(dbPath string) Connector {
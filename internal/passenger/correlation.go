@@ -0,0 +1,131 @@
+package passenger
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"titanic-api/pkg/stats"
+)
+
+var (
+	// defaultCorrelationFields are correlated when no fields are requested
+	defaultCorrelationFields = []string{"survived", "class", "age", "siblings-spouses", "parents-children", "fare",
+		"sex"}
+)
+
+// Correlation correlates the numeric and binary encoded Fields of the
+// passengers matching Filter. Sex is encoded 1 for male and 0 for female.
+type Correlation struct {
+	Filter Filter
+	Fields []string
+}
+
+// CorrelationMatrix holds the correlation coefficients of every pair of
+// Fields, computed over the Pairs passengers having both values. A
+// coefficient is null when it is undefined, e.g. for a constant field.
+type CorrelationMatrix struct {
+	Fields   []string     `json:"fields"`
+	Pairs    [][]int      `json:"pairs"`
+	Pearson  [][]*float64 `json:"pearson"`
+	Spearman [][]*float64 `json:"spearman"`
+}
+
+func NewCorrelation(filter Filter, fieldNames []string) (Correlation, error) {
+	visited := make(map[string]bool)
+	for _, name := range fieldNames {
+		f, found := fields[name]
+		switch {
+		case !found:
+			return Correlation{}, fmt.Errorf("unknown correlation field provided '%s' in query", name)
+		case f.number == nil && name != "sex":
+			return Correlation{}, fmt.Errorf("correlation field '%s' is not numeric or binary", name)
+		case visited[name]:
+			return Correlation{}, fmt.Errorf("correlation field '%s' provided multiple times in query", name)
+		}
+		visited[name] = true
+	}
+	if len(fieldNames) == 0 {
+		fieldNames = defaultCorrelationFields
+	}
+
+	return Correlation{Filter: filter, Fields: fieldNames}, nil
+}
+
+// Apply correlates passengers, they are expected to match the filter already.
+// Missing values are handled pairwise.
+func (c Correlation) Apply(passengers []*Passenger) *CorrelationMatrix {
+	columns := make([][]float64, len(c.Fields))
+	for i, name := range c.Fields {
+		columns[i] = make([]float64, len(passengers))
+		for j, p := range passengers {
+			columns[i][j] = encode(name, p)
+		}
+	}
+
+	m := &CorrelationMatrix{
+		Fields:   c.Fields,
+		Pairs:    make([][]int, len(c.Fields)),
+		Pearson:  make([][]*float64, len(c.Fields)),
+		Spearman: make([][]*float64, len(c.Fields)),
+	}
+	for i := range c.Fields {
+		m.Pairs[i] = make([]int, len(c.Fields))
+		m.Pearson[i] = make([]*float64, len(c.Fields))
+		m.Spearman[i] = make([]*float64, len(c.Fields))
+		for j := range c.Fields {
+			r, n := stats.Pearson(columns[i], columns[j])
+			rho, _ := stats.Spearman(columns[i], columns[j])
+			m.Pairs[i][j], m.Pearson[i][j], m.Spearman[i][j] = n, coefficient(r), coefficient(rho)
+		}
+	}
+	return m
+}
+
+// Records renders the matrices as CSV records, a header followed by a row
+// per method and field. Undefined coefficients are left empty.
+func (m *CorrelationMatrix) Records() [][]string {
+	records := [][]string{append([]string{"method", "field"}, m.Fields...)}
+	for _, matrix := range []struct {
+		method string
+		values [][]*float64
+	}{{"pearson", m.Pearson}, {"spearman", m.Spearman}} {
+		for i, name := range m.Fields {
+			record := []string{matrix.method, name}
+			for _, v := range matrix.values[i] {
+				switch v {
+				case nil:
+					record = append(record, "")
+				default:
+					record = append(record, strconv.FormatFloat(*v, 'g', -1, 64))
+				}
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// encode reads a correlation field value, NaN when it is missing.
+func encode(name string, p *Passenger) float64 {
+	if name == "sex" {
+		switch strings.ToLower(fields[name].text(p)) {
+		case "male":
+			return 1
+		case "female":
+			return 0
+		}
+		return math.NaN()
+	}
+	if v, ok := fields[name].number(p); ok {
+		return v
+	}
+	return math.NaN()
+}
+
+func coefficient(v float64) *float64 {
+	if math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,131 @@
+package passenger
+
+import (
+	"fmt"
+	"sort"
+	"titanic-api/pkg/stats"
+)
+
+var (
+	// categoricalFields are the fields a crosstab can be built over
+	categoricalFields = map[string]bool{"survived": true, "class": true, "sex": true, "embarked": true,
+		"siblings-spouses": true, "parents-children": true, "title": true, "deck": true}
+)
+
+// Crosstab counts the passengers matching Filter by the values of the Rows
+// and Cols fields.
+type Crosstab struct {
+	Filter Filter
+	Rows   string
+	Cols   string
+}
+
+// Table is a contingency table. Row and column keys are ordered with missing
+// keys first, percentages are relative to the row or column totals. The
+// chi-square test leaves the missing keys out.
+type Table struct {
+	RowField       string           `json:"row-field"`
+	ColField       string           `json:"col-field"`
+	Rows           []interface{}    `json:"rows"`
+	Cols           []interface{}    `json:"cols"`
+	Counts         [][]int          `json:"counts"`
+	RowPercentages [][]float64      `json:"row-percentages"`
+	ColPercentages [][]float64      `json:"col-percentages"`
+	RowTotals      []int            `json:"row-totals"`
+	ColTotals      []int            `json:"col-totals"`
+	Total          int              `json:"total"`
+	ChiSquare      *stats.ChiSquare `json:"chi-square"`
+}
+
+func NewCrosstab(filter Filter, rows, cols string) (Crosstab, error) {
+	for _, name := range []string{rows, cols} {
+		if !categoricalFields[name] {
+			return Crosstab{}, fmt.Errorf("crosstab fields must be two of survived, class, sex, embarked, " +
+				"siblings-spouses, parents-children, title, deck")
+		}
+	}
+	if rows == cols {
+		return Crosstab{}, fmt.Errorf("crosstab rows and cols must be different fields")
+	}
+	return Crosstab{Filter: filter, Rows: rows, Cols: cols}, nil
+}
+
+// Apply tabulates passengers, they are expected to match the filter already.
+func (c Crosstab) Apply(passengers []*Passenger) *Table {
+	t := &Table{RowField: c.Rows, ColField: c.Cols, Rows: []interface{}{}, Cols: []interface{}{}}
+
+	type cell struct{ row, col interface{} }
+	counts := make(map[cell]int)
+	rows, cols := make(map[interface{}]bool), make(map[interface{}]bool)
+	for _, p := range passengers {
+		k := cell{row: fields[c.Rows].value(p), col: fields[c.Cols].value(p)}
+		counts[k]++
+		if !rows[k.row] {
+			rows[k.row] = true
+			t.Rows = append(t.Rows, k.row)
+		}
+		if !cols[k.col] {
+			cols[k.col] = true
+			t.Cols = append(t.Cols, k.col)
+		}
+	}
+	sortKeys(t.Rows)
+	sortKeys(t.Cols)
+
+	t.Counts = make([][]int, len(t.Rows))
+	t.RowTotals, t.ColTotals = make([]int, len(t.Rows)), make([]int, len(t.Cols))
+	for i, row := range t.Rows {
+		t.Counts[i] = make([]int, len(t.Cols))
+		for j, col := range t.Cols {
+			n := counts[cell{row: row, col: col}]
+			t.Counts[i][j] = n
+			t.RowTotals[i] += n
+			t.ColTotals[j] += n
+			t.Total += n
+		}
+	}
+
+	t.RowPercentages, t.ColPercentages = make([][]float64, len(t.Rows)), make([][]float64, len(t.Rows))
+	for i := range t.Rows {
+		t.RowPercentages[i], t.ColPercentages[i] = make([]float64, len(t.Cols)), make([]float64, len(t.Cols))
+		for j := range t.Cols {
+			// totals of listed rows and columns are never zero
+			t.RowPercentages[i][j] = 100 * float64(t.Counts[i][j]) / float64(t.RowTotals[i])
+			t.ColPercentages[i][j] = 100 * float64(t.Counts[i][j]) / float64(t.ColTotals[j])
+		}
+	}
+
+	// missing keys are no category of their own, they are left out of the test
+	t.ChiSquare = stats.ChiSquareTest(t.knownCounts())
+	return t
+}
+
+// knownCounts are the counts of the rows and columns whose key is not missing.
+func (t *Table) knownCounts() [][]int {
+	var counts [][]int
+	for i, row := range t.Rows {
+		if missingKey(row) {
+			continue
+		}
+		var known []int
+		for j, col := range t.Cols {
+			if !missingKey(col) {
+				known = append(known, t.Counts[i][j])
+			}
+		}
+		counts = append(counts, known)
+	}
+	return counts
+}
+
+// missingKey reports whether a field value is missing, see field.value.
+func missingKey(key interface{}) bool {
+	return key == nil || key == ""
+}
+
+// sortKeys orders single field keys, a missing key sorts first.
+func sortKeys(keys []interface{}) {
+	sort.Slice(keys, func(i, j int) bool {
+		return compareKeys([]interface{}{keys[i]}, []interface{}{keys[j]}) < 0
+	})
+}
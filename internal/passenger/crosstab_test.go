@@ -0,0 +1,40 @@
+package passenger
+
+import (
+	"testing"
+	"titanic-api/pkg/stats"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCrosstab_MissingKey_LeftOutOfTest(t *testing.T) {
+	Convey("Test crosstab\n", t, func() {
+		crosstab, err := NewCrosstab(Filter{}, "embarked", "survived")
+		So(err, ShouldBeNil)
+
+		var passengers []*Passenger
+		add := func(port *Port, survived, count int) {
+			for i := 0; i < count; i++ {
+				passengers = append(passengers, &Passenger{PassengerId: len(passengers) + 1, Embarked: port,
+					Survived: survived})
+			}
+		}
+		cherbourg, southampton := PortCherbourg, PortSouthampton
+		add(&cherbourg, 1, 9)
+		add(&cherbourg, 0, 6)
+		add(&southampton, 1, 5)
+		add(&southampton, 0, 12)
+		add(nil, 1, 2)
+
+		table := crosstab.Apply(passengers)
+		Convey("Missing Key Is Counted", func() {
+			So(table.Rows, ShouldResemble, []interface{}{"", "C", "S"})
+			So(table.Counts[0], ShouldResemble, []int{0, 2})
+			So(table.Total, ShouldEqual, 34)
+		})
+		Convey("Missing Key Is Left Out Of The Test", func() {
+			So(table.ChiSquare.DegreesOfFreedom, ShouldEqual, 1)
+			So(table.ChiSquare, ShouldResemble, stats.ChiSquareTest([][]int{{6, 9}, {12, 5}}))
+		})
+	})
+}
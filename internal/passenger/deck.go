@@ -0,0 +1,52 @@
+package passenger
+
+// Deck is the passengers of a deck, a passenger being on the deck of its
+// first cabin. Passengers without cabin are on no deck.
+type Deck struct {
+	Deck         string   `json:"deck"`
+	Passengers   int      `json:"passengers"`
+	Survivors    int      `json:"survivors"`
+	SurvivalRate *float64 `json:"survival-rate"`
+	// Cabins are only set for a single deck
+	Cabins []*DeckCabin `json:"cabins,omitempty"`
+}
+
+// DeckCabin is the passengers of a dataset cabin value, e.g. `C23 C25 C27`.
+type DeckCabin struct {
+	Cabin      string `json:"cabin"`
+	Passengers int    `json:"passengers"`
+	Survivors  int    `json:"survivors"`
+}
+
+var (
+	// survivalMetrics count the passengers and survivors of a group, see groupCounts
+	survivalMetrics = []Metric{{Function: FunctionCount}, {Function: FunctionSum, Field: "survived"}}
+)
+
+// newDecks reads the decks of groups keyed by deck and computed with
+// survivalMetrics, the group of passengers without cabin is skipped.
+func newDecks(groups []*Group) []*Deck {
+	decks := []*Deck{}
+	for _, g := range groups {
+		if deck := g.Keys[0].(string); len(deck) > 0 {
+			passengers, survivors := groupCounts(g)
+			decks = append(decks, newDeck(deck, passengers, survivors))
+		}
+	}
+	return decks
+}
+
+func newDeck(deck string, passengers, survivors int) *Deck {
+	return &Deck{Deck: deck, Passengers: passengers, Survivors: survivors,
+		SurvivalRate: survivalRate(passengers, survivors)}
+}
+
+// groupCounts reads the passenger and survivor counts of a group computed with
+// survivalMetrics, the sum of an empty group is nil.
+func groupCounts(g *Group) (passengers, survivors int) {
+	passengers = int(*g.Values[0])
+	if g.Values[1] != nil {
+		survivors = int(*g.Values[1])
+	}
+	return passengers, survivors
+}
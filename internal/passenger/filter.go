@@ -0,0 +1,292 @@
+package passenger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Operator string
+
+const (
+	OperatorEq       Operator = "eq"
+	OperatorNe       Operator = "ne"
+	OperatorGt       Operator = "gt"
+	OperatorGte      Operator = "gte"
+	OperatorLt       Operator = "lt"
+	OperatorLte      Operator = "lte"
+	OperatorContains Operator = "contains"
+)
+
+// field describes a filterable passenger attribute, keyed by its API name.
+type field struct {
+	column string
+	// expr is the SQL expression compared against, defaults to column
+	expr string
+	// guard is an SQL predicate excluding rows where the value is missing
+	guard string
+	// number and text read the field value, exactly one of them is set;
+	// number reports false when the value is missing
+	number func(p *Passenger) (float64, bool)
+	text   func(p *Passenger) string
+}
+
+func (f field) sqlExpr() string {
+	if len(f.expr) > 0 {
+		return f.expr
+	}
+	return f.column
+}
+
+// sqlValue is the SQL expression of the field value, NULL when it is missing.
+func (f field) sqlValue() string {
+	switch {
+	case len(f.guard) > 0:
+		return "CASE WHEN " + f.guard + " THEN " + f.sqlExpr() + " END"
+	case f.text != nil:
+		return "IFNULL(" + f.sqlExpr() + ", '')"
+	}
+	return f.sqlExpr()
+}
+
+// value reads the field value as a float64 or string, nil when it is missing.
+func (f field) value(p *Passenger) interface{} {
+	if f.text != nil {
+		return f.text(p)
+	}
+	if v, ok := f.number(p); ok {
+		return v
+	}
+	return nil
+}
+
+var fields = map[string]field{
+	"id": {
+		column: "id",
+		number: func(p *Passenger) (float64, bool) { return float64(p.PassengerId), true },
+	},
+	"survived": {
+		column: "survived",
+		number: func(p *Passenger) (float64, bool) { return float64(p.Survived), true },
+	},
+	"class": {
+		column: "class",
+		number: func(p *Passenger) (float64, bool) { return float64(p.Pclass), true },
+	},
+	"name": {
+		column: "name",
+		text:   func(p *Passenger) string { return p.Name },
+	},
+	"sex": {
+		column: "sex",
+		text:   func(p *Passenger) string { return string(p.Sex) },
+	},
+	"age": {
+		column: "age",
+		expr:   "CAST(age AS REAL)",
+		guard:  "age IS NOT NULL AND age <> ''",
+		number: func(p *Passenger) (float64, bool) {
+			if p.Age == nil {
+				return 0, false
+			}
+			return *p.Age, true
+		},
+	},
+	"siblings-spouses": {
+		column: "siblings_spouses",
+		number: func(p *Passenger) (float64, bool) { return float64(p.SibSp), true },
+	},
+	"parents-children": {
+		column: "parents_children",
+		number: func(p *Passenger) (float64, bool) { return float64(p.Parch), true },
+	},
+	"ticket": {
+		column: "ticket",
+		text:   func(p *Passenger) string { return p.Ticket },
+	},
+	"fare": {
+		column: "fare",
+		number: func(p *Passenger) (float64, bool) { return p.Fare, true },
+	},
+	"cabin": {
+		column: "cabin",
+		text:   func(p *Passenger) string { return p.CabinString() },
+	},
+	"embarked": {
+		column: "embarked",
+		text:   func(p *Passenger) string { return p.EmbarkedString() },
+	},
+	"deck": {
+		column: "cabin",
+		expr:   "cabin_deck(cabin)",
+		text:   func(p *Passenger) string { return cabinDeck(p.CabinString()) },
+	},
+	"surname":     nameField("surname"),
+	"title":       nameField("title"),
+	"given-names": nameField("given-names"),
+	"nickname":    nameField("nickname"),
+	"maiden-name": nameField("maiden-name"),
+}
+
+// nameField is a part of the parsed name, computed in sql by the name_part
+// function of the sqlite driver.
+func nameField(part string) field {
+	return field{
+		column: "name",
+		expr:   "name_part(name, '" + part + "')",
+		text:   func(p *Passenger) string { return nameParts[part](ParseName(p.Name)) },
+	}
+}
+
+var (
+	numberOperators = map[Operator]bool{OperatorEq: true, OperatorNe: true, OperatorGt: true,
+		OperatorGte: true, OperatorLt: true, OperatorLte: true}
+	textOperators = map[Operator]bool{OperatorEq: true, OperatorNe: true, OperatorContains: true}
+)
+
+// Condition restricts passengers by comparing a field with one or more values.
+// Eq and contains match any of the values, ne matches none of them and the
+// ordering operators take a single value. A missing age never matches.
+type Condition struct {
+	Field    string
+	Operator Operator
+	Values   []string
+	numbers  []float64
+}
+
+// Filter selects the passengers matching all of its conditions.
+type Filter struct {
+	Conditions []Condition
+}
+
+func NewCondition(name string, op Operator, values []string) (Condition, error) {
+	f, found := fields[name]
+	if !found {
+		return Condition{}, fmt.Errorf("unknown filter field provided '%s' in query", name)
+	}
+
+	switch {
+	case f.number != nil && !numberOperators[op], f.text != nil && !textOperators[op]:
+		return Condition{}, fmt.Errorf("unsupported filter operator provided '%s' for field '%s' in query", op, name)
+	case len(values) == 0:
+		return Condition{}, fmt.Errorf("no value provided for filter field '%s' in query", name)
+	case len(values) > 1 && op != OperatorEq && op != OperatorNe && op != OperatorContains:
+		return Condition{}, fmt.Errorf("filter operator '%s' for field '%s' accepts a single value", op, name)
+	}
+
+	c := Condition{Field: name, Operator: op, Values: values}
+	if f.number != nil {
+		for _, v := range values {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return Condition{}, fmt.Errorf("invalid value provided '%s' for filter field '%s' in query", v, name)
+			}
+			c.numbers = append(c.numbers, n)
+		}
+	}
+	return c, nil
+}
+
+// Match reports whether p satisfies the condition.
+func (c Condition) Match(p *Passenger) bool {
+	f := fields[c.Field]
+	if f.number != nil {
+		v, ok := f.number(p)
+		if !ok {
+			return false
+		}
+		return c.matchNumber(v)
+	}
+	return c.matchText(f.text(p))
+}
+
+func (c Condition) matchNumber(v float64) bool {
+	switch c.Operator {
+	case OperatorGt:
+		return v > c.numbers[0]
+	case OperatorGte:
+		return v >= c.numbers[0]
+	case OperatorLt:
+		return v < c.numbers[0]
+	case OperatorLte:
+		return v <= c.numbers[0]
+	}
+
+	var found bool
+	for _, n := range c.numbers {
+		if v == n {
+			found = true
+			break
+		}
+	}
+	return found == (c.Operator == OperatorEq)
+}
+
+func (c Condition) matchText(v string) bool {
+	var found bool
+	for _, s := range c.Values {
+		if c.Operator == OperatorContains && strings.Contains(strings.ToLower(v), strings.ToLower(s)) ||
+			c.Operator != OperatorContains && v == s {
+			found = true
+			break
+		}
+	}
+	return found == (c.Operator != OperatorNe)
+}
+
+// Match reports whether p satisfies all the filter conditions.
+func (f Filter) Match(p *Passenger) bool {
+	for _, c := range f.Conditions {
+		if !c.Match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// SQL translates the condition into a where clause and its arguments.
+func (c Condition) SQL() (string, []interface{}) {
+	f := fields[c.Field]
+	expr := f.sqlExpr()
+
+	var guard string
+	if len(f.guard) > 0 {
+		guard = f.guard + " AND "
+	}
+
+	var values []interface{}
+	if f.number != nil {
+		for _, n := range c.numbers {
+			values = append(values, n)
+		}
+	} else {
+		for _, v := range c.Values {
+			values = append(values, v)
+		}
+	}
+
+	switch c.Operator {
+	case OperatorGt:
+		return guard + expr + " > ?", values
+	case OperatorGte:
+		return guard + expr + " >= ?", values
+	case OperatorLt:
+		return guard + expr + " < ?", values
+	case OperatorLte:
+		return guard + expr + " <= ?", values
+	case OperatorNe:
+		return guard + expr + " NOT IN ?", []interface{}{values}
+	case OperatorContains:
+		var clauses []string
+		var args []interface{}
+		for _, v := range c.Values {
+			clauses = append(clauses, expr+` LIKE ? ESCAPE '\'`)
+			args = append(args, "%"+likeEscaper.Replace(v)+"%")
+		}
+		return "(" + strings.Join(clauses, " OR ") + ")", args
+	default:
+		return guard + expr + " IN ?", []interface{}{values}
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
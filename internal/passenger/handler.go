@@ -1,75 +1,387 @@
 package passenger
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"io"
 	"log"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"reflect"
+	"net/url"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"titanic-api/pkg/histogram"
 	"titanic-api/pkg/response"
 )
 
 const (
 	maxAttributeParamLength = 256
+	maxPageLimit            = 1000
+	// maxImportSize caps the size of import request bodies
+	maxImportSize = 32 << 20
 )
 
 var (
-	ErrInvalidID = fmt.Errorf("id provided is not a valid integer")
+	ErrInvalidID     = fmt.Errorf("id provided is not a valid integer")
+	ErrInvalidBody   = fmt.Errorf("request body is not a valid passenger")
+	ErrIDMismatch    = fmt.Errorf("id provided in body does not match id in path")
+	ErrInvalidTicket = fmt.Errorf("ticket provided is not valid")
+	ErrInvalidDeck   = fmt.Errorf("deck provided is not a single letter")
+	ErrInvalidImport = fmt.Errorf("import must be a multipart form with a 'file' field")
+	ErrImportFormat  = fmt.Errorf("import file must be csv (text/csv) or ndjson (application/x-ndjson)")
+	ErrImportEmpty   = fmt.Errorf("import file has no rows")
+	ErrImportTooBig  = fmt.Errorf("import file exceeds %d bytes", maxImportSize)
+)
+
+var (
+	// reservedParams are list query parameters which are not passenger filters
+	reservedParams = map[string]bool{"attributes": true, "limit": true, "cursor": true, "sort": true,
+		"imputed": true, "impute": true, "format": true}
+	// aggregateParams are aggregate query parameters which are not passenger filters
+	aggregateParams = map[string]bool{"groupBy": true, "metrics": true, "format": true}
+	// statisticsParams are statistics query parameters which are not passenger filters
+	statisticsParams = map[string]bool{"groupBy": true, "fields": true, "format": true}
+	// crosstabParams are crosstab query parameters which are not passenger filters
+	crosstabParams = map[string]bool{"rows": true, "cols": true, "format": true}
+	// correlationParams are correlation query parameters which are not passenger filters
+	correlationParams = map[string]bool{"fields": true, "format": true}
+	// importColumns are the attributes of import csv columns, named after the
+	// attribute or the dataset header
+	importColumns = map[string]string{"id": "id", "passengerid": "id", "survived": "survived", "class": "class",
+		"pclass": "class", "name": "name", "sex": "sex", "age": "age", "siblings-spouses": "siblings-spouses",
+		"sibsp": "siblings-spouses", "parents-children": "parents-children", "parch": "parents-children",
+		"ticket": "ticket", "fare": "fare", "cabin": "cabin", "embarked": "embarked"}
+	// derivedColumns are the columns of exported lists computed from other
+	// columns, they are ignored on import
+	derivedColumns = map[string]bool{"name-parts": true, "surname": true, "title": true, "given-names": true,
+		"nickname": true, "maiden-name": true, "cabins": true, "deck": true, "imputed": true}
+	// exportColumns are the columns of exports without attributes, the dataset
+	// columns which imports read back
+	exportColumns = []string{"id", "survived", "class", "name", "sex", "age", "siblings-spouses",
+		"parents-children", "ticket", "fare", "cabin", "embarked"}
 )
 
 type Response struct {
-	PassengerId int     `json:"id"`
-	Survived    int     `json:"survived"`
-	Pclass      int     `json:"class"`
-	Name        string  `json:"name"`
-	Sex         string  `json:"sex"`
-	Age         string  `json:"age"`
-	SibSp       int     `json:"siblings-spouses"`
-	Parch       int     `json:"parents-children"`
-	Ticket      string  `json:"ticket"`
-	Fare        float64 `json:"fare"`
-	Cabin       string  `json:"cabin"`
-	Embarked    string  `json:"embarked"`
+	PassengerId int    `json:"id"`
+	Survived    int    `json:"survived"`
+	Pclass      int    `json:"class"`
+	Name        string `json:"name"`
+	// NameParts is the parsed name, it is ignored in requests
+	NameParts Name    `json:"name-parts"`
+	Sex       string  `json:"sex"`
+	Age       string  `json:"age"`
+	SibSp     int     `json:"siblings-spouses"`
+	Parch     int     `json:"parents-children"`
+	Ticket    string  `json:"ticket"`
+	Fare      float64 `json:"fare"`
+	Cabin     string  `json:"cabin"`
+	// Cabins is the parsed cabin, it is ignored in requests
+	Cabins   []Cabin `json:"cabins"`
+	Embarked string  `json:"embarked"`
+	// Imputed lists the attributes whose value was imputed, it is ignored in requests
+	Imputed []string `json:"imputed,omitempty"`
+}
+
+// ResponseV2 is the typed passenger representation, missing values are null.
+type ResponseV2 struct {
+	PassengerId int      `json:"id"`
+	Survived    int      `json:"survived"`
+	Pclass      Class    `json:"class"`
+	Name        string   `json:"name"`
+	NameParts   Name     `json:"name-parts"`
+	Sex         Sex      `json:"sex"`
+	Age         *float64 `json:"age"`
+	SibSp       int      `json:"siblings-spouses"`
+	Parch       int      `json:"parents-children"`
+	Ticket      string   `json:"ticket"`
+	Fare        float64  `json:"fare"`
+	Cabin       *string  `json:"cabin"`
+	Cabins      []Cabin  `json:"cabins"`
+	Embarked    *Port    `json:"embarked"`
+	// Imputed lists the attributes whose value was imputed
+	Imputed []string `json:"imputed,omitempty"`
+}
+
+// PartyResponse is a travelling party with its members in the passenger shape
+// of the API version.
+type PartyResponse struct {
+	*Party
+	Members []*MemberResponse `json:"members"`
+}
+
+type MemberResponse struct {
+	Passenger     interface{} `json:"passenger"`
+	FamilySize    int         `json:"family-size"`
+	PerPersonFare float64     `json:"per-person-fare"`
 }
 
 type Handler struct {
 	service Service
+	view    *view
 }
 
 func (h *Handler) RegisterHandler() *chi.Mux {
 	router := chi.NewRouter()
 	router.Get("/", h.GetAll)
+	router.Get("/export", h.Export)
+	router.Get("/aggregate", h.Aggregate)
+	router.Get("/stats", h.Statistics)
+	router.Get("/crosstab", h.Crosstab)
+	router.Get("/correlation", h.Correlation)
 	router.Get("/{id}", h.Get)
+	router.Get("/{id}/group", h.Group)
 	router.Get("/fare/histogram/percentile", h.FareHistogram)
+	router.Get("/{field}/histogram", h.Histogram)
+	if h.view.writable {
+		router.Post("/", h.Create)
+		router.Post("/import", h.Import)
+		router.Put("/{id}", h.Update)
+		router.Patch("/{id}", h.Patch)
+		router.Delete("/{id}", h.Delete)
+	}
 	return router
 }
 
 // Package 	godoc
 // @Summary Get passengers
-// @Description Get all passengers
+// @Description Get all passengers matching the filters. Every attribute can be filtered with
+// @Description `<attribute>=<values>` or `<attribute>.<operator>=<values>` where values are comma separated
+// @Description and operator is one of eq, ne, gt, gte, lt, lte (numeric attributes) or eq, ne, contains (text attributes).
+// @Description Lists without limit are streamed in json and ndjson, a failure midway leaves the json array unterminated
 // @Tags    passenger
 // @ID 		passenger-get-all
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/xml
+// @Param survived query int false "Survived (0 or 1)"
+// @Param class query []int false "Passenger class" collectionFormat(csv)
+// @Param sex query string false "Sex (male or female)"
+// @Param age.gte query number false "Minimum age"
+// @Param fare.lt query number false "Fare upper bound"
+// @Param embarked query []string false "Embarkation port (S, C, Q)" collectionFormat(csv)
+// @Param sort query []string false "Sort fields, prefix with '-' for descending order e.g. -fare,name" collectionFormat(csv)
+// @Param limit query int false "Maximum number of passengers returned (1-1000)"
+// @Param cursor query string false "Page cursor taken from the Link response header"
+// @Param attributes query []string false "Allowed: id, age, sex, name, surname, title, given-names, nickname, maiden-name, survived, class, siblings-spouses, parents-children, ticket, fare, cabin, deck, embarked"
+// @Param imputed query bool false "Fill missing ages with the title and class median and missing embarked with the most frequent port, filled attributes are listed in imputed. Filters and sorting apply to the stored values"
+// @Param impute query string false "Imputation rules field:strategy[:group+group] e.g. age:regression,embarked:mode,cabin:mode:class, strategies are mean, median, regression (age) and mode (embarked, cabin)"
+// @Param format query string false "Response format overriding the Accept header: json (default), csv, ndjson or xml"
 // @Success 200 {object} []Response
+// @Header  200 {integer} X-Total-Count "Number of passengers matching the filters"
+// @Header  200 {string} Link "Next and prev page links"
+// @Failure 400 {object} response.Error
+// @Failure 406 {object} response.Error
 // @Failure 500 {object} response.Error
 // @Router  /passenger [get]
 func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
-	passengers, err := h.service.GetAll()
+	query, err := h.parseQuery(r.URL.Query())
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	imputation, err := parseImputation(r.URL.Query())
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// imputation groups passengers by other fields so they are read whole
+	serviceQuery := query
+	if imputation != nil {
+		serviceQuery.Attributes = nil
+	}
+
+	// unpaged lists are streamed unless the format needs the whole body
+	if query.Limit == 0 {
+		stream, err := response.NewStream(r, w, http.StatusOK)
+		switch err {
+		case nil:
+			h.streamAll(w, r, stream, func(p *Passenger) error {
+				return stream.Write(h.item(query.Attributes, p))
+			}, serviceQuery, imputation)
+			return
+		case response.ErrNotAcceptable:
+			response.SendError(r, w, http.StatusNotAcceptable, err.Error())
+			return
+		}
+	}
+
+	page, err := h.service.GetAll(r.Context(), serviceQuery)
+	if err == nil && imputation != nil {
+		page.Passengers, err = h.service.Impute(r.Context(), *imputation, page.Passengers)
+	}
 	switch err {
 	case nil:
-		var rs []*Response
-		for _, p := range passengers {
-			rs = append(rs, h.convertPassenger(p))
+		rs := make([]interface{}, 0, len(page.Passengers))
+		for _, p := range page.Passengers {
+			rs = append(rs, h.item(query.Attributes, p))
 		}
-		response.SendBody(r, w, http.StatusOK, rs)
+		w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+		if links := h.pageLinks(r, page); len(links) > 0 {
+			w.Header().Set("Link", links)
+		}
+		// empty csv lists keep the header of an item
+		response.SendBody(r, w, http.StatusOK, &response.List{Items: rs, Template: h.item(query.Attributes, &Passenger{})})
 	default:
 		log.Println(fmt.Sprintf("request id: %s failed to get passengers: %v",
 			middleware.GetReqID(r.Context()), err.Error()))
-		response.SendError(r, w, http.StatusInternalServerError, response.ErrInternalFailure.Error())
+		response.SendFailure(r, w, err)
+	}
+}
+
+// passengerStream is a response written a passenger at a time, a
+// response.Stream or a response.Export.
+type passengerStream interface {
+	Started() bool
+	Close() error
+}
+
+// streamAll writes the passengers of query with write as they are read from
+// the store. Failures met once the stream started end the body early, e.g. as
+// an unterminated JSON array.
+func (h *Handler) streamAll(w http.ResponseWriter, r *http.Request, stream passengerStream,
+	write func(p *Passenger) error, query Query, imputation *Imputation) {
+	var imputer *Imputer
+	var err error
+	if imputation != nil {
+		imputer, err = h.service.Fit(r.Context(), *imputation)
+	}
+	var it PassengerIterator
+	var total int
+	if err == nil {
+		it, total, err = h.service.Iterate(r.Context(), query)
+	}
+	if err != nil {
+		log.Println(fmt.Sprintf("request id: %s failed to get passengers: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+		return
+	}
+	defer it.Close()
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	for err == nil && it.Next() {
+		p := it.Passenger()
+		if imputer != nil {
+			p = imputer.Apply([]*Passenger{p})[0]
+		}
+		err = write(p)
+	}
+	if err == nil {
+		err = it.Err()
+	}
+	if err == nil {
+		err = stream.Close()
+	}
+	switch {
+	case err == nil:
+	case !stream.Started():
+		log.Println(fmt.Sprintf("request id: %s failed to get passengers: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to stream passengers: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+	}
+}
+
+// item is the passenger list item of p, projected on attributes when set.
+func (h *Handler) item(attributes []string, p *Passenger) interface{} {
+	if len(attributes) == 0 {
+		return h.view.render(p)
+	}
+	return h.view.project(attributes, p)
+}
+
+// Package 	godoc
+// @Summary Export passengers
+// @Description Download the passengers matching the list filters as a file, a column per attribute.
+// @Description Cells keep their type in xlsx, whose header row is frozen, ndjson lines are list items which import
+// @Description reads back. Imputed exports list the imputed attributes in a last imputed column.
+// @Description A failure midway leaves the file truncated
+// @Tags    passenger
+// @ID 		passenger-export
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "File format: csv (default), ndjson or xlsx"
+// @Param survived query int false "Survived (0 or 1)"
+// @Param class query []int false "Passenger class" collectionFormat(csv)
+// @Param sex query string false "Sex (male or female)"
+// @Param sort query []string false "Sort fields, prefix with '-' for descending order e.g. -fare,name" collectionFormat(csv)
+// @Param limit query int false "Maximum number of passengers exported (1-1000)"
+// @Param attributes query []string false "Exported columns, see the passenger list. Defaults to the dataset columns"
+// @Param imputed query bool false "Fill missing values, see the passenger list"
+// @Param impute query string false "Imputation rules, see the passenger list"
+// @Success 200 {file} file
+// @Header  200 {integer} X-Total-Count "Number of passengers matching the filters"
+// @Header  200 {string} Content-Disposition "attachment; filename=passengers.<format>"
+// @Failure 400 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/export [get]
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	query, err := h.parseQuery(r.URL.Query())
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	imputation, err := parseImputation(r.URL.Query())
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	columns := query.Attributes
+	if len(columns) == 0 {
+		columns = exportColumns
+	}
+	if imputation != nil {
+		columns = append(columns[:len(columns):len(columns)], "imputed")
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = response.FormatCSV
+	}
+	export, err := response.NewExport(r, w, format, "passengers", columns)
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// passengers are read whole, the columns are picked from them
+	query.Attributes = nil
+	h.streamAll(w, r, export, func(p *Passenger) error {
+		return export.Write(h.exportRow(format, columns, p))
+	}, query, imputation)
+}
+
+// exportRow is the values of the columns of p. Ndjson rows are read with the
+// attributes of the API version, like list items, so the version imports them
+// back. Other formats read the v2 attributes, so numbers stay numbers and
+// missing values stay empty.
+func (h *Handler) exportRow(format string, columns []string, p *Passenger) []interface{} {
+	attributes := viewV2.attributes
+	if format == response.FormatNDJSON {
+		attributes = h.view.attributes
+	}
+
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		switch {
+		case c != "imputed":
+			values[i] = attributes[c](p)
+		case format == response.FormatNDJSON:
+			values[i] = p.Imputed
+		default:
+			values[i] = strings.Join(p.Imputed, ",")
+		}
 	}
+	return values
 }
 
 // Package 	godoc
@@ -77,16 +389,26 @@ func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
 // @Description Get passenger by ID number
 // @Tags    passenger
 // @ID 		passenger-get
-// @Produce json
+// @Produce json,text/csv,application/x-ndjson,application/xml
 // @Param id path int true "Passenger ID"
-// @Param attributes query []string false "Allowed: id, age, sex, name, survived, class, siblings-spouses, parents-children, ticket, fare, cabin, embarked"
+// @Param attributes query []string false "Allowed: id, age, sex, name, surname, title, given-names, nickname, maiden-name, survived, class, siblings-spouses, parents-children, ticket, fare, cabin, deck, embarked"
+// @Param imputed query bool false "Fill missing values, see the passenger list"
+// @Param impute query string false "Imputation rules, see the passenger list"
+// @Param format query string false "Response format overriding the Accept header: json (default), csv, ndjson or xml"
 // @Success 200 {object} Response
 // @Failure 404 {object} response.Error
+// @Failure 406 {object} response.Error
 // @Failure 500 {object} response.Error
 // @Router  /passenger/{id} [get]
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
-	attr := r.URL.Query().Get("attributes")
-	if err := h.validateAttributes(attr); err != nil {
+	attributes, err := h.parseAttributes(r.URL.Query().Get("attributes"))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	imputation, err := parseImputation(r.URL.Query())
+	if err != nil {
 		response.SendError(r, w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -97,7 +419,13 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	storePassenger, err := h.service.Get(pid)
+	storePassenger, err := h.service.Get(r.Context(), pid)
+	if err == nil && imputation != nil {
+		var imputed []*Passenger
+		if imputed, err = h.service.Impute(r.Context(), *imputation, []*Passenger{storePassenger}); err == nil {
+			storePassenger = imputed[0]
+		}
+	}
 	switch {
 	case err == ErrPassengerNotFound:
 		response.SendError(r, w, http.StatusNotFound, ErrPassengerNotFound.Error())
@@ -105,130 +433,1332 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	case err != nil:
 		log.Println(fmt.Sprintf("request id: %s failed to get passenger: %v",
 			middleware.GetReqID(r.Context()), err.Error()))
-		response.SendError(r, w, http.StatusInternalServerError, response.ErrInternalFailure.Error())
+		response.SendFailure(r, w, err)
 		return
 	}
 
-	p := h.convertPassenger(storePassenger)
-	switch len(attr) {
+	switch len(attributes) {
 	case 0:
-		response.SendBody(r, w, http.StatusOK, p)
+		response.SendBody(r, w, http.StatusOK, h.view.render(storePassenger))
 	default:
-		response.SendBody(r, w, http.StatusOK, h.filterAttributes(p, attr))
+		response.SendBody(r, w, http.StatusOK, h.view.project(attributes, storePassenger))
 	}
 }
 
+// RegisterTicketHandler registers the ticket routes, mounted apart from the
+// passenger routes.
+func (h *Handler) RegisterTicketHandler() *chi.Mux {
+	router := chi.NewRouter()
+	router.Get("/{ticket}", h.Ticket)
+	return router
+}
+
 // Package 	godoc
-// @Summary Get fare histogram histogram
-// @Description Get histogram represention of number of passengers in each precentile
-// @Tags    passenger
-// @ID 		passenger-fare-histogram
+// @Summary Get ticket
+// @Description Get the passengers sharing a ticket, with their family size, per-person fare (the ticket fare
+// @Description divided by the passengers sharing it) and the survival outcome of the group
+// @Tags    ticket
+// @ID 		ticket-get
 // @Produce json
-// @Success 200 {object} histogram.Histogram
+// @Param ticket path string true "Ticket, path escaped e.g. A%2F5%2021171"
+// @Success 200 {object} PartyResponse
+// @Failure 404 {object} response.Error
 // @Failure 500 {object} response.Error
-// @Router  /passenger/fare/histogram/percentile [get]
-func (h *Handler) FareHistogram(w http.ResponseWriter, r *http.Request) {
-	histogram, err := h.service.FarePercentileHistogram()
-	switch err {
-	case nil:
-		response.SendBody(r, w, http.StatusOK, histogram)
-	default:
-		log.Println(fmt.Sprintf("request id: %s failed to get fare histogram histogram: %v",
+// @Router  /ticket/{ticket} [get]
+func (h *Handler) Ticket(w http.ResponseWriter, r *http.Request) {
+	// chi matches the escaped path when it has escaped characters e.g. slashes
+	ticket, err := url.PathUnescape(chi.URLParam(r, "ticket"))
+	if err != nil || len(ticket) == 0 {
+		response.SendError(r, w, http.StatusBadRequest, ErrInvalidTicket.Error())
+		return
+	}
+
+	party, err := h.service.Ticket(r.Context(), ticket)
+	switch {
+	case err == ErrTicketNotFound:
+		response.SendError(r, w, http.StatusNotFound, ErrTicketNotFound.Error())
+		return
+	case err != nil:
+		log.Println(fmt.Sprintf("request id: %s failed to get ticket: %v",
 			middleware.GetReqID(r.Context()), err.Error()))
-		response.SendError(r, w, http.StatusInternalServerError, response.ErrInternalFailure.Error())
+		response.SendFailure(r, w, err)
+		return
 	}
+
+	response.SendBody(r, w, http.StatusOK, h.renderParty(party))
 }
 
-func (h *Handler) validateAttributes(attr string) error {
+// Package 	godoc
+// @Summary Get passenger group
+// @Description Get the travelling party of a passenger: passengers sharing a ticket with it and relatives sharing
+// @Description its surname and family size, transitively. Fare sums the fares of the party tickets
+// @Tags    passenger
+// @ID 		passenger-get-group
+// @Produce json
+// @Param id path int true "Passenger ID"
+// @Success 200 {object} PartyResponse
+// @Failure 400 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/{id}/group [get]
+func (h *Handler) Group(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, ErrInvalidID.Error())
+		return
+	}
+
+	party, err := h.service.Party(r.Context(), pid)
 	switch {
-	case len(attr) == 0:
-		return nil
-	case len(attr) > maxAttributeParamLength:
-		return fmt.Errorf("attributes query parameter is too long max length %d", maxAttributeParamLength)
+	case err == ErrPassengerNotFound:
+		response.SendError(r, w, http.StatusNotFound, ErrPassengerNotFound.Error())
+		return
+	case err != nil:
+		log.Println(fmt.Sprintf("request id: %s failed to get passenger group: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+		return
 	}
 
-	attributes := strings.Split(attr, ",")
+	response.SendBody(r, w, http.StatusOK, h.renderParty(party))
+}
 
-	for i, a := range attributes {
-		attributes[i] = strings.TrimSpace(a)
+// RegisterDeckHandler registers the deck routes, mounted apart from the
+// passenger routes.
+func (h *Handler) RegisterDeckHandler() *chi.Mux {
+	router := chi.NewRouter()
+	router.Get("/", h.Decks)
+	router.Get("/{letter}", h.Deck)
+	return router
+}
+
+// Package 	godoc
+// @Summary Get decks
+// @Description Get the passenger and survivor counts of every deck, a passenger is on the deck of its first cabin.
+// @Description Passengers without cabin are on no deck
+// @Tags    deck
+// @ID 		deck-get-all
+// @Produce json
+// @Success 200 {object} []Deck
+// @Failure 500 {object} response.Error
+// @Router  /deck [get]
+func (h *Handler) Decks(w http.ResponseWriter, r *http.Request) {
+	decks, err := h.service.Decks(r.Context())
+	if err != nil {
+		log.Println(fmt.Sprintf("request id: %s failed to get decks: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+		return
 	}
 
-	passengerValue := reflect.ValueOf(&Response{}).Elem()
-	passengerType := passengerValue.Type()
+	response.SendBody(r, w, http.StatusOK, decks)
+}
 
-	visited := make(map[string]bool)
-	for _, attribute := range attributes {
-		var valid bool
-		for i := 0; i < passengerValue.NumField(); i++ {
-			if valid {
-				break
-			}
-			fieldType := passengerType.Field(i)
-
-			jsonField := fieldType.Tag.Get("json")
-			switch jsonField {
-			case attribute:
-				if _, found := visited[attribute]; found {
-					return fmt.Errorf("attribute '%s' provided multiple times in query", attribute)
-				}
-				visited[attribute] = true
-				valid = true
-			default:
-				valid = false
-			}
-		}
-		if !valid {
-			return fmt.Errorf("unknown attribute filter provided '%s' in query", attribute)
-		}
+// Package 	godoc
+// @Summary Get deck
+// @Description Get the passenger and survivor counts of a deck and of its cabins
+// @Tags    deck
+// @ID 		deck-get
+// @Produce json
+// @Param letter path string true "Deck letter e.g. C"
+// @Success 200 {object} Deck
+// @Failure 400 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /deck/{letter} [get]
+func (h *Handler) Deck(w http.ResponseWriter, r *http.Request) {
+	letter := strings.ToUpper(chi.URLParam(r, "letter"))
+	if len(letter) != 1 || letter[0] < 'A' || letter[0] > 'Z' {
+		response.SendError(r, w, http.StatusBadRequest, ErrInvalidDeck.Error())
+		return
 	}
-	return nil
+
+	deck, err := h.service.Deck(r.Context(), letter)
+	switch {
+	case err == ErrDeckNotFound:
+		response.SendError(r, w, http.StatusNotFound, ErrDeckNotFound.Error())
+		return
+	case err != nil:
+		log.Println(fmt.Sprintf("request id: %s failed to get deck: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+		return
+	}
+
+	response.SendBody(r, w, http.StatusOK, deck)
 }
 
-func (h *Handler) filterAttributes(p *Response, attr string) map[string]interface{} {
-	attributes := strings.Split(attr, ",")
-	rs := make(map[string]interface{})
+// RegisterPortHandler registers the embarkation port routes, mounted apart
+// from the passenger routes.
+func (h *Handler) RegisterPortHandler() *chi.Mux {
+	router := chi.NewRouter()
+	router.Get("/", h.Ports)
+	router.Get("/{code}", h.Port)
+	return router
+}
 
-	for i, a := range attributes {
-		attributes[i] = strings.TrimSpace(a)
+// Package 	godoc
+// @Summary Get ports
+// @Description Get the embarkation ports in boarding order with their coordinates, boarding date and the
+// @Description passenger and survivor counts of those who boarded there
+// @Tags    port
+// @ID 		port-get-all
+// @Produce json,application/geo+json,text/csv,application/x-ndjson,application/xml
+// @Param format query string false "Response format overriding the Accept header: json (default), geojson (a FeatureCollection of points), csv, ndjson or xml"
+// @Success 200 {object} []EmbarkationPort
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /port [get]
+func (h *Handler) Ports(w http.ResponseWriter, r *http.Request) {
+	// geojson is specific to ports, other formats are negotiated
+	geoJSON := r.URL.Query().Get("format") == "geojson"
+
+	ports, err := h.service.Ports(r.Context())
+	switch {
+	case err == nil && geoJSON:
+		response.SendGeoJSON(r, w, http.StatusOK, PortsFeatureCollection(ports))
+	case err == nil:
+		response.SendBody(r, w, http.StatusOK, ports)
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to get ports: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
 	}
+}
 
-	passengerValue := reflect.ValueOf(p).Elem()
-	passengerType := passengerValue.Type()
+// Package 	godoc
+// @Summary Get port
+// @Description Get an embarkation port with the passenger and survivor counts of those who boarded there per class
+// @Tags    port
+// @ID 		port-get
+// @Produce json,application/geo+json,text/csv,application/x-ndjson,application/xml
+// @Param code path string true "Port code (S, C, Q)"
+// @Param format query string false "Response format overriding the Accept header: json (default), geojson (a point Feature), csv, ndjson or xml"
+// @Success 200 {object} EmbarkationPort
+// @Failure 404 {object} response.Error
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /port/{code} [get]
+func (h *Handler) Port(w http.ResponseWriter, r *http.Request) {
+	// geojson is specific to ports, other formats are negotiated
+	geoJSON := r.URL.Query().Get("format") == "geojson"
 
-	for _, a := range attributes {
-		for i := 0; i < passengerValue.NumField(); i++ {
-			fieldValue := passengerValue.Field(i)
-			fieldType := passengerType.Field(i)
+	port, err := h.service.Port(r.Context(), Port(strings.ToUpper(chi.URLParam(r, "code"))))
+	switch {
+	case err == ErrPortNotFound:
+		response.SendError(r, w, http.StatusNotFound, ErrPortNotFound.Error())
+	case err == nil && geoJSON:
+		response.SendGeoJSON(r, w, http.StatusOK, port.Feature())
+	case err == nil:
+		response.SendBody(r, w, http.StatusOK, port)
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to get port: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
 
-			jsonField := fieldType.Tag.Get("json")
-			if jsonField != a {
-				continue
-			}
-			rs[jsonField] = fieldValue.Interface()
+func (h *Handler) renderParty(party *Party) *PartyResponse {
+	rs := &PartyResponse{Party: party, Members: make([]*MemberResponse, len(party.Members))}
+	for i, m := range party.Members {
+		rs.Members[i] = &MemberResponse{
+			Passenger:     h.view.render(m.Passenger),
+			FamilySize:    m.FamilySize,
+			PerPersonFare: m.PerPersonFare,
 		}
 	}
 	return rs
 }
 
-func (h *Handler) convertPassenger(source *Passenger) *Response {
-	var dest Response
-	// Directly assign values from source to destination
-	dest.PassengerId = source.PassengerId
-	dest.Survived = source.Survived
-	dest.Pclass = source.Pclass
-	dest.Name = source.Name
-	dest.Sex = source.Sex
-	dest.Age = source.Age
-	dest.SibSp = source.SibSp
-	dest.Parch = source.Parch
-	dest.Ticket = source.Ticket
-	dest.Fare = source.Fare
-	dest.Cabin = source.Cabin
-	dest.Embarked = source.Embarked
+// Package 	godoc
+// @Summary Create passenger
+// @Description Create a new passenger
+// @Tags    passenger
+// @ID 		passenger-create
+// @Accept  json
+// @Produce json
+// @Param passenger body Response true "Passenger"
+// @Success 201 {object} Response
+// @Failure 400 {object} response.Error
+// @Failure 409 {object} response.Error
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	// the format is checked before the store is changed
+	if response.SendNotAcceptable(r, w) {
+		return
+	}
 
-	return &dest
+	var rs Response
+	if err := h.decodeBody(r, &rs); err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.validatePassenger(&rs); err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.service.Create(r.Context(), convertResponse(&rs))
+	switch {
+	case err == ErrPassengerExists:
+		response.SendError(r, w, http.StatusConflict, ErrPassengerExists.Error())
+		return
+	case err != nil:
+		log.Println(fmt.Sprintf("request id: %s failed to create passenger: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, strconv.Itoa(rs.PassengerId)))
+	response.SendBody(r, w, http.StatusCreated, &rs)
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+// Package 	godoc
+// @Summary Update passenger
+// @Description Replace passenger by ID number
+// @Tags    passenger
+// @ID 		passenger-update
+// @Accept  json
+// @Produce json
+// @Param id path int true "Passenger ID"
+// @Param passenger body Response true "Passenger"
+// @Success 200 {object} Response
+// @Failure 400 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/{id} [put]
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	// the format is checked before the store is changed
+	if response.SendNotAcceptable(r, w) {
+		return
+	}
+
+	pid, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, ErrInvalidID.Error())
+		return
+	}
+
+	var rs Response
+	if err := h.decodeBody(r, &rs); err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.update(w, r, pid, &rs)
+}
+
+// Package 	godoc
+// @Summary Patch passenger
+// @Description Update the provided fields of passenger by ID number
+// @Tags    passenger
+// @ID 		passenger-patch
+// @Accept  json
+// @Produce json
+// @Param id path int true "Passenger ID"
+// @Param passenger body Response true "Passenger fields"
+// @Success 200 {object} Response
+// @Failure 400 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/{id} [patch]
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	// the format is checked before the store is changed
+	if response.SendNotAcceptable(r, w) {
+		return
+	}
+
+	pid, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, ErrInvalidID.Error())
+		return
+	}
+
+	// the stored values are merged as they are, imputation only applies on read
+	storePassenger, err := h.service.Get(r.Context(), pid)
+	switch {
+	case err == ErrPassengerNotFound:
+		response.SendError(r, w, http.StatusNotFound, ErrPassengerNotFound.Error())
+		return
+	case err != nil:
+		log.Println(fmt.Sprintf("request id: %s failed to get passenger: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+		return
+	}
+
+	// fields missing from the body keep their stored values
+	rs := convertPassenger(storePassenger)
+	if err := h.decodeBody(r, rs); err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.update(w, r, pid, rs)
+}
+
+// Package 	godoc
+// @Summary Delete passenger
+// @Description Delete passenger by ID number
+// @Tags    passenger
+// @ID 		passenger-delete
+// @Param id path int true "Passenger ID"
+// @Success 204
+// @Failure 400 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/{id} [delete]
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, ErrInvalidID.Error())
+		return
+	}
+
+	err = h.service.Delete(r.Context(), pid)
+	switch {
+	case err == ErrPassengerNotFound:
+		response.SendError(r, w, http.StatusNotFound, ErrPassengerNotFound.Error())
+	case err != nil:
+		log.Println(fmt.Sprintf("request id: %s failed to delete passenger: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	default:
+		response.SendStatus(r, w, http.StatusNoContent)
+	}
+}
+
+// Package 	godoc
+// @Summary Import passengers
+// @Description Import passengers from a csv or ndjson file, rows are validated one by one and the valid
+// @Description ones are written at once. Csv columns are attribute names or dataset headers, derived columns
+// @Description such as title or deck are ignored, ndjson lines are passengers as in the body of create.
+// @Description Rows which fail validation, repeat an id or conflict with a stored passenger are reported by line.
+// @Description Replace imports with such rows write nothing and are answered with the report and 422
+// @Tags    passenger
+// @ID 		passenger-import
+// @Accept  multipart/form-data
+// @Produce json
+// @Param file formData file true "Passengers file, csv (text/csv, .csv) or ndjson (application/x-ndjson, .ndjson)"
+// @Param mode query string false "upsert (default) creates and replaces passengers, insert only creates them, replace replaces the whole store"
+// @Param dryRun query bool false "Validate and report without writing"
+// @Success 200 {object} ImportReport
+// @Failure 400 {object} response.Error
+// @Failure 413 {object} response.Error
+// @Failure 415 {object} response.Error
+// @Failure 422 {object} ImportReport
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/import [post]
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	// the format is checked before the store is changed
+	if response.SendNotAcceptable(r, w) {
+		return
+	}
+
+	mode := ImportModeUpsert
+	if param := r.URL.Query().Get("mode"); len(param) > 0 {
+		mode = ImportMode(param)
+	}
+	if !mode.Valid() {
+		response.SendError(r, w, http.StatusBadRequest, "mode must be one of upsert, insert, replace")
+		return
+	}
+	var dryRun bool
+	if param := r.URL.Query().Get("dryRun"); len(param) > 0 {
+		var err error
+		if dryRun, err = strconv.ParseBool(param); err != nil {
+			response.SendError(r, w, http.StatusBadRequest, "dryRun must be true or false")
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	rows, err := h.parseImport(r)
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig):
+		response.SendError(r, w, http.StatusRequestEntityTooLarge, ErrImportTooBig.Error())
+		return
+	case err == ErrImportFormat:
+		response.SendError(r, w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	case err != nil:
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.service.Import(r.Context(), Import{Mode: mode, DryRun: dryRun, Rows: rows})
+	switch err {
+	case nil:
+		response.SendBody(r, w, http.StatusOK, report)
+	case ErrImportRejected:
+		response.SendBody(r, w, http.StatusUnprocessableEntity, report)
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to import passengers: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Get fare histogram histogram
+// @Description Get histogram represention of number of passengers in each precentile.
+// @Description Every bin holds the fares in (lower, upper], the first bin also holds the lowest fare
+// @Tags    passenger
+// @ID 		passenger-fare-histogram
+// @Produce json
+// @Param percentiles query []number false "Increasing percentile cut points in (0, 100], defaults to 25,50,75,100" collectionFormat(csv)
+// @Success 200 {object} histogram.Histogram
+// @Failure 400 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/fare/histogram/percentile [get]
+func (h *Handler) FareHistogram(w http.ResponseWriter, r *http.Request) {
+	percentiles := histogram.DefaultPercentiles
+	if param := r.URL.Query().Get("percentiles"); len(param) > 0 {
+		var err error
+		if percentiles, err = parsePercentiles(param); err != nil {
+			response.SendError(r, w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	fareHistogram, err := h.service.FarePercentileHistogram(r.Context(), percentiles)
+	switch {
+	case err == nil:
+		response.SendBody(r, w, http.StatusOK, fareHistogram)
+	case errors.Is(err, histogram.ErrEmptyData):
+		response.SendError(r, w, http.StatusNotFound, err.Error())
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to get fare histogram histogram: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Get field histogram
+// @Description Get histogram of a numeric passenger field binned by the chosen strategy, passengers missing the
+// @Description value are skipped. Equal-width bins take either a bin count or a bin width, sturges, scott and
+// @Description freedman-diaconis pick the bins from the data and log spaces bins evenly on a log(1+x) scale.
+// @Description Width based bins hold the values in [lower, upper), the last bin also holds the highest value.
+// @Description With splitBy every category gets a series counted over the same bins
+// @Tags    passenger
+// @ID 		passenger-histogram
+// @Produce json
+// @Param field path string true "One of age, fare, siblings-spouses, parents-children"
+// @Param splitBy query string false "One of survived, class, sex, embarked"
+// @Param strategy query string false "One of percentile, equal-width (default), sturges, scott, freedman-diaconis, log"
+// @Param bins query int false "Number of bins for equal-width (default 10) and log (default sturges) strategies"
+// @Param width query number false "Bin width for the equal-width strategy"
+// @Param percentiles query []number false "Percentile cut points for the percentile strategy" collectionFormat(csv)
+// @Success 200 {object} histogram.Histogram
+// @Failure 400 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/{field}/histogram [get]
+func (h *Handler) Histogram(w http.ResponseWriter, r *http.Request) {
+	field := chi.URLParam(r, "field")
+	if !histogramFields[field] {
+		response.SendError(r, w, http.StatusBadRequest, ErrInvalidHistogramField.Error())
+		return
+	}
+	splitBy := r.URL.Query().Get("splitBy")
+	if len(splitBy) > 0 && !splitFields[splitBy] {
+		response.SendError(r, w, http.StatusBadRequest, ErrInvalidSplitField.Error())
+		return
+	}
+
+	opts, err := parseHistogramOptions(r.URL.Query())
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fieldHistogram, err := h.service.Histogram(r.Context(), field, splitBy, opts)
+	switch {
+	case err == nil:
+		response.SendBody(r, w, http.StatusOK, fieldHistogram)
+	case errors.Is(err, histogram.ErrEmptyData):
+		response.SendError(r, w, http.StatusNotFound, err.Error())
+	case errors.Is(err, histogram.ErrTooManyBins), errors.Is(err, histogram.ErrNegativeData):
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to get %s histogram: %v",
+			middleware.GetReqID(r.Context()), field, err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Aggregate passengers
+// @Description Group the passengers matching the filters and compute metrics for every group.
+// @Description Metrics are count or one of count, sum, avg, mean, min, max applied to a field e.g. avg(fare),
+// @Description missing values are skipped. Filters are the same as for the passenger list
+// @Tags    passenger
+// @ID 		passenger-aggregate
+// @Produce json,text/csv,application/x-ndjson,application/xml
+// @Param groupBy query []string false "Fields to group by e.g. class,sex" collectionFormat(csv)
+// @Param metrics query []string false "Metrics to compute, defaults to count e.g. count,avg(fare),mean(survived)" collectionFormat(csv)
+// @Param format query string false "Response format overriding the Accept header: json (default), csv, ndjson or xml"
+// @Success 200 {array} object
+// @Failure 400 {object} response.Error
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/aggregate [get]
+func (h *Handler) Aggregate(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	filter, err := h.parseFilter(values, aggregateParams)
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	aggregation, err := NewAggregation(filter, splitParam(values.Get("groupBy")), splitParam(values.Get("metrics")))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groups, err := h.service.Aggregate(r.Context(), aggregation)
+	switch err {
+	case nil:
+		names := append([]string{}, aggregation.GroupBy...)
+		for _, m := range aggregation.Metrics {
+			names = append(names, m.String())
+		}
+
+		rs := make([]*Row, 0, len(groups))
+		for _, g := range groups {
+			row := &Row{names: names, values: make([]interface{}, 0, len(names))}
+			row.values = append(row.values, g.Keys...)
+			for _, v := range g.Values {
+				row.values = append(row.values, v)
+			}
+			rs = append(rs, row)
+		}
+		response.SendBody(r, w, http.StatusOK, rs)
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to aggregate passengers: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Get passenger statistics
+// @Description Summarise numeric fields of the passengers matching the filters, optionally per group.
+// @Description Every summary holds count, missing, mean, std, min, q1, median, q3, max, iqr, skew and kurtosis,
+// @Description missing values (e.g. unknown ages) are counted but skipped. Filters are the same as for the passenger list
+// @Tags    passenger
+// @ID 		passenger-statistics
+// @Produce json,text/csv,application/x-ndjson,application/xml
+// @Param fields query []string false "Numeric fields to summarise, defaults to age,fare,siblings-spouses,parents-children" collectionFormat(csv)
+// @Param groupBy query []string false "Fields to group by e.g. class" collectionFormat(csv)
+// @Param format query string false "Response format overriding the Accept header: json (default), csv, ndjson or xml"
+// @Success 200 {array} object
+// @Failure 400 {object} response.Error
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/stats [get]
+func (h *Handler) Statistics(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	filter, err := h.parseFilter(values, statisticsParams)
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	statistics, err := NewStatistics(filter, splitParam(values.Get("fields")), splitParam(values.Get("groupBy")))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groups, err := h.service.Statistics(r.Context(), statistics)
+	switch err {
+	case nil:
+		names := append(append([]string{}, statistics.GroupBy...), "stats")
+		rs := make([]*Row, 0, len(groups))
+		for _, g := range groups {
+			summaries := &Row{names: statistics.Fields, values: make([]interface{}, 0, len(g.Summaries))}
+			for _, s := range g.Summaries {
+				summaries.values = append(summaries.values, s)
+			}
+			rs = append(rs, &Row{names: names, values: append(append([]interface{}{}, g.Keys...), summaries)})
+		}
+		response.SendBody(r, w, http.StatusOK, rs)
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to get passenger statistics: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Get passenger crosstab
+// @Description Count the passengers matching the filters by two categorical fields, with row and column
+// @Description percentages, totals and Pearson's chi-square test of independence (null when the table has
+// @Description fewer than two non empty rows or columns). Filters are the same as for the passenger list
+// @Tags    passenger
+// @ID 		passenger-crosstab
+// @Produce json,text/csv,application/x-ndjson,application/xml
+// @Param rows query string true "One of survived, class, sex, embarked, siblings-spouses, parents-children, title, deck"
+// @Param cols query string true "One of survived, class, sex, embarked, siblings-spouses, parents-children, title, deck"
+// @Param format query string false "Response format overriding the Accept header: json (default), csv, ndjson or xml"
+// @Success 200 {object} Table
+// @Failure 400 {object} response.Error
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/crosstab [get]
+func (h *Handler) Crosstab(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	filter, err := h.parseFilter(values, crosstabParams)
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	crosstab, err := NewCrosstab(filter, values.Get("rows"), values.Get("cols"))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	table, err := h.service.Crosstab(r.Context(), crosstab)
+	switch err {
+	case nil:
+		response.SendBody(r, w, http.StatusOK, table)
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to get passenger crosstab: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Get passenger correlation matrices
+// @Description Compute the Pearson and Spearman correlation matrices of numeric passenger fields, with sex encoded
+// @Description 1 for male and 0 for female. Missing values are handled pairwise, pairs holds the number of
+// @Description passengers each coefficient is computed over. Filters are the same as for the passenger list
+// @Tags    passenger
+// @ID 		passenger-correlation
+// @Produce json,text/csv,application/x-ndjson,application/xml
+// @Param fields query string false "Comma separated numeric fields or sex, defaults to survived, class, age, siblings-spouses, parents-children, fare, sex"
+// @Param format query string false "Response format overriding the Accept header: json (default), csv, ndjson or xml"
+// @Success 200 {object} CorrelationMatrix
+// @Failure 400 {object} response.Error
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /passenger/correlation [get]
+func (h *Handler) Correlation(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	filter, err := h.parseFilter(values, correlationParams)
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	correlation, err := NewCorrelation(filter, splitParam(values.Get("fields")))
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	matrix, err := h.service.Correlation(r.Context(), correlation)
+	if err != nil {
+		log.Println(fmt.Sprintf("request id: %s failed to get passenger correlation: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+		return
+	}
+
+	// the matrix renders its own csv records
+	response.SendBody(r, w, http.StatusOK, matrix)
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request, pid int, rs *Response) {
+	switch rs.PassengerId {
+	case 0:
+		rs.PassengerId = pid
+	case pid:
+	default:
+		response.SendError(r, w, http.StatusBadRequest, ErrIDMismatch.Error())
+		return
+	}
+	if err := h.validatePassenger(rs); err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := h.service.Update(r.Context(), convertResponse(rs))
+	switch {
+	case err == ErrPassengerNotFound:
+		response.SendError(r, w, http.StatusNotFound, ErrPassengerNotFound.Error())
+	case err != nil:
+		log.Println(fmt.Sprintf("request id: %s failed to update passenger: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	default:
+		response.SendBody(r, w, http.StatusOK, rs)
+	}
+}
+
+func (h *Handler) decodeBody(r *http.Request, rs *Response) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(rs); err != nil {
+		return fmt.Errorf("%s: %s", ErrInvalidBody.Error(), err.Error())
+	}
+	return nil
+}
+
+// parseImport parses the rows of the file field of a multipart import.
+func (h *Handler) parseImport(r *http.Request) ([]*ImportRow, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, ErrInvalidImport
+	}
+
+	for {
+		part, err := reader.NextPart()
+		switch {
+		case err == io.EOF:
+			return nil, ErrInvalidImport
+		case err != nil:
+			return nil, fmt.Errorf("%s: %w", ErrInvalidImport.Error(), err)
+		case part.FormName() != "file":
+			continue
+		}
+
+		var rows []*ImportRow
+		switch importFormat(part) {
+		case response.FormatCSV:
+			rows, err = h.parseImportCSV(part)
+		case response.FormatNDJSON:
+			rows, err = h.parseImportNDJSON(part)
+		default:
+			return nil, ErrImportFormat
+		}
+		if err == nil && len(rows) == 0 {
+			err = ErrImportEmpty
+		}
+		return rows, err
+	}
+}
+
+// importFormat is the format of an import file part, empty when unsupported.
+func importFormat(part *multipart.Part) string {
+	mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return response.FormatCSV
+	case "application/x-ndjson", "application/ndjson":
+		return response.FormatNDJSON
+	}
+
+	// browsers and tools often send files as application/octet-stream
+	switch strings.ToLower(path.Ext(part.FileName())) {
+	case ".csv":
+		return response.FormatCSV
+	case ".ndjson", ".jsonl":
+		return response.FormatNDJSON
+	}
+	return ""
+}
+
+// parseImportCSV parses csv rows, the header names the column of every field.
+// Lines are the file lines, the header being the first one.
+func (h *Handler) parseImportCSV(file io.Reader) ([]*ImportRow, error) {
+	reader := csv.NewReader(file)
+	// rows with missing or extra cells are reported like other invalid rows
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	switch {
+	case err == io.EOF:
+		return nil, ErrImportEmpty
+	case err != nil:
+		return nil, fmt.Errorf("import csv is not valid: %w", err)
+	}
+
+	columns := make([]string, len(header))
+	found := make(map[string]bool)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		prefix, _, _ := strings.Cut(name, ".")
+		switch {
+		case len(importColumns[name]) > 0:
+			columns[i] = importColumns[name]
+			found[columns[i]] = true
+		case !derivedColumns[prefix]:
+			return nil, fmt.Errorf("unknown import column provided '%s'", header[i])
+		}
+	}
+	for _, attribute := range []string{"id", "survived", "class", "name", "sex", "age", "siblings-spouses",
+		"parents-children", "ticket", "fare", "cabin", "embarked"} {
+		if !found[attribute] {
+			return nil, fmt.Errorf("import column '%s' is missing", attribute)
+		}
+	}
+
+	var rows []*ImportRow
+	for {
+		record, err := reader.Read()
+		switch {
+		case err == io.EOF:
+			return rows, nil
+		case err != nil:
+			return nil, fmt.Errorf("import csv is not valid: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		row := &ImportRow{Line: line}
+		rows = append(rows, row)
+		if len(record) != len(header) {
+			row.Errors = append(row.Errors, fmt.Sprintf("row has %d columns, header has %d", len(record), len(header)))
+			continue
+		}
+
+		var rs Response
+		for i, value := range record {
+			if len(columns[i]) == 0 {
+				continue
+			}
+			if err := setImportCell(importField(&rs, columns[i]), header[i], value); err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			} else if columns[i] == "id" {
+				pid := rs.PassengerId
+				row.ID = &pid
+			}
+		}
+		h.validateImportRow(row, &rs)
+	}
+}
+
+// parseImportNDJSON parses a passenger per line, blank lines are skipped.
+func (h *Handler) parseImportNDJSON(file io.Reader) ([]*ImportRow, error) {
+	var rows []*ImportRow
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxImportSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		row := &ImportRow{Line: line}
+		rows = append(rows, row)
+
+		var rs Response
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&rs)
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr):
+			// decoding goes on after type errors so the id is known unless it is the invalid field
+			row.Errors = append(row.Errors, fmt.Sprintf("%s must be of type %s, not %s", typeErr.Field,
+				typeErr.Type, typeErr.Value))
+			if typeErr.Field != "id" {
+				pid := rs.PassengerId
+				row.ID = &pid
+			}
+			continue
+		case err != nil:
+			row.Errors = append(row.Errors, fmt.Sprintf("%s: %s", ErrInvalidBody.Error(), err.Error()))
+			continue
+		}
+		pid := rs.PassengerId
+		row.ID = &pid
+		h.validateImportRow(row, &rs)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// validateImportRow sets the passenger of a row whose cells are all valid.
+func (h *Handler) validateImportRow(row *ImportRow, rs *Response) {
+	if len(row.Errors) > 0 {
+		return
+	}
+	if err := h.validatePassenger(rs); err != nil {
+		row.Errors = append(row.Errors, err.Error())
+		return
+	}
+	row.Passenger = convertResponse(rs)
+}
+
+// importField is the Response field of an import attribute.
+func importField(rs *Response, attribute string) interface{} {
+	switch attribute {
+	case "id":
+		return &rs.PassengerId
+	case "survived":
+		return &rs.Survived
+	case "class":
+		return &rs.Pclass
+	case "name":
+		return &rs.Name
+	case "sex":
+		return &rs.Sex
+	case "age":
+		return &rs.Age
+	case "siblings-spouses":
+		return &rs.SibSp
+	case "parents-children":
+		return &rs.Parch
+	case "ticket":
+		return &rs.Ticket
+	case "fare":
+		return &rs.Fare
+	case "cabin":
+		return &rs.Cabin
+	default:
+		return &rs.Embarked
+	}
+}
+
+// setImportCell parses the value of a csv cell into dest, an int, float or string field.
+func setImportCell(dest interface{}, column, value string) error {
+	var err error
+	switch dest := dest.(type) {
+	case *int:
+		if *dest, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s '%s' is not an integer", column, value)
+		}
+	case *float64:
+		if *dest, err = strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return fmt.Errorf("%s '%s' is not a number", column, value)
+		}
+	case *string:
+		*dest = value
+	}
+	return nil
+}
+
+func (h *Handler) validatePassenger(p *Response) error {
+	switch {
+	case p.PassengerId <= 0:
+		return fmt.Errorf("id must be a positive integer")
+	case p.Survived != 0 && p.Survived != 1:
+		return fmt.Errorf("survived must be 0 or 1")
+	case !Class(p.Pclass).Valid():
+		return fmt.Errorf("class must be 1, 2 or 3")
+	case len(strings.TrimSpace(p.Name)) == 0:
+		return fmt.Errorf("name must not be empty")
+	case !Sex(p.Sex).Valid():
+		return fmt.Errorf("sex must be 'male' or 'female'")
+	case p.SibSp < 0:
+		return fmt.Errorf("siblings-spouses must not be negative")
+	case p.Parch < 0:
+		return fmt.Errorf("parents-children must not be negative")
+	case p.Fare < 0:
+		return fmt.Errorf("fare must not be negative")
+	case len(p.Embarked) > 0 && !Port(p.Embarked).Valid():
+		return fmt.Errorf("embarked must be one of 'S', 'C', 'Q' or empty")
+	}
+
+	if len(p.Age) > 0 {
+		age, err := strconv.ParseFloat(p.Age, 64)
+		if err != nil || age < 0 {
+			return fmt.Errorf("age must be empty or a non-negative number")
+		}
+	}
+	return nil
+}
+
+func (h *Handler) parseQuery(values url.Values) (Query, error) {
+	var query Query
+	filter, err := h.parseFilter(values, reservedParams)
+	if err != nil {
+		return Query{}, err
+	}
+	query.Filter = filter
+
+	attributes, err := h.parseAttributes(values.Get("attributes"))
+	if err != nil {
+		return Query{}, err
+	}
+	query.Attributes = attributes
+
+	if sortParam := values.Get("sort"); len(sortParam) > 0 {
+		visited := make(map[string]bool)
+		for _, key := range strings.Split(sortParam, ",") {
+			key = strings.TrimSpace(key)
+			name := strings.TrimPrefix(key, "-")
+			if visited[name] {
+				return Query{}, fmt.Errorf("sort field '%s' provided multiple times in query", name)
+			}
+			visited[name] = true
+
+			sortKey, err := NewSortKey(name, strings.HasPrefix(key, "-"))
+			if err != nil {
+				return Query{}, err
+			}
+			query.Sort = append(query.Sort, sortKey)
+		}
+	}
+
+	if limitParam := values.Get("limit"); len(limitParam) > 0 {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return Query{}, fmt.Errorf("limit must be an integer between 1 and %d", maxPageLimit)
+		}
+		query.Limit = limit
+	}
+
+	if cursorParam := values.Get("cursor"); len(cursorParam) > 0 {
+		cursor, err := query.DecodeCursor(cursorParam)
+		if err != nil {
+			return Query{}, err
+		}
+		query.Cursor = cursor
+	}
+
+	return query, nil
+}
+
+// pageLinks formats the next and prev page links in Link header format.
+func (h *Handler) pageLinks(r *http.Request, page *Page) string {
+	var links []string
+	for _, link := range []struct {
+		rel    string
+		cursor *Cursor
+	}{{"next", page.Next}, {"prev", page.Prev}} {
+		if link.cursor == nil {
+			continue
+		}
+		values := r.URL.Query()
+		values.Set("cursor", link.cursor.Encode())
+		u := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), link.rel))
+	}
+	return strings.Join(links, ", ")
+}
+
+func (h *Handler) parseFilter(query url.Values, reserved map[string]bool) (Filter, error) {
+	var keys []string
+	for key := range query {
+		if !reserved[key] {
+			keys = append(keys, key)
+		}
+	}
+	// keep conditions in a stable order
+	sort.Strings(keys)
+
+	var filter Filter
+	for _, key := range keys {
+		name, op, found := strings.Cut(key, ".")
+		if !found {
+			op = string(OperatorEq)
+		}
+
+		var values []string
+		for _, v := range query[key] {
+			for _, value := range strings.Split(v, ",") {
+				values = append(values, strings.TrimSpace(value))
+			}
+		}
+
+		c, err := NewCondition(name, Operator(op), values)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.Conditions = append(filter.Conditions, c)
+	}
+	return filter, nil
+}
+
+func (h *Handler) parseAttributes(attr string) ([]string, error) {
+	switch {
+	case len(attr) == 0:
+		return nil, nil
+	case len(attr) > maxAttributeParamLength:
+		return nil, fmt.Errorf("attributes query parameter is too long max length %d", maxAttributeParamLength)
+	}
+
+	attributes := strings.Split(attr, ",")
+
+	for i, a := range attributes {
+		attributes[i] = strings.TrimSpace(a)
+	}
+
+	visited := make(map[string]bool)
+	for _, attribute := range attributes {
+		if _, valid := h.view.attributes[attribute]; !valid {
+			return nil, fmt.Errorf("unknown attribute filter provided '%s' in query", attribute)
+		}
+		if _, found := visited[attribute]; found {
+			return nil, fmt.Errorf("attribute '%s' provided multiple times in query", attribute)
+		}
+		visited[attribute] = true
+	}
+	return attributes, nil
+}
+
+// parseImputation reads the imputed flag and impute rules, rules imply the
+// flag and the flag alone takes DefaultImputation. Nil means no imputation.
+func parseImputation(values url.Values) (*Imputation, error) {
+	imputed, spec := values.Get("imputed"), values.Get("impute")
+	switch imputed {
+	case "", "true", "false":
+	default:
+		return nil, fmt.Errorf("imputed must be true or false")
+	}
+
+	switch {
+	case imputed == "false" && len(spec) > 0:
+		return nil, fmt.Errorf("impute rules provided with imputed set to false")
+	case len(spec) > 0:
+		imputation, err := NewImputation(spec)
+		if err != nil {
+			return nil, err
+		}
+		return &imputation, nil
+	case imputed == "true":
+		imputation := DefaultImputation
+		return &imputation, nil
+	}
+	return nil, nil
+}
+
+func parsePercentiles(param string) ([]float64, error) {
+	var percentiles []float64
+	for _, v := range splitParam(param) {
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, histogram.ErrInvalidPercentiles
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, histogram.ValidatePercentiles(percentiles)
+}
+
+func parseHistogramOptions(values url.Values) (histogram.Options, error) {
+	opts := histogram.Options{Strategy: histogram.StrategyEqualWidth}
+	if strategy := values.Get("strategy"); len(strategy) > 0 {
+		opts.Strategy = histogram.Strategy(strategy)
+	}
+
+	if param := values.Get("bins"); len(param) > 0 {
+		bins, err := strconv.Atoi(param)
+		if err != nil || bins < 1 {
+			return histogram.Options{}, histogram.ErrInvalidBins
+		}
+		opts.Bins = bins
+	}
+	if param := values.Get("width"); len(param) > 0 {
+		width, err := strconv.ParseFloat(param, 64)
+		if err != nil || width <= 0 {
+			return histogram.Options{}, histogram.ErrInvalidWidth
+		}
+		opts.Width = width
+	}
+	if param := values.Get("percentiles"); len(param) > 0 {
+		percentiles, err := parsePercentiles(param)
+		if err != nil {
+			return histogram.Options{}, err
+		}
+		opts.Percentiles = percentiles
+	}
+
+	return opts, opts.Validate()
+}
+
+// splitParam splits a comma separated query parameter, an empty parameter has no values.
+func splitParam(param string) []string {
+	if len(param) == 0 {
+		return nil
+	}
+
+	values := strings.Split(param, ",")
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return values
+}
+
+func convertPassenger(source *Passenger) *Response {
+	var dest Response
+	// Directly assign values from source to destination
+	dest.PassengerId = source.PassengerId
+	dest.Survived = source.Survived
+	dest.Pclass = int(source.Pclass)
+	dest.Name = source.Name
+	dest.NameParts = ParseName(source.Name)
+	dest.Sex = string(source.Sex)
+	dest.Age = source.AgeString()
+	dest.SibSp = source.SibSp
+	dest.Parch = source.Parch
+	dest.Ticket = source.Ticket
+	dest.Fare = source.Fare
+	dest.Cabin = source.CabinString()
+	dest.Cabins = ParseCabins(source.CabinString())
+	dest.Embarked = source.EmbarkedString()
+	dest.Imputed = source.Imputed
+
+	return &dest
+}
+
+func convertPassengerV2(source *Passenger) *ResponseV2 {
+	var dest ResponseV2
+	// Directly assign values from source to destination
+	dest.PassengerId = source.PassengerId
+	dest.Survived = source.Survived
+	dest.Pclass = source.Pclass
+	dest.Name = source.Name
+	dest.NameParts = ParseName(source.Name)
+	dest.Sex = source.Sex
+	dest.Age = source.Age
+	dest.SibSp = source.SibSp
+	dest.Parch = source.Parch
+	dest.Ticket = source.Ticket
+	dest.Fare = source.Fare
+	dest.Cabin = source.Cabin
+	dest.Cabins = ParseCabins(source.CabinString())
+	dest.Embarked = source.Embarked
+	dest.Imputed = source.Imputed
+
+	return &dest
+}
+
+// convertResponse converts a validated v1 response, missing values are empty strings.
+func convertResponse(source *Response) *Passenger {
+	var dest Passenger
+	// Directly assign values from source to destination
+	dest.PassengerId = source.PassengerId
+	dest.Survived = source.Survived
+	dest.Pclass = Class(source.Pclass)
+	dest.Name = source.Name
+	dest.Sex = Sex(source.Sex)
+	dest.SibSp = source.SibSp
+	dest.Parch = source.Parch
+	dest.Ticket = source.Ticket
+	dest.Fare = source.Fare
+
+	if age, err := strconv.ParseFloat(source.Age, 64); err == nil {
+		dest.Age = &age
+	}
+	if len(source.Cabin) > 0 {
+		cabin := source.Cabin
+		dest.Cabin = &cabin
+	}
+	if len(source.Embarked) > 0 {
+		embarked := Port(source.Embarked)
+		dest.Embarked = &embarked
+	}
+
+	return &dest
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service, view: viewV1}
+}
+
+// NewHandlerV2 returns the read only handler serving the typed passenger representation.
+func NewHandlerV2(service Service) *Handler {
+	return &Handler{service: service, view: viewV2}
 }
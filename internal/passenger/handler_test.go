@@ -1,17 +1,23 @@
 package passenger
 
 import (
+	"bytes"
 	ctx "context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"github.com/go-chi/chi"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 	"titanic-api/pkg/histogram"
 	"titanic-api/pkg/response"
+	"titanic-api/pkg/stats"
 
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/stretchr/testify/mock"
@@ -31,161 +37,2385 @@ type MockService struct {
 	mock.Mock
 }
 
-func (ms *MockService) FarePercentileHistogram() (*histogram.Histogram, error) {
-	args := ms.Called()
+func (ms *MockService) FarePercentileHistogram(_ ctx.Context, percentiles []float64) (*histogram.Histogram, error) {
+	args := ms.Called(percentiles)
+	var res *histogram.Histogram
+	if args.Get(0) != nil {
+		res = args.Get(0).(*histogram.Histogram)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Histogram(_ ctx.Context, field, splitBy string, opts histogram.Options) (*histogram.Histogram, error) {
+	args := ms.Called(field, splitBy, opts)
 	var res *histogram.Histogram
 	if args.Get(0) != nil {
 		res = args.Get(0).(*histogram.Histogram)
 	}
-	return res, args.Error(1)
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Aggregate(_ ctx.Context, aggregation Aggregation) ([]*Group, error) {
+	args := ms.Called(aggregation)
+	var res []*Group
+	if args.Get(0) != nil {
+		res = args.Get(0).([]*Group)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Statistics(_ ctx.Context, statistics Statistics) ([]*StatisticsGroup, error) {
+	args := ms.Called(statistics)
+	var res []*StatisticsGroup
+	if args.Get(0) != nil {
+		res = args.Get(0).([]*StatisticsGroup)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Ticket(_ ctx.Context, ticket string) (*Party, error) {
+	args := ms.Called(ticket)
+	var res *Party
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Party)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Party(_ ctx.Context, pid int) (*Party, error) {
+	args := ms.Called(pid)
+	var res *Party
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Party)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Decks(_ ctx.Context) ([]*Deck, error) {
+	args := ms.Called()
+	var res []*Deck
+	if args.Get(0) != nil {
+		res = args.Get(0).([]*Deck)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Deck(_ ctx.Context, deck string) (*Deck, error) {
+	args := ms.Called(deck)
+	var res *Deck
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Deck)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Ports(_ ctx.Context) ([]*EmbarkationPort, error) {
+	args := ms.Called()
+	var res []*EmbarkationPort
+	if args.Get(0) != nil {
+		res = args.Get(0).([]*EmbarkationPort)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Port(_ ctx.Context, code Port) (*EmbarkationPort, error) {
+	args := ms.Called(code)
+	var res *EmbarkationPort
+	if args.Get(0) != nil {
+		res = args.Get(0).(*EmbarkationPort)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Crosstab(_ ctx.Context, crosstab Crosstab) (*Table, error) {
+	args := ms.Called(crosstab)
+	var res *Table
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Table)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Correlation(_ ctx.Context, correlation Correlation) (*CorrelationMatrix, error) {
+	args := ms.Called(correlation)
+	var res *CorrelationMatrix
+	if args.Get(0) != nil {
+		res = args.Get(0).(*CorrelationMatrix)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Get(_ ctx.Context, pid int) (*Passenger, error) {
+	args := ms.Called(pid)
+	var res *Passenger
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Passenger)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) GetAll(_ ctx.Context, query Query) (*Page, error) {
+	args := ms.Called(query)
+	var res *Page
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Page)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Iterate(_ ctx.Context, query Query) (PassengerIterator, int, error) {
+	args := ms.Called(query)
+	var res PassengerIterator
+	if args.Get(0) != nil {
+		res = args.Get(0).(PassengerIterator)
+	}
+	return res, args.Int(1), args.Error(2)
+}
+
+func (ms *MockService) Fit(_ ctx.Context, imputation Imputation) (*Imputer, error) {
+	args := ms.Called(imputation)
+	var res *Imputer
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Imputer)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Impute(_ ctx.Context, imputation Imputation, passengers []*Passenger) ([]*Passenger, error) {
+	args := ms.Called(imputation, passengers)
+	var res []*Passenger
+	if args.Get(0) != nil {
+		res = args.Get(0).([]*Passenger)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Create(_ ctx.Context, p *Passenger) error {
+	args := ms.Called(p)
+	return args.Error(0)
+}
+
+func (ms *MockService) Update(_ ctx.Context, p *Passenger) error {
+	args := ms.Called(p)
+	return args.Error(0)
+}
+
+func (ms *MockService) Delete(_ ctx.Context, pid int) error {
+	args := ms.Called(pid)
+	return args.Error(0)
+}
+
+func (ms *MockService) Import(_ ctx.Context, batch Import) (*ImportReport, error) {
+	args := ms.Called(batch)
+	var res *ImportReport
+	if args.Get(0) != nil {
+		res = args.Get(0).(*ImportReport)
+	}
+	return res, args.Error(1)
+}
+
+// pre test setup function
+func setup() {
+	mService = new(MockService)
+	handler = NewHandler(mService)
+}
+
+/*
+Test functions
+*/
+func TestHandlerGetAll_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(3)
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Iterate", Query{}).Return(newSliceIterator(ctx.Background(), passengers), 3, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var p []*Response
+			err := json.NewDecoder(w.Body).Decode(&p)
+			So(err, ShouldBeNil)
+			So(len(p), ShouldEqual, len(passengers))
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_ValidRequest_ResponseInternalError(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Iterate", Query{}).Return(nil, 0, errors.New("error"))
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 500", func() {
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		})
+		Convey("Result As Expected", func() {
+			var errorResp response.Error
+			err := json.NewDecoder(w.Body).Decode(&errorResp)
+			So(err, ShouldBeNil)
+			So(errorResp.Message, ShouldEqual, response.ErrInternalFailure.Error())
+			So(errorResp.Code, ShouldEqual, http.StatusInternalServerError)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_ValidRequestWithFilter_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(2)
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger?survived=1&embarked=S,C&age.gte=18", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Iterate", mock.MatchedBy(func(q Query) bool {
+		f := q.Filter
+		return len(f.Conditions) == 3 &&
+			f.Conditions[0].Field == "age" && f.Conditions[0].Operator == OperatorGte &&
+			f.Conditions[1].Field == "embarked" && len(f.Conditions[1].Values) == 2 &&
+			f.Conditions[2].Field == "survived" && f.Conditions[2].Operator == OperatorEq
+	})).Return(newSliceIterator(ctx.Background(), passengers), 2, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var p []*Response
+			err := json.NewDecoder(w.Body).Decode(&p)
+			So(err, ShouldBeNil)
+			So(len(p), ShouldEqual, len(passengers))
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_InvalidFilter_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger?fare.contains=10", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 400", func() {
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Code, ShouldEqual, http.StatusBadRequest)
+			So(rs.Message, ShouldContainSubstring, "contains")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_ValidRequestImputed_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(2)
+	passengers[1].Age = nil
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger?imputed=true&attributes=id,age", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// imputation reads whole passengers whatever the projection
+	mService.On("Iterate", Query{}).Return(newSliceIterator(ctx.Background(), passengers), 2, nil /* error */)
+	// the median age of the passengers the imputation is fitted on is 28.5
+	fitted := createPassengers(2)
+	*fitted[1].Age = 27
+	mService.On("Fit", DefaultImputation).Return(DefaultImputation.Fit(fitted), nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Body.String(), ShouldEqual, `[{"id":0,"age":"30"},{"id":1,"age":"28.5","imputed":["age"]}]`+"\n")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_InvalidImputation_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, rawQuery := range []string{"imputed=yes", "impute=fare:mean", "impute=age:mode", "impute=age",
+		"impute=age:median:ticket", "impute=age:mean,age:median", "imputed=false&impute=age:mean"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.GetAll(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_NegotiatedFormat_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(2)
+	// csv and xml need the whole body, ndjson is streamed
+	mService.On("GetAll", mock.Anything).Return(&Page{Passengers: passengers, Total: 2}, nil /* error */)
+	mService.On("Iterate", mock.Anything).Return(newSliceIterator(ctx.Background(), passengers), 2, nil /* error */)
+
+	for _, tc := range []struct {
+		rawQuery, accept, contentType, body string
+	}{
+		{rawQuery: "attributes=id,fare,cabin", accept: "text/csv", contentType: "text/csv",
+			body: "id,fare,cabin\n0,50.25,C123\n1,50.25,C123\n"},
+		{rawQuery: "attributes=id,title", accept: "application/xml;q=0.5, application/x-ndjson",
+			contentType: "application/x-ndjson", body: `{"id":0,"title":""}` + "\n" + `{"id":1,"title":""}` + "\n"},
+		{rawQuery: "attributes=id,age&format=xml", accept: "text/csv", contentType: "application/xml",
+			body: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				"<response><item><id>0</id><age>30</age></item><item><id>1</id><age>30</age></item></response>"},
+	} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger?"+tc.rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept", tc.accept)
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.GetAll(w, r)
+
+		// then
+		Convey("Test handler "+tc.rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 200", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("Response As Expected", func() {
+				So(w.Header().Get("Content-Type"), ShouldStartWith, tc.contentType)
+				So(w.Body.String(), ShouldEqual, tc.body)
+			})
+		})
+	}
+}
+
+func TestHandlerGetAll_EmptyCSV_ResponseHeader(t *testing.T) {
+	setup()
+
+	mService.On("GetAll", mock.Anything).Return(&Page{Passengers: []*Passenger{}}, nil /* error */)
+
+	for rawQuery, body := range map[string]string{
+		"survived=1&attributes=id,name,title": "id,name,title\n",
+		"survived=1":                          "id,survived,class,name,name-parts.surname",
+	} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept", "text/csv")
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.GetAll(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 200", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("Header Is Kept", func() {
+				So(w.Body.String(), ShouldStartWith, body)
+				So(strings.Count(w.Body.String(), "\n"), ShouldEqual, 1)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_UnsupportedFormat_ResponseNotAcceptable(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(1)
+	mService.On("GetAll", mock.Anything).Return(&Page{Passengers: passengers, Total: 1}, nil /* error */)
+
+	for rawQuery, accept := range map[string]string{"format=yaml": "", "": "image/png, application/json;q=0"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Accept", accept)
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.GetAll(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+accept+"\n", t, func() {
+			Convey("Status Code Should Be 406", func() {
+				So(w.Code, ShouldEqual, http.StatusNotAcceptable)
+			})
+			Convey("Response As Expected", func() {
+				var rs *response.Error
+				err := json.NewDecoder(w.Body).Decode(&rs)
+				So(err, ShouldBeNil)
+				So(rs.Message, ShouldEqual, response.ErrNotAcceptable.Error())
+			})
+		})
+	}
+}
+
+func TestHandlerGetAll_ValidRequestWithPage_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(3)
+	query := Query{Sort: []SortKey{{Field: "fare", Desc: true}}, Limit: 2}
+	page := &Page{
+		Passengers: passengers[:2],
+		Total:      3,
+		Next:       query.NewCursor(passengers[1], false),
+	}
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger?sort=-fare&limit=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("GetAll", query).Return(page, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Headers As Expected", func() {
+			So(w.Header().Get("X-Total-Count"), ShouldEqual, "3")
+			So(w.Header().Get("Link"), ShouldContainSubstring, "cursor="+page.Next.Encode())
+			So(w.Header().Get("Link"), ShouldContainSubstring, `rel="next"`)
+			So(w.Header().Get("Link"), ShouldNotContainSubstring, `rel="prev"`)
+		})
+		Convey("Response As Expected", func() {
+			var p []*Response
+			err := json.NewDecoder(w.Body).Decode(&p)
+			So(err, ShouldBeNil)
+			So(len(p), ShouldEqual, 2)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_InvalidPage_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, rawQuery := range []string{"limit=0", "limit=abc", "sort=fare,-fare", "sort=unknown", "cursor=invalid"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.GetAll(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_ValidRequestWithAttributes_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(2)
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger?attributes=name,%20id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Iterate", Query{Attributes: []string{"name", "id"}}).Return(newSliceIterator(ctx.Background(), passengers), 2,
+		nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Body.String(), ShouldStartWith, `[{"name":"John Doe","id":0},{"name":"John Doe","id":1}]`)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_DeadlineExceeded_ResponseGatewayTimeout(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Iterate", Query{}).Return(nil, 0, ctx.DeadlineExceeded)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 504", func() {
+			So(w.Code, ShouldEqual, http.StatusGatewayTimeout)
+		})
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Message, ShouldEqual, response.ErrTimeout.Error())
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGetAll_Streamed_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(2)
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger?attributes=id&format=ndjson", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Iterate", Query{Attributes: []string{"id"}}).Return(newSliceIterator(ctx.Background(), passengers), 5,
+		nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Header().Get("X-Total-Count"), ShouldEqual, "5")
+			So(w.Header().Get("Content-Type"), ShouldEqual, "application/x-ndjson")
+			So(w.Body.String(), ShouldEqual, `{"id":0}`+"\n"+`{"id":1}`+"\n")
+			So(w.Flushed, ShouldBeTrue)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+// failingIterator fails once its passengers are read.
+type failingIterator struct {
+	PassengerIterator
+}
+
+func (it *failingIterator) Err() error {
+	return errors.New("error")
+}
+
+func TestHandlerGetAll_StreamFailure_ResponseUnterminated(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(1)
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger?attributes=id", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Iterate", Query{Attributes: []string{"id"}}).Return(
+		&failingIterator{newSliceIterator(ctx.Background(), passengers)}, 2, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.GetAll(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response Ends Early", func() {
+			So(w.Body.String(), ShouldEqual, `[{"id":0}`)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerExport_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(2)
+	passengers[1].Age = nil
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/export?survived=1&attributes=id,name,age", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Iterate", mock.MatchedBy(func(q Query) bool {
+		return len(q.Filter.Conditions) == 1 && q.Filter.Conditions[0].Field == "survived" && q.Attributes == nil
+	})).Return(newSliceIterator(ctx.Background(), passengers), 2, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Export(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Header().Get("X-Total-Count"), ShouldEqual, "2")
+			So(w.Header().Get("Content-Type"), ShouldStartWith, "text/csv")
+			So(w.Header().Get("Content-Disposition"), ShouldEqual, "attachment; filename=passengers.csv")
+			So(w.Body.String(), ShouldEqual, "id,name,age\n0,John Doe,30\n1,John Doe,\n")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerExport_Imputed_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(1)
+	passengers[0].Age = nil
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/export?format=ndjson&imputed=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fitted := createPassengers(2)
+	*fitted[1].Age = 27
+	mService.On("Fit", DefaultImputation).Return(DefaultImputation.Fit(fitted), nil /* error */)
+	mService.On("Iterate", Query{}).Return(newSliceIterator(ctx.Background(), passengers), 1, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Export(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Header().Get("Content-Disposition"), ShouldEqual, "attachment; filename=passengers.ndjson")
+			So(w.Body.String(), ShouldEqual, `{"id":0,"survived":1,"class":1,"name":"John Doe","sex":"Male",`+
+				`"age":"28.5","siblings-spouses":1,"parents-children":0,"ticket":"A123","fare":50.25,"cabin":"C123",`+
+				`"embarked":"S","imputed":["age"]}`+"\n")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerExport_Import_RoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		format, contentType string
+	}{
+		{format: "csv", contentType: "text/csv"},
+		{format: "ndjson", contentType: "application/x-ndjson"},
+	} {
+		setup()
+
+		passengers := createPassengers(2)
+		for i, p := range passengers {
+			p.PassengerId, p.Sex = i+1, "female"
+		}
+		passengers[1].Age, passengers[1].Cabin, passengers[1].Embarked = nil, nil, nil
+
+		// given
+		r, err := http.NewRequest("GET", "/passenger/export?format="+tc.format, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mService.On("Iterate", Query{}).Return(newSliceIterator(ctx.Background(), passengers), 2, nil /* error */)
+		var imported Import
+		mService.On("Import", mock.Anything).Run(func(args mock.Arguments) {
+			imported = args.Get(0).(Import)
+		}).Return(&ImportReport{}, nil /* error */)
+
+		// when
+		exported := httptest.NewRecorder()
+		handler.Export(exported, r)
+		w := httptest.NewRecorder()
+		handler.Import(w, createImportRequest(t, "", "passengers."+tc.format, tc.contentType, exported.Body.String()))
+
+		// then
+		Convey("Test handler "+tc.format+"\n", t, func() {
+			Convey("Status Code Should Be 200", func() {
+				So(exported.Code, ShouldEqual, http.StatusOK)
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("Exported Passengers Are Imported", func() {
+				So(len(imported.Rows), ShouldEqual, 2)
+				for i, row := range imported.Rows {
+					So(row.Errors, ShouldBeEmpty)
+					So(row.Passenger, ShouldResemble, passengers[i])
+				}
+			})
+		})
+
+		mService.AssertExpectations(t)
+	}
+}
+
+func TestHandlerExport_InvalidFormat_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/export?format=json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Export(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 400", func() {
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGet_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	passenger := createPassengers(1)[0]
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Get", passenger.PassengerId).Return(passenger, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Get(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs *Response
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.PassengerId, ShouldEqual, passenger.PassengerId)
+			So(rs.Name, ShouldEqual, passenger.Name)
+			So(rs.Age, ShouldEqual, passenger.AgeString())
+			So(rs.Sex, ShouldEqual, string(passenger.Sex))
+			So(rs.Cabin, ShouldEqual, passenger.CabinString())
+			So(rs.Parch, ShouldEqual, passenger.Parch)
+			So(rs.Pclass, ShouldEqual, int(passenger.Pclass))
+			So(rs.Embarked, ShouldEqual, passenger.EmbarkedString())
+			So(rs.Survived, ShouldEqual, passenger.Survived)
+			So(rs.Ticket, ShouldEqual, passenger.Ticket)
+			So(rs.Fare, ShouldEqual, passenger.Fare)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerV2Get_MissingValues_ResponseNull(t *testing.T) {
+	setup()
+	handler = NewHandlerV2(mService)
+
+	passenger := createPassengers(1)[0]
+	passenger.Age, passenger.Cabin, passenger.Embarked = nil, nil, nil
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Get", passenger.PassengerId).Return(passenger, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Get(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs map[string]interface{}
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs, ShouldContainKey, "age")
+			So(rs["age"], ShouldBeNil)
+			So(rs, ShouldContainKey, "cabin")
+			So(rs["cabin"], ShouldBeNil)
+			So(rs, ShouldContainKey, "embarked")
+			So(rs["embarked"], ShouldBeNil)
+			So(rs["class"], ShouldEqual, float64(passenger.Pclass))
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGet_ValidRequestWithAttributes_ResponseOk(t *testing.T) {
+	setup()
+
+	passenger := createPassengers(2)[1]
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	// Create a new url.Values and add query parameters
+	queryParams := url.Values{}
+	queryParams.Add("attributes", "id,name, age")
+
+	// Set the query parameters as the RawQuery of the request URL
+	r.URL.RawQuery = queryParams.Encode()
+
+	mService.On("Get", passenger.PassengerId).Return(passenger, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Get(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs *Response
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.PassengerId, ShouldEqual, passenger.PassengerId)
+			So(rs.Name, ShouldEqual, passenger.Name)
+			So(rs.Age, ShouldEqual, passenger.AgeString())
+			So(rs.Sex, ShouldEqual, "")
+			So(rs.Cabin, ShouldEqual, "")
+			So(rs.Parch, ShouldEqual, 0)
+			So(rs.Pclass, ShouldEqual, 0)
+			So(rs.Embarked, ShouldEqual, "")
+			So(rs.Survived, ShouldEqual, 0)
+			So(rs.Ticket, ShouldEqual, "")
+			So(rs.Fare, ShouldEqual, 0.0)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGet_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "invalid-id")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Get(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 400", func() {
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Code, ShouldEqual, http.StatusBadRequest)
+			So(rs.Message, ShouldEqual, ErrInvalidID.Error())
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGet_InvalidRequestWithAttributes_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	passenger := createPassengers(2)[1]
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	// Create a new url.Values and add query parameters
+	queryParams := url.Values{}
+	queryParams.Add("attributes", "id,name, invalid-attribute")
+
+	// Set the query parameters as the RawQuery of the request URL
+	r.URL.RawQuery = queryParams.Encode()
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Get(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 400", func() {
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Code, ShouldEqual, http.StatusBadRequest)
+			So(rs.Message, ShouldContainSubstring, "invalid-attribute")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGet_InvalidRequestWithAttributesTwice_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	passenger := createPassengers(2)[1]
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	// Create a new url.Values and add query parameters
+	queryParams := url.Values{}
+	queryParams.Add("attributes", "id,id")
+
+	// Set the query parameters as the RawQuery of the request URL
+	r.URL.RawQuery = queryParams.Encode()
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Get(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 400", func() {
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Code, ShouldEqual, http.StatusBadRequest)
+			So(rs.Message, ShouldContainSubstring, "id")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGet_ValidRequest_ResponseInternalError(t *testing.T) {
+	setup()
+
+	passenger := createPassengers(2)[1]
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Get", passenger.PassengerId).Return(nil, errors.New("error"))
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Get(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 500", func() {
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		})
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Code, ShouldEqual, http.StatusInternalServerError)
+			So(rs.Message, ShouldEqual, response.ErrInternalFailure.Error())
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerTicket_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(2)
+	passengers[0].Ticket, passengers[1].Ticket = "A/5 21171", "A/5 21171"
+	party := newParty(passengers, map[string]int{"A/5 21171": 2})
+
+	// given
+	r, err := http.NewRequest("GET", "/ticket/{ticket}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("ticket", "A%2F5%2021171")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Ticket", "A/5 21171").Return(party, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Ticket(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs struct {
+				Tickets       []string `json:"tickets"`
+				Size          int      `json:"size"`
+				PerPersonFare float64  `json:"per-person-fare"`
+				Outcome       Outcome  `json:"outcome"`
+				Members       []struct {
+					Passenger     *Response `json:"passenger"`
+					FamilySize    int       `json:"family-size"`
+					PerPersonFare float64   `json:"per-person-fare"`
+				} `json:"members"`
+			}
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Tickets, ShouldResemble, []string{"A/5 21171"})
+			So(rs.Size, ShouldEqual, 2)
+			So(rs.PerPersonFare, ShouldEqual, 25.125)
+			So(rs.Outcome, ShouldEqual, OutcomeAllSurvived)
+			So(len(rs.Members), ShouldEqual, 2)
+			So(rs.Members[1].Passenger.PassengerId, ShouldEqual, passengers[1].PassengerId)
+			So(rs.Members[1].FamilySize, ShouldEqual, 2)
+			So(rs.Members[1].PerPersonFare, ShouldEqual, 25.125)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerTicket_MissingTicket_ResponseNotFound(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/ticket/{ticket}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("ticket", "X1")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Ticket", "X1").Return(nil, ErrTicketNotFound)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Ticket(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 404", func() {
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+		})
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Message, ShouldEqual, ErrTicketNotFound.Error())
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGroup_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(3)
+	passengers[2].Survived = 0
+	party := newParty(passengers, map[string]int{"A123": 3})
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{id}/group", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Party", 1).Return(party, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Group(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs map[string]interface{}
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs["size"], ShouldEqual, 3)
+			So(rs["survivors"], ShouldEqual, 2)
+			So(rs["outcome"], ShouldEqual, string(OutcomeSomeSurvived))
+			So(len(rs["members"].([]interface{})), ShouldEqual, 3)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerGroup_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, id := range []string{"abc", "1.5", ""} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger/{id}/group", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Group(w, r)
+
+		// then
+		Convey("Test handler "+id+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerDecks_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	value := func(v float64) *float64 { return &v }
+	decks := newDecks([]*Group{
+		{Keys: []interface{}{""}, Values: []*float64{value(687), value(206)}},
+		{Keys: []interface{}{"A"}, Values: []*float64{value(15), value(7)}},
+		{Keys: []interface{}{"T"}, Values: []*float64{value(1), value(0)}},
+	})
+
+	// given
+	r, err := http.NewRequest("GET", "/deck", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Decks").Return(decks, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Decks(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Body.String(), ShouldEqual,
+				`[{"deck":"A","passengers":15,"survivors":7,"survival-rate":0.4666666666666667},`+
+					`{"deck":"T","passengers":1,"survivors":0,"survival-rate":0}]`+"\n")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerDeck_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	deck := newDeck("T", 1, 0)
+	deck.Cabins = []*DeckCabin{{Cabin: "T", Passengers: 1}}
+
+	// given
+	r, err := http.NewRequest("GET", "/deck/{letter}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("letter", "t")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Deck", "T").Return(deck, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Deck(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Body.String(), ShouldEqual, `{"deck":"T","passengers":1,"survivors":0,"survival-rate":0,`+
+				`"cabins":[{"cabin":"T","passengers":1,"survivors":0}]}`+"\n")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerDeck_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, letter := range []string{"", "AB", "1", "%"} {
+		// given
+		r, err := http.NewRequest("GET", "/deck/{letter}", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("letter", letter)
+		r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Deck(w, r)
+
+		// then
+		Convey("Test handler "+letter+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerDeck_MissingDeck_ResponseNotFound(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/deck/{letter}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("letter", "Z")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Deck", "Z").Return(nil, ErrDeckNotFound)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Deck(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 404", func() {
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerPorts_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	ports := []*EmbarkationPort{lookupPort(PortSouthampton), lookupPort(PortQueenstown)}
+	ports[0].setCounts(644, 217)
+	ports[1].setCounts(77, 30)
+
+	// given
+	r, err := http.NewRequest("GET", "/port", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Ports").Return(ports, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Ports(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs []*EmbarkationPort
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(len(rs), ShouldEqual, 2)
+			So(rs[0].Name, ShouldEqual, "Southampton")
+			So(rs[0].Passengers, ShouldEqual, 644)
+			So(*rs[1].SurvivalRate, ShouldAlmostEqual, 30.0/77)
+			So(rs[1].BoardingDate, ShouldEqual, "1912-04-11")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerPorts_ValidRequestGeoJSON_ResponseOk(t *testing.T) {
+	setup()
+
+	ports := []*EmbarkationPort{lookupPort(PortCherbourg)}
+	ports[0].setCounts(168, 93)
+
+	// given
+	r, err := http.NewRequest("GET", "/port?format=geojson", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Ports").Return(ports, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Ports(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(w.Header().Get("Content-Type"), ShouldEqual, "application/geo+json")
+		})
+		Convey("Response As Expected", func() {
+			var rs struct {
+				Type     string `json:"type"`
+				Features []struct {
+					ID       string `json:"id"`
+					Geometry struct {
+						Type        string    `json:"type"`
+						Coordinates []float64 `json:"coordinates"`
+					} `json:"geometry"`
+					Properties *EmbarkationPort `json:"properties"`
+				} `json:"features"`
+			}
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Type, ShouldEqual, "FeatureCollection")
+			So(len(rs.Features), ShouldEqual, 1)
+			So(rs.Features[0].ID, ShouldEqual, "C")
+			So(rs.Features[0].Geometry.Type, ShouldEqual, "Point")
+			So(rs.Features[0].Geometry.Coordinates, ShouldResemble, []float64{-1.6222, 49.6453})
+			So(rs.Features[0].Properties.Survivors, ShouldEqual, 93)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerPort_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	port := lookupPort(PortQueenstown)
+	port.setCounts(77, 30)
+
+	// given
+	r, err := http.NewRequest("GET", "/port/{code}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("code", "q")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Port", PortQueenstown).Return(port, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Port(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs *EmbarkationPort
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Code, ShouldEqual, PortQueenstown)
+			So(rs.Passengers, ShouldEqual, 77)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerPort_MissingPort_ResponseNotFound(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/port/{code}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("code", "X")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Port", Port("X")).Return(nil, ErrPortNotFound)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Port(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 404", func() {
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerFareHistogram_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	h := createHistogram()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/fare/histogram/percentile", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("FarePercentileHistogram", histogram.DefaultPercentiles).Return(h, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.FareHistogram(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var hist *histogram.Histogram
+			err := json.NewDecoder(w.Body).Decode(&hist)
+			So(err, ShouldBeNil)
+			So(len(hist.Entries), ShouldEqual, len(h.Entries))
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerFareHistogram_ValidRequest_ResponseInternalError(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/fare/histogram/percentile", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("FarePercentileHistogram", histogram.DefaultPercentiles).Return(nil, errors.New("error"))
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.FareHistogram(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 500", func() {
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		})
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Code, ShouldEqual, http.StatusInternalServerError)
+			So(rs.Message, ShouldEqual, response.ErrInternalFailure.Error())
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerFareHistogram_ValidPercentiles_ResponseOk(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/fare/histogram/percentile?percentiles=10,%2050,90", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("FarePercentileHistogram", []float64{10, 50, 90}).Return(createHistogram(), nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.FareHistogram(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerFareHistogram_InvalidPercentiles_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, rawQuery := range []string{"percentiles=abc", "percentiles=0", "percentiles=50,101", "percentiles=50,25",
+		"percentiles=25,25", "percentiles=NaN"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger/fare/histogram/percentile?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.FareHistogram(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerFareHistogram_EmptyData_ResponseNotFound(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/fare/histogram/percentile", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("FarePercentileHistogram", histogram.DefaultPercentiles).Return(nil, histogram.ErrEmptyData)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.FareHistogram(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 404", func() {
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerHistogram_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	for rawQuery, opts := range map[string]histogram.Options{
+		"":                             {Strategy: histogram.StrategyEqualWidth},
+		"strategy=equal-width&width=5": {Strategy: histogram.StrategyEqualWidth, Width: 5},
+		"strategy=log&bins=8":          {Strategy: histogram.StrategyLog, Bins: 8},
+		"strategy=freedman-diaconis":   {Strategy: histogram.StrategyFreedmanDiaconis},
+	} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger/{field}/histogram?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("field", "fare")
+		r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		mService.On("Histogram", "fare", "", opts).Return(createHistogram(), nil /* error */)
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Histogram(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 200", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerHistogram_SplitBy_ResponseOk(t *testing.T) {
+	setup()
+
+	h := createHistogram()
+	h.Series = []*histogram.Series{{Key: 0.0, Entries: h.Entries}, {Key: 1.0, Entries: h.Entries}}
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/{field}/histogram?splitBy=survived&strategy=sturges", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("field", "age")
+	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	mService.On("Histogram", "age", "survived", histogram.Options{Strategy: histogram.StrategySturges}).
+		Return(h, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Histogram(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs *histogram.Histogram
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(len(rs.Series), ShouldEqual, 2)
+			So(rs.Series[1].Key, ShouldEqual, 1)
+			So(len(rs.Series[1].Entries), ShouldEqual, len(h.Entries))
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerHistogram_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, path := range []string{"name/histogram", "fare/histogram?strategy=unknown", "fare/histogram?bins=0",
+		"fare/histogram?bins=abc", "fare/histogram?width=-1", "fare/histogram?bins=2&width=1",
+		"fare/histogram?strategy=scott&bins=5", "fare/histogram?strategy=percentile&percentiles=50,10",
+		"fare/histogram?splitBy=name", "fare/histogram?splitBy=age"} {
+		field, _, _ := strings.Cut(path, "/")
+
+		// given
+		r, err := http.NewRequest("GET", "/passenger/"+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("field", field)
+		r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Histogram(w, r)
+
+		// then
+		Convey("Test handler "+path+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerAggregate_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	count, fare := 94.0, 106.125
+	groups := []*Group{
+		{Keys: []interface{}{1.0, "female"}, Values: []*float64{&count, &fare, nil}},
+	}
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/aggregate?groupBy=class,sex&metrics=count,avg(fare),min(age)&survived=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Aggregate", mock.MatchedBy(func(a Aggregation) bool {
+		return len(a.Filter.Conditions) == 1 && a.Filter.Conditions[0].Field == "survived" &&
+			len(a.GroupBy) == 2 && a.GroupBy[0] == "class" && a.GroupBy[1] == "sex" &&
+			len(a.Metrics) == 3 && a.Metrics[1] == Metric{Function: FunctionAvg, Field: "fare"}
+	})).Return(groups, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Aggregate(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Body.String(), ShouldEqual,
+				`[{"class":1,"sex":"female","count":94,"avg(fare)":106.125,"min(age)":null}]`+"\n")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerAggregate_ValidRequestByTitle_ResponseOk(t *testing.T) {
+	setup()
+
+	count, survived := 40.0, 0.575
+	groups := []*Group{
+		{Keys: []interface{}{"Master"}, Values: []*float64{&count, &survived}},
+	}
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/aggregate?groupBy=title&metrics=count,avg(survived)&title=Master", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Aggregate", mock.MatchedBy(func(a Aggregation) bool {
+		return len(a.Filter.Conditions) == 1 && a.Filter.Conditions[0].Field == "title" &&
+			a.Filter.Conditions[0].Values[0] == "Master" && len(a.GroupBy) == 1 && a.GroupBy[0] == "title"
+	})).Return(groups, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Aggregate(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			So(w.Body.String(), ShouldEqual, `[{"title":"Master","count":40,"avg(survived)":0.575}]`+"\n")
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerAggregate_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, rawQuery := range []string{"groupBy=unknown", "groupBy=sex,sex", "metrics=avg", "metrics=avg(name)",
+		"metrics=median(fare)", "metrics=avg(fare", "metrics=count,count", "fare.contains=1"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger/aggregate?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Aggregate(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerStatistics_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	mean := 38.25
+	groups := []*StatisticsGroup{
+		{Keys: []interface{}{1.0}, Summaries: []*stats.Summary{{Count: 186, Missing: 30, Mean: &mean}}},
+	}
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/stats?fields=age&groupBy=class&sex=female", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Statistics", mock.MatchedBy(func(s Statistics) bool {
+		return len(s.Filter.Conditions) == 1 && s.Filter.Conditions[0].Field == "sex" &&
+			len(s.Fields) == 1 && s.Fields[0] == "age" && len(s.GroupBy) == 1 && s.GroupBy[0] == "class"
+	})).Return(groups, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Statistics(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs []struct {
+				Class float64                   `json:"class"`
+				Stats map[string]*stats.Summary `json:"stats"`
+			}
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(len(rs), ShouldEqual, 1)
+			So(rs[0].Class, ShouldEqual, 1)
+			So(rs[0].Stats["age"].Count, ShouldEqual, 186)
+			So(rs[0].Stats["age"].Missing, ShouldEqual, 30)
+			So(*rs[0].Stats["age"].Mean, ShouldEqual, mean)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerStatistics_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, rawQuery := range []string{"fields=unknown", "fields=name", "fields=age,age", "groupBy=unknown",
+		"age.contains=1"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger/stats?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Statistics(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerCrosstab_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	table := Crosstab{Rows: "sex", Cols: "survived"}.Apply(createPassengers(4))
+
+	// given
+	r, err := http.NewRequest("GET", "/passenger/crosstab?rows=sex&cols=survived&class=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Crosstab", mock.MatchedBy(func(c Crosstab) bool {
+		return len(c.Filter.Conditions) == 1 && c.Filter.Conditions[0].Field == "class" &&
+			c.Rows == "sex" && c.Cols == "survived"
+	})).Return(table, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Crosstab(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs *Table
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.RowField, ShouldEqual, "sex")
+			So(rs.ColField, ShouldEqual, "survived")
+			So(rs.Total, ShouldEqual, 4)
+			So(rs.Counts, ShouldResemble, table.Counts)
+			So(rs.RowTotals, ShouldResemble, table.RowTotals)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerCrosstab_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, rawQuery := range []string{"", "rows=sex", "rows=sex&cols=sex", "rows=sex&cols=age",
+		"rows=name&cols=sex", "rows=sex&cols=survived&age.contains=1"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger/crosstab?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Crosstab(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerCorrelation_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	passengers := createPassengers(3)
+	for i, p := range passengers {
+		age := float64(20 + 10*i)
+		p.Age, p.Fare = &age, float64(10*i)
+	}
+	matrix := Correlation{Fields: []string{"age", "fare", "sex"}}.Apply(passengers)
+
+	for _, format := range []string{"json", "csv"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger/correlation?fields=age,fare,sex&class=1&format="+format, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mService.On("Correlation", mock.MatchedBy(func(c Correlation) bool {
+			return len(c.Filter.Conditions) == 1 && c.Filter.Conditions[0].Field == "class" &&
+				len(c.Fields) == 3 && c.Fields[2] == "sex"
+		})).Return(matrix, nil /* error */).Once()
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Correlation(w, r)
+
+		// then
+		Convey("Test handler "+format+"\n", t, func() {
+			Convey("Status Code Should Be 200", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+			Convey("Response As Expected", func() {
+				switch format {
+				case "json":
+					var rs *CorrelationMatrix
+					err := json.NewDecoder(w.Body).Decode(&rs)
+					So(err, ShouldBeNil)
+					So(rs.Fields, ShouldResemble, []string{"age", "fare", "sex"})
+					So(*rs.Pearson[0][1], ShouldAlmostEqual, 1, 1e-12)
+					So(*rs.Spearman[1][0], ShouldAlmostEqual, 1, 1e-12)
+					So(rs.Pearson[0][2], ShouldBeNil)
+					So(rs.Pairs[0][1], ShouldEqual, 3)
+				default:
+					So(w.Header().Get("Content-Type"), ShouldStartWith, "text/csv")
+					records, err := csv.NewReader(w.Body).ReadAll()
+					So(err, ShouldBeNil)
+					So(len(records), ShouldEqual, 7)
+					So(records[0], ShouldResemble, []string{"method", "field", "age", "fare", "sex"})
+					So(records[1], ShouldResemble, []string{"pearson", "age", "1", "1", ""})
+					So(records[4][:2], ShouldResemble, []string{"spearman", "age"})
+				}
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerCorrelation_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, rawQuery := range []string{"fields=unknown", "fields=name", "fields=embarked", "fields=age,age",
+		"age.contains=1"} {
+		// given
+		r, err := http.NewRequest("GET", "/passenger/correlation?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Correlation(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
 }
 
-func (ms *MockService) Get(pid int) (*Passenger, error) {
-	args := ms.Called(pid)
-	var res *Passenger
-	if args.Get(0) != nil {
-		res = args.Get(0).(*Passenger)
+func TestHandlerCreate_ValidRequest_ResponseCreated(t *testing.T) {
+	setup()
+
+	passenger := createPassengers(2)[1]
+	passenger.Sex = "male"
+	body, _ := json.Marshal(convertPassenger(passenger))
+
+	// given
+	r, err := http.NewRequest("POST", "/passenger", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
 	}
-	return res, args.Error(1)
+	mService.On("Create", passenger).Return(nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Create(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 201", func() {
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(w.Header().Get("Location"), ShouldEqual, "/passenger/1")
+		})
+		Convey("Response As Expected", func() {
+			var rs *Response
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.PassengerId, ShouldEqual, passenger.PassengerId)
+			So(rs.Name, ShouldEqual, passenger.Name)
+		})
+	})
+
+	mService.AssertExpectations(t)
 }
 
-func (ms *MockService) GetAll() ([]*Passenger, error) {
-	args := ms.Called()
-	var res []*Passenger
-	if args.Get(0) != nil {
-		res = args.Get(0).([]*Passenger)
+func TestHandlerWrite_NotAcceptable_StoreUntouched(t *testing.T) {
+	setup()
+
+	passenger := createPassengers(2)[1]
+	passenger.Sex = "male"
+	body, _ := json.Marshal(convertPassenger(passenger))
+
+	for _, tc := range []struct {
+		name string
+		r    *http.Request
+	}{
+		{name: "create", r: httptest.NewRequest("POST", "/", bytes.NewReader(body))},
+		{name: "update", r: httptest.NewRequest("PUT", "/1", bytes.NewReader(body))},
+		{name: "patch", r: httptest.NewRequest("PATCH", "/1", bytes.NewReader(body))},
+		{name: "import", r: createImportRequest(t, "mode=replace", "a.ndjson", "application/x-ndjson", string(body))},
+	} {
+		// given
+		tc.r.URL.Path = strings.TrimPrefix(tc.r.URL.Path, "/passenger")
+		tc.r.Header.Set("Accept", "text/html")
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.RegisterHandler().ServeHTTP(w, tc.r)
+
+		// then
+		Convey("Test handler "+tc.name+"\n", t, func() {
+			Convey("Status Code Should Be 406", func() {
+				So(w.Code, ShouldEqual, http.StatusNotAcceptable)
+			})
+		})
 	}
-	return res, args.Error(1)
-}
 
-// pre test setup function
-func setup() {
-	mService = new(MockService)
-	handler = NewHandler(mService)
+	for _, method := range []string{"Create", "Update", "Get", "Import"} {
+		mService.AssertNotCalled(t, method, mock.Anything)
+	}
 }
 
-/*
-Test functions
-*/
-func TestHandlerGetAll_ValidRequest_ResponseOk(t *testing.T) {
+func TestHandlerCreate_ExistingPassenger_ResponseConflict(t *testing.T) {
 	setup()
 
-	passengers := createPassengers(3)
+	passenger := createPassengers(2)[1]
+	passenger.Sex = "male"
+	body, _ := json.Marshal(convertPassenger(passenger))
 
 	// given
-	r, err := http.NewRequest("GET", "/passenger", nil)
+	r, err := http.NewRequest("POST", "/passenger", bytes.NewReader(body))
 	if err != nil {
 		t.Fatal(err)
 	}
-	mService.On("GetAll").Return(createPassengers(3), nil /* error */)
+	mService.On("Create", passenger).Return(ErrPassengerExists)
 
 	w := httptest.NewRecorder()
 
 	// when
-	handler.GetAll(w, r)
+	handler.Create(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
-		Convey("Status Code Should Be 200", func() {
-			So(w.Code, ShouldEqual, http.StatusOK)
+		Convey("Status Code Should Be 409", func() {
+			So(w.Code, ShouldEqual, http.StatusConflict)
 		})
 		Convey("Response As Expected", func() {
-			var p []*Passenger
-			err := json.NewDecoder(w.Body).Decode(&p)
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
 			So(err, ShouldBeNil)
-			So(len(p), ShouldEqual, len(passengers))
+			So(rs.Code, ShouldEqual, http.StatusConflict)
+			So(rs.Message, ShouldEqual, ErrPassengerExists.Error())
 		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
-func TestHandlerGetAll_ValidRequest_ResponseInternalError(t *testing.T) {
+func TestHandlerCreate_InvalidPassenger_ResponseBadRequest(t *testing.T) {
 	setup()
 
+	passenger := convertPassenger(createPassengers(2)[1])
+	passenger.Pclass = 4
+	body, _ := json.Marshal(passenger)
+
 	// given
-	r, err := http.NewRequest("GET", "/passenger", nil)
+	r, err := http.NewRequest("POST", "/passenger", bytes.NewReader(body))
 	if err != nil {
 		t.Fatal(err)
 	}
-	mService.On("GetAll").Return(nil, errors.New("error"))
 
 	w := httptest.NewRecorder()
 
 	// when
-	handler.GetAll(w, r)
+	handler.Create(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
-		Convey("Status Code Should Be 500", func() {
-			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		Convey("Status Code Should Be 400", func() {
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
 		})
-		Convey("Result As Expected", func() {
-			var errorResp response.Error
-			err := json.NewDecoder(w.Body).Decode(&errorResp)
+		Convey("Response As Expected", func() {
+			var rs *response.Error
+			err := json.NewDecoder(w.Body).Decode(&rs)
 			So(err, ShouldBeNil)
-			So(errorResp.Message, ShouldEqual, response.ErrInternalFailure.Error())
-			So(errorResp.Code, ShouldEqual, http.StatusInternalServerError)
+			So(rs.Code, ShouldEqual, http.StatusBadRequest)
+			So(rs.Message, ShouldContainSubstring, "class")
 		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
-func TestHandlerGet_ValidRequest_ResponseOk(t *testing.T) {
+func TestHandlerUpdate_MismatchedID_ResponseBadRequest(t *testing.T) {
 	setup()
 
-	passenger := createPassengers(1)[0]
+	body, _ := json.Marshal(convertPassenger(createPassengers(2)[1]))
 
 	// given
-	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	r, err := http.NewRequest("PUT", "/passenger/{id}", bytes.NewReader(body))
 	if err != nil {
 		t.Fatal(err)
 	}
 	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	rctx.URLParams.Add("id", "2")
 	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
 
-	mService.On("Get", passenger.PassengerId).Return(passenger, nil /* error */)
-
 	w := httptest.NewRecorder()
 
 	// when
-	handler.Get(w, r)
+	handler.Update(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
-		Convey("Status Code Should Be 200", func() {
-			So(w.Code, ShouldEqual, http.StatusOK)
+		Convey("Status Code Should Be 400", func() {
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
 		})
 		Convey("Response As Expected", func() {
-			var rs *Response
+			var rs *response.Error
 			err := json.NewDecoder(w.Body).Decode(&rs)
 			So(err, ShouldBeNil)
-			So(rs.PassengerId, ShouldEqual, passenger.PassengerId)
-			So(rs.Name, ShouldEqual, passenger.Name)
-			So(rs.Age, ShouldEqual, passenger.Age)
-			So(rs.Sex, ShouldEqual, passenger.Sex)
-			So(rs.Cabin, ShouldEqual, passenger.Cabin)
-			So(rs.Parch, ShouldEqual, passenger.Parch)
-			So(rs.Pclass, ShouldEqual, passenger.Pclass)
-			So(rs.Embarked, ShouldEqual, passenger.Embarked)
-			So(rs.Survived, ShouldEqual, passenger.Survived)
-			So(rs.Ticket, ShouldEqual, passenger.Ticket)
-			So(rs.Fare, ShouldEqual, passenger.Fare)
+			So(rs.Message, ShouldEqual, ErrIDMismatch.Error())
 		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
-func TestHandlerGet_ValidRequestWithAttributes_ResponseOk(t *testing.T) {
+func TestHandlerPatch_ValidRequest_ResponseOk(t *testing.T) {
 	setup()
 
 	passenger := createPassengers(2)[1]
+	passenger.Sex = "male"
+	patched := *passenger
+	cabin := "B42"
+	patched.Survived = 0
+	patched.Cabin = &cabin
 
 	// given
-	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	r, err := http.NewRequest("PATCH", "/passenger/{id}", bytes.NewBufferString(`{"survived": 0, "cabin": "B42"}`))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -193,19 +2423,13 @@ func TestHandlerGet_ValidRequestWithAttributes_ResponseOk(t *testing.T) {
 	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
 	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
 
-	// Create a new url.Values and add query parameters
-	queryParams := url.Values{}
-	queryParams.Add("attributes", "id,name, age")
-
-	// Set the query parameters as the RawQuery of the request URL
-	r.URL.RawQuery = queryParams.Encode()
-
 	mService.On("Get", passenger.PassengerId).Return(passenger, nil /* error */)
+	mService.On("Update", &patched).Return(nil /* error */)
 
 	w := httptest.NewRecorder()
 
 	// when
-	handler.Get(w, r)
+	handler.Patch(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
@@ -216,262 +2440,297 @@ func TestHandlerGet_ValidRequestWithAttributes_ResponseOk(t *testing.T) {
 			var rs *Response
 			err := json.NewDecoder(w.Body).Decode(&rs)
 			So(err, ShouldBeNil)
-			So(rs.PassengerId, ShouldEqual, passenger.PassengerId)
-			So(rs.Name, ShouldEqual, passenger.Name)
-			So(rs.Age, ShouldEqual, passenger.Age)
-			So(rs.Sex, ShouldEqual, "")
-			So(rs.Cabin, ShouldEqual, "")
-			So(rs.Parch, ShouldEqual, 0)
-			So(rs.Pclass, ShouldEqual, 0)
-			So(rs.Embarked, ShouldEqual, "")
 			So(rs.Survived, ShouldEqual, 0)
-			So(rs.Ticket, ShouldEqual, "")
-			So(rs.Fare, ShouldEqual, 0.0)
+			So(rs.Cabin, ShouldEqual, "B42")
+			So(rs.Name, ShouldEqual, passenger.Name)
 		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
-func TestHandlerGet_InvalidRequest_ResponseBadRequest(t *testing.T) {
+func TestHandlerPatch_Imputed_StoredValuesKept(t *testing.T) {
 	setup()
 
+	passenger := createPassengers(2)[1]
+	passenger.Sex = "male"
+	passenger.Age = nil
+	patched := *passenger
+	patched.Fare = 8.5
+
 	// given
-	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	r, err := http.NewRequest("PATCH", "/passenger/{id}?imputed=true", bytes.NewBufferString(`{"fare": 8.5}`))
 	if err != nil {
 		t.Fatal(err)
 	}
 	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "invalid-id")
+	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
 	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
 
+	mService.On("Get", passenger.PassengerId).Return(passenger, nil /* error */)
+	mService.On("Update", &patched).Return(nil /* error */)
+
 	w := httptest.NewRecorder()
 
 	// when
-	handler.Get(w, r)
+	handler.Patch(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
-		Convey("Status Code Should Be 400", func() {
-			So(w.Code, ShouldEqual, http.StatusBadRequest)
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
 		})
-		Convey("Response As Expected", func() {
-			var rs *response.Error
-			err := json.NewDecoder(w.Body).Decode(&rs)
-			So(err, ShouldBeNil)
-			So(rs.Code, ShouldEqual, http.StatusBadRequest)
-			So(rs.Message, ShouldEqual, ErrInvalidID.Error())
+		Convey("Missing Values Are Not Imputed", func() {
+			updated := mService.Calls[len(mService.Calls)-1].Arguments.Get(0).(*Passenger)
+			So(updated.Age, ShouldBeNil)
+			So(updated.Imputed, ShouldBeEmpty)
 		})
 	})
 
 	mService.AssertExpectations(t)
+	mService.AssertNotCalled(t, "Impute", mock.Anything, mock.Anything)
 }
 
-func TestHandlerGet_InvalidRequestWithAttributes_ResponseBadRequest(t *testing.T) {
+func TestHandlerDelete_ValidRequest_ResponseNoContent(t *testing.T) {
 	setup()
 
-	passenger := createPassengers(2)[1]
-
 	// given
-	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	r, err := http.NewRequest("DELETE", "/passenger/{id}", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	rctx.URLParams.Add("id", "1")
 	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
 
-	// Create a new url.Values and add query parameters
-	queryParams := url.Values{}
-	queryParams.Add("attributes", "id,name, invalid-attribute")
-
-	// Set the query parameters as the RawQuery of the request URL
-	r.URL.RawQuery = queryParams.Encode()
+	mService.On("Delete", 1).Return(nil /* error */)
 
 	w := httptest.NewRecorder()
 
 	// when
-	handler.Get(w, r)
+	handler.Delete(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
-		Convey("Status Code Should Be 400", func() {
-			So(w.Code, ShouldEqual, http.StatusBadRequest)
-		})
-		Convey("Response As Expected", func() {
-			var rs *response.Error
-			err := json.NewDecoder(w.Body).Decode(&rs)
-			So(err, ShouldBeNil)
-			So(rs.Code, ShouldEqual, http.StatusBadRequest)
-			So(rs.Message, ShouldContainSubstring, "invalid-attribute")
+		Convey("Status Code Should Be 204", func() {
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(w.Body.Len(), ShouldEqual, 0)
 		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
-func TestHandlerGet_InvalidRequestWithAttributesTwice_ResponseBadRequest(t *testing.T) {
+func TestHandlerDelete_MissingPassenger_ResponseNotFound(t *testing.T) {
 	setup()
 
-	passenger := createPassengers(2)[1]
-
 	// given
-	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
+	r, err := http.NewRequest("DELETE", "/passenger/{id}", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
+	rctx.URLParams.Add("id", "1")
 	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
 
-	// Create a new url.Values and add query parameters
-	queryParams := url.Values{}
-	queryParams.Add("attributes", "id,id")
-
-	// Set the query parameters as the RawQuery of the request URL
-	r.URL.RawQuery = queryParams.Encode()
+	mService.On("Delete", 1).Return(ErrPassengerNotFound)
 
 	w := httptest.NewRecorder()
 
 	// when
-	handler.Get(w, r)
+	handler.Delete(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
-		Convey("Status Code Should Be 400", func() {
-			So(w.Code, ShouldEqual, http.StatusBadRequest)
-		})
-		Convey("Response As Expected", func() {
-			var rs *response.Error
-			err := json.NewDecoder(w.Body).Decode(&rs)
-			So(err, ShouldBeNil)
-			So(rs.Code, ShouldEqual, http.StatusBadRequest)
-			So(rs.Message, ShouldContainSubstring, "id")
+		Convey("Status Code Should Be 404", func() {
+			So(w.Code, ShouldEqual, http.StatusNotFound)
 		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
-func TestHandlerGet_ValidRequest_ResponseInternalError(t *testing.T) {
+func TestHandlerImport_ValidCSV_ResponseOk(t *testing.T) {
 	setup()
 
-	passenger := createPassengers(2)[1]
-
 	// given
-	r, err := http.NewRequest("GET", "/passenger/{id}", nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", strconv.Itoa(passenger.PassengerId))
-	r = r.WithContext(ctx.WithValue(r.Context(), chi.RouteCtxKey, rctx))
-
-	mService.On("Get", passenger.PassengerId).Return(nil, errors.New("error"))
+	content := "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked,title\n" +
+		`1,0,3,"Braund, Mr. Owen Harris",male,22,1,0,A/5 21171,7.25,,S,Mr` + "\n" +
+		`2,1,x,"Cumings, Mrs. John Bradley",female,38,1,0,PC 17599,71.2833,C85,C,Mrs` + "\n" +
+		`3,1,3,"Heikkinen, Miss. Laina",female,26,0,0,STON/O2. 3101282,7.925,,X,Miss` + "\n"
+	r := createImportRequest(t, "mode=insert&dryRun=true", "batch.csv", "text/csv", content)
+	report := &ImportReport{Mode: ImportModeInsert, DryRun: true, Rows: 3, Created: 1, Failed: 2}
+	mService.On("Import", mock.MatchedBy(func(batch Import) bool {
+		rows := batch.Rows
+		return batch.Mode == ImportModeInsert && batch.DryRun && len(rows) == 3 &&
+			rows[0].Line == 2 && rows[0].Passenger != nil && rows[0].Passenger.Name == "Braund, Mr. Owen Harris" &&
+			rows[0].Passenger.Cabin == nil && *rows[0].Passenger.Embarked == PortSouthampton &&
+			rows[1].Passenger == nil && *rows[1].ID == 2 && rows[1].Errors[0] == "Pclass 'x' is not an integer" &&
+			rows[2].Passenger == nil && rows[2].Errors[0] == "embarked must be one of 'S', 'C', 'Q' or empty"
+	})).Return(report, nil /* error */)
 
 	w := httptest.NewRecorder()
 
 	// when
-	handler.Get(w, r)
+	handler.Import(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
-		Convey("Status Code Should Be 500", func() {
-			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
 		})
 		Convey("Response As Expected", func() {
-			var rs *response.Error
+			var rs *ImportReport
 			err := json.NewDecoder(w.Body).Decode(&rs)
 			So(err, ShouldBeNil)
-			So(rs.Code, ShouldEqual, http.StatusInternalServerError)
-			So(rs.Message, ShouldEqual, response.ErrInternalFailure.Error())
+			So(rs, ShouldResemble, report)
 		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
-func TestHandlerFareHistogram_ValidRequest_ResponseOk(t *testing.T) {
+func TestHandlerImport_ValidNDJSON_ResponseOk(t *testing.T) {
 	setup()
 
-	h := createHistogram()
-
 	// given
-	r, err := http.NewRequest("GET", "/passenger/fare/histogram/percentile", nil)
-	if err != nil {
-		t.Fatal(err)
-	}
-	mService.On("FarePercentileHistogram").Return(h, nil /* error */)
+	content := `{"id":1,"survived":0,"class":3,"name":"Braund, Mr. Owen Harris","sex":"male","age":"22"}` + "\n\n" +
+		`{"id":2,"survived":"1","class":1,"name":"Cumings, Mrs. John Bradley","sex":"female"}` + "\n" +
+		`{"id":3,"unknown":1}` + "\n"
+	r := createImportRequest(t, "", "batch.jsonl", "application/octet-stream", content)
+	mService.On("Import", mock.MatchedBy(func(batch Import) bool {
+		rows := batch.Rows
+		return batch.Mode == ImportModeUpsert && !batch.DryRun && len(rows) == 3 &&
+			rows[0].Line == 1 && rows[0].Passenger != nil && *rows[0].Passenger.Age == 22 &&
+			rows[1].Line == 3 && *rows[1].ID == 2 && rows[1].Errors[0] == "survived must be of type int, not string" &&
+			rows[2].Line == 4 && rows[2].ID == nil && strings.Contains(rows[2].Errors[0], "unknown")
+	})).Return(&ImportReport{Mode: ImportModeUpsert, Rows: 3, Created: 1, Failed: 2}, nil /* error */)
 
 	w := httptest.NewRecorder()
 
 	// when
-	handler.FareHistogram(w, r)
+	handler.Import(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
 		Convey("Status Code Should Be 200", func() {
 			So(w.Code, ShouldEqual, http.StatusOK)
 		})
-		Convey("Response As Expected", func() {
-			var hist *histogram.Histogram
-			err := json.NewDecoder(w.Body).Decode(&hist)
-			So(err, ShouldBeNil)
-			So(len(hist.Entries), ShouldEqual, len(h.Entries))
-		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
-func TestHandlerFareHistogram_ValidRequest_ResponseInternalError(t *testing.T) {
+func TestHandlerImport_InvalidRequest_ResponseError(t *testing.T) {
 	setup()
 
-	// given
-	r, err := http.NewRequest("GET", "/passenger/fare/histogram/percentile", nil)
-	if err != nil {
-		t.Fatal(err)
+	header := "id,survived,class,name,sex,age,siblings-spouses,parents-children,ticket,fare,cabin,embarked"
+	for _, tc := range []struct {
+		name, rawQuery, filename, contentType, content string
+		code                                           int
+	}{
+		{name: "mode", rawQuery: "mode=merge", filename: "a.csv", content: header + "\n", code: http.StatusBadRequest},
+		{name: "dry run", rawQuery: "dryRun=maybe", filename: "a.csv", code: http.StatusBadRequest},
+		{name: "format", filename: "a.xlsx", contentType: "application/octet-stream", code: http.StatusUnsupportedMediaType},
+		{name: "unknown column", filename: "a.csv", content: header + ",weight\n", code: http.StatusBadRequest},
+		{name: "missing column", filename: "a.csv", content: "id,name\n1,Doe\n", code: http.StatusBadRequest},
+		{name: "empty", filename: "a.csv", content: header + "\n", code: http.StatusBadRequest},
+	} {
+		// given
+		contentType := tc.contentType
+		if len(contentType) == 0 {
+			contentType = "text/csv"
+		}
+		r := createImportRequest(t, tc.rawQuery, tc.filename, contentType, tc.content)
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Import(w, r)
+
+		// then
+		Convey("Test handler "+tc.name+"\n", t, func() {
+			Convey("Status Code Should Be "+strconv.Itoa(tc.code), func() {
+				So(w.Code, ShouldEqual, tc.code)
+			})
+		})
 	}
-	mService.On("FarePercentileHistogram").Return(nil, errors.New("error"))
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerImport_ReplaceRejected_ResponseUnprocessable(t *testing.T) {
+	setup()
+
+	// given
+	r := createImportRequest(t, "mode=replace", "a.ndjson", "application/x-ndjson", `{"id":1}`+"\n")
+	report := &ImportReport{Mode: ImportModeReplace, Rows: 1, Failed: 1,
+		Errors: []*ImportRowError{{Line: 1, Errors: []string{"name is required"}}}}
+	mService.On("Import", mock.Anything).Return(report, ErrImportRejected)
 
 	w := httptest.NewRecorder()
 
 	// when
-	handler.FareHistogram(w, r)
+	handler.Import(w, r)
 
 	// then
 	Convey("Test handler\n", t, func() {
-		Convey("Status Code Should Be 500", func() {
-			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+		Convey("Status Code Should Be 422", func() {
+			So(w.Code, ShouldEqual, http.StatusUnprocessableEntity)
 		})
-		Convey("Response As Expected", func() {
-			var rs *response.Error
-			err := json.NewDecoder(w.Body).Decode(&rs)
-			So(err, ShouldBeNil)
-			So(rs.Code, ShouldEqual, http.StatusInternalServerError)
-			So(rs.Message, ShouldEqual, response.ErrInternalFailure.Error())
+		Convey("Report Is Returned", func() {
+			var rs *ImportReport
+			So(json.NewDecoder(w.Body).Decode(&rs), ShouldBeNil)
+			So(rs, ShouldResemble, report)
 		})
 	})
 
 	mService.AssertExpectations(t)
 }
 
+// createImportRequest creates an import request uploading content as the file field.
+func createImportRequest(t *testing.T, rawQuery, filename, contentType, content string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {`form-data; name="file"; filename="` + filename + `"`},
+		"Content-Type":        {contentType},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = part.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest("POST", "/passenger/import?"+rawQuery, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r
+}
+
 func createPassengers(size int) []*Passenger {
 	var passengers []*Passenger
 	for i := 0; i < size; i++ {
+		age, cabin, embarked := 30.0, "C123", PortSouthampton
 		p := &Passenger{
 			PassengerId: i,
 			Survived:    1,
 			Pclass:      1,
 			Name:        "John Doe",
 			Sex:         "Male",
-			Age:         "30",
+			Age:         &age,
 			SibSp:       1,
 			Parch:       0,
 			Ticket:      "A123",
 			Fare:        50.25,
-			Cabin:       "C123",
-			Embarked:    "S",
+			Cabin:       &cabin,
+			Embarked:    &embarked,
 		}
 		passengers = append(passengers, p)
 	}
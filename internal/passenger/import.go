@@ -0,0 +1,127 @@
+package passenger
+
+import (
+	"fmt"
+)
+
+// ImportMode is how imported passengers are written to the store.
+type ImportMode string
+
+const (
+	// ImportModeUpsert creates new passengers and replaces stored ones
+	ImportModeUpsert ImportMode = "upsert"
+	// ImportModeInsert creates new passengers and rejects stored ones
+	ImportModeInsert ImportMode = "insert"
+	// ImportModeReplace replaces every stored passenger with the imported ones
+	ImportModeReplace ImportMode = "replace"
+)
+
+// ErrImportRejected is returned with the report of replace imports which have
+// invalid rows, replacing the store would delete the passengers of those rows.
+var ErrImportRejected = fmt.Errorf("replace import rejected, every row must be valid")
+
+func (m ImportMode) Valid() bool {
+	return m == ImportModeUpsert || m == ImportModeInsert || m == ImportModeReplace
+}
+
+// Import is a parsed import file, its valid rows are written at once.
+type Import struct {
+	Mode   ImportMode
+	DryRun bool
+	Rows   []*ImportRow
+}
+
+// ImportRow is a row of an import file. Passenger is nil when the row failed
+// validation, ID is nil when the id itself is not valid.
+type ImportRow struct {
+	Line      int
+	ID        *int
+	Passenger *Passenger
+	Errors    []string
+}
+
+// ImportResult is what the store wrote, or would write on dry runs.
+type ImportResult struct {
+	Created int
+	Updated int
+	// Deleted counts the stored passengers missing from a replace import
+	Deleted int
+	// Conflicts are the ids already stored, rejected in insert mode
+	Conflicts []int
+}
+
+// ImportReport counts the passengers written by an import and lists the rows
+// which were not.
+type ImportReport struct {
+	Mode    ImportMode        `json:"mode"`
+	DryRun  bool              `json:"dry-run"`
+	Rows    int               `json:"rows"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Deleted int               `json:"deleted"`
+	Failed  int               `json:"failed"`
+	Errors  []*ImportRowError `json:"errors"`
+}
+
+// ImportRowError is a row which was not written, Line is its line in the file.
+type ImportRowError struct {
+	Line   int      `json:"line"`
+	ID     *int     `json:"id"`
+	Errors []string `json:"errors"`
+}
+
+// validate rejects the rows repeating the id of a previous row.
+func (i Import) validate() {
+	lines := make(map[int]int)
+	for _, row := range i.Rows {
+		if row.ID == nil {
+			continue
+		}
+		if line, found := lines[*row.ID]; found {
+			row.Errors = append(row.Errors, fmt.Sprintf("id %d is already imported on line %d", *row.ID, line))
+			row.Passenger = nil
+			continue
+		}
+		lines[*row.ID] = row.Line
+	}
+}
+
+// passengers are the passengers of the valid rows.
+func (i Import) passengers() []*Passenger {
+	var passengers []*Passenger
+	for _, row := range i.Rows {
+		if row.Passenger != nil {
+			passengers = append(passengers, row.Passenger)
+		}
+	}
+	return passengers
+}
+
+// report lists the rows which failed validation or were rejected by the store.
+func (i Import) report(result *ImportResult) *ImportReport {
+	report := &ImportReport{
+		Mode:    i.Mode,
+		DryRun:  i.DryRun,
+		Rows:    len(i.Rows),
+		Created: result.Created,
+		Updated: result.Updated,
+		Deleted: result.Deleted,
+		Errors:  []*ImportRowError{},
+	}
+
+	conflicts := make(map[int]bool, len(result.Conflicts))
+	for _, id := range result.Conflicts {
+		conflicts[id] = true
+	}
+	for _, row := range i.Rows {
+		errs := row.Errors
+		if row.Passenger != nil && conflicts[row.Passenger.PassengerId] {
+			errs = append(errs, ErrPassengerExists.Error())
+		}
+		if len(errs) > 0 {
+			report.Errors = append(report.Errors, &ImportRowError{Line: row.Line, ID: row.ID, Errors: errs})
+		}
+	}
+	report.Failed = len(report.Errors)
+	return report
+}
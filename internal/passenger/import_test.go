@@ -0,0 +1,33 @@
+package passenger
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestImport_Rows_Report(t *testing.T) {
+	Convey("Test import\n", t, func() {
+		one, two := 1, 2
+		batch := Import{Mode: ImportModeInsert, Rows: []*ImportRow{
+			{Line: 2, ID: &one, Passenger: &Passenger{PassengerId: 1}},
+			{Line: 3, ID: &two, Passenger: &Passenger{PassengerId: 2}},
+			{Line: 4, ID: &one, Passenger: &Passenger{PassengerId: 1}},
+			{Line: 5, Errors: []string{"id 'x' is not an integer"}},
+		}}
+		batch.validate()
+
+		Convey("Repeated Ids Are Rejected", func() {
+			So(batch.passengers(), ShouldResemble, []*Passenger{{PassengerId: 1}, {PassengerId: 2}})
+			So(batch.Rows[2].Errors, ShouldResemble, []string{"id 1 is already imported on line 2"})
+		})
+		Convey("Conflicts Are Reported", func() {
+			report := batch.report(&ImportResult{Created: 1, Conflicts: []int{2}})
+			So(report.Rows, ShouldEqual, 4)
+			So(report.Created, ShouldEqual, 1)
+			So(report.Failed, ShouldEqual, 3)
+			So(report.Errors[0], ShouldResemble, &ImportRowError{Line: 3, ID: &two, Errors: []string{ErrPassengerExists.Error()}})
+			So(report.Errors[2].ID, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,346 @@
+package passenger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"titanic-api/pkg/stats"
+)
+
+type Strategy string
+
+const (
+	StrategyMean       Strategy = "mean"
+	StrategyMedian     Strategy = "median"
+	StrategyMode       Strategy = "mode"
+	StrategyRegression Strategy = "regression"
+)
+
+var (
+	// imputableFields are the fields with missing values and their strategies
+	imputableFields = map[string]map[Strategy]bool{
+		"age":      {StrategyMean: true, StrategyMedian: true, StrategyRegression: true},
+		"embarked": {StrategyMode: true},
+		"cabin":    {StrategyMode: true},
+	}
+	// imputationGroups are the fields imputation statistics can be grouped by
+	imputationGroups = map[string]bool{"class": true, "sex": true, "title": true, "embarked": true,
+		"siblings-spouses": true, "parents-children": true}
+	// DefaultImputation fills ages with the median of the title and class and
+	// embarked with the most frequent port, cabins are left missing
+	DefaultImputation = Imputation{Rules: []ImputationRule{
+		{Field: "age", Strategy: StrategyMedian, GroupBy: []string{"title", "class"}},
+		{Field: "embarked", Strategy: StrategyMode},
+	}}
+)
+
+// ImputationRule fills the missing values of Field with Strategy, computed
+// over the passengers of the same GroupBy group. Regression ignores GroupBy.
+type ImputationRule struct {
+	Field    string
+	Strategy Strategy
+	GroupBy  []string
+}
+
+// Imputation fills missing passenger values by applying its rules in order.
+type Imputation struct {
+	Rules []ImputationRule
+}
+
+// NewImputation parses comma separated rules of the form
+// field:strategy[:group+group], e.g. age:median:title+class,embarked:mode.
+func NewImputation(spec string) (Imputation, error) {
+	var imputation Imputation
+	visited := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		terms := strings.Split(strings.TrimSpace(part), ":")
+		if len(terms) < 2 || len(terms) > 3 {
+			return Imputation{}, fmt.Errorf("imputation rule '%s' must be field:strategy[:group+group]", part)
+		}
+
+		rule := ImputationRule{Field: terms[0], Strategy: Strategy(terms[1])}
+		strategies, found := imputableFields[rule.Field]
+		switch {
+		case !found:
+			return Imputation{}, fmt.Errorf("imputation field '%s' must be one of age, embarked, cabin", rule.Field)
+		case !strategies[rule.Strategy]:
+			return Imputation{}, fmt.Errorf("imputation strategy '%s' is not supported for '%s'", rule.Strategy,
+				rule.Field)
+		case visited[rule.Field]:
+			return Imputation{}, fmt.Errorf("imputation field '%s' provided multiple times in query", rule.Field)
+		}
+		visited[rule.Field] = true
+
+		if len(terms) == 3 {
+			rule.GroupBy = strings.Split(terms[2], "+")
+			for _, name := range rule.GroupBy {
+				if !imputationGroups[name] {
+					return Imputation{}, fmt.Errorf("unknown imputation group field provided '%s' in query", name)
+				}
+			}
+		}
+		imputation.Rules = append(imputation.Rules, rule)
+	}
+	return imputation, nil
+}
+
+// imputer fills the missing values of a field once fitted.
+type imputer interface {
+	fit(passengers []*Passenger)
+	// impute fills the value of p when missing and reports whether it did
+	impute(p *Passenger) bool
+}
+
+// Fit computes the imputation statistics over passengers, usually the whole
+// store so the filled values do not depend on the page read.
+func (i Imputation) Fit(passengers []*Passenger) *Imputer {
+	im := &Imputer{}
+	for _, rule := range i.Rules {
+		var fitted imputer
+		switch rule.Strategy {
+		case StrategyRegression:
+			fitted = &regressionImputer{}
+		default:
+			fitted = &groupImputer{rule: rule}
+		}
+		fitted.fit(passengers)
+		im.fields, im.imputers = append(im.fields, rule.Field), append(im.imputers, fitted)
+	}
+	return im
+}
+
+// Imputer is a fitted Imputation.
+type Imputer struct {
+	fields   []string
+	imputers []imputer
+}
+
+// Apply returns passengers with their missing values filled. Filled
+// passengers are copies listing the filled fields in Imputed, the others are
+// returned as is.
+func (im *Imputer) Apply(passengers []*Passenger) []*Passenger {
+	rs := make([]*Passenger, len(passengers))
+	for i, p := range passengers {
+		filled := *p
+		filled.Imputed = nil
+		for j, fitted := range im.imputers {
+			if fitted.impute(&filled) {
+				filled.Imputed = append(filled.Imputed, im.fields[j])
+			}
+		}
+		switch len(filled.Imputed) {
+		case 0:
+			rs[i] = p
+		default:
+			rs[i] = &filled
+		}
+	}
+	return rs
+}
+
+// groupImputer fills a field with the mean, median or mode of its group,
+// groups without values take the statistic of all passengers.
+type groupImputer struct {
+	rule    ImputationRule
+	overall interface{}
+	groups  map[string]interface{}
+}
+
+func (g *groupImputer) fit(passengers []*Passenger) {
+	var all []interface{}
+	byGroup := make(map[string][]interface{})
+	for _, p := range passengers {
+		v := imputationValue(g.rule.Field, p)
+		if v == nil {
+			continue
+		}
+		all = append(all, v)
+		key := g.key(p)
+		byGroup[key] = append(byGroup[key], v)
+	}
+
+	g.overall = g.statistic(all)
+	g.groups = make(map[string]interface{}, len(byGroup))
+	for key, values := range byGroup {
+		g.groups[key] = g.statistic(values)
+	}
+}
+
+func (g *groupImputer) impute(p *Passenger) bool {
+	if imputationValue(g.rule.Field, p) != nil {
+		return false
+	}
+	v, found := g.groups[g.key(p)]
+	if !found {
+		v = g.overall
+	}
+	return setImputed(g.rule.Field, p, v)
+}
+
+func (g *groupImputer) key(p *Passenger) string {
+	keys := make([]interface{}, len(g.rule.GroupBy))
+	for i, name := range g.rule.GroupBy {
+		keys[i] = fields[name].value(p)
+	}
+	return fmt.Sprintf("%#v", keys)
+}
+
+// statistic is nil without values.
+func (g *groupImputer) statistic(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if g.rule.Strategy == StrategyMode {
+		counts := make(map[interface{}]int)
+		for _, v := range values {
+			counts[v]++
+		}
+		// ties are broken by the smallest value so the mode is stable
+		var mode interface{}
+		for v, n := range counts {
+			if mode == nil || n > counts[mode] || (n == counts[mode] && fmt.Sprint(v) < fmt.Sprint(mode)) {
+				mode = v
+			}
+		}
+		return mode
+	}
+
+	numbers := make([]float64, len(values))
+	for i, v := range values {
+		numbers[i] = v.(float64)
+	}
+	if g.rule.Strategy == StrategyMedian {
+		sort.Float64s(numbers)
+		return stats.Quantile(numbers, 0.5)
+	}
+	var sum float64
+	for _, v := range numbers {
+		sum += v
+	}
+	return sum / float64(len(numbers))
+}
+
+// regressionImputer fills ages with a least squares fit on class,
+// siblings-spouses, parents-children, log fare and title, which also encodes
+// the sex. Predicted ages are kept within the observed range.
+type regressionImputer struct {
+	titles       []string
+	coefficients []float64
+	min, max     float64
+	// fallback is the median age, used when the regression cannot be fitted
+	fallback *float64
+}
+
+func (r *regressionImputer) fit(passengers []*Passenger) {
+	var ages []float64
+	var aged []*Passenger
+	counts := make(map[string]int)
+	for _, p := range passengers {
+		if p.Age == nil {
+			continue
+		}
+		ages, aged = append(ages, *p.Age), append(aged, p)
+		counts[ParseName(p.Name).Title]++
+	}
+	if len(ages) == 0 {
+		return
+	}
+
+	sorted := append([]float64{}, ages...)
+	sort.Float64s(sorted)
+	median := stats.Quantile(sorted, 0.5)
+	r.fallback, r.min, r.max = &median, sorted[0], sorted[len(sorted)-1]
+
+	// every title but the most frequent gets an indicator, the most frequent
+	// is the baseline
+	for t := range counts {
+		r.titles = append(r.titles, t)
+	}
+	sort.Slice(r.titles, func(i, j int) bool {
+		if counts[r.titles[i]] != counts[r.titles[j]] {
+			return counts[r.titles[i]] > counts[r.titles[j]]
+		}
+		return r.titles[i] < r.titles[j]
+	})
+	r.titles = r.titles[1:]
+
+	x := make([][]float64, len(aged))
+	for i, p := range aged {
+		x[i] = r.predictors(p)
+	}
+	if coefficients, err := stats.LinearRegression(x, ages); err == nil {
+		r.coefficients = coefficients
+	}
+}
+
+func (r *regressionImputer) impute(p *Passenger) bool {
+	switch {
+	case p.Age != nil || r.fallback == nil:
+		return false
+	case r.coefficients == nil:
+		return setImputed("age", p, *r.fallback)
+	}
+
+	age := r.coefficients[0]
+	for i, v := range r.predictors(p) {
+		age += r.coefficients[i+1] * v
+	}
+	return setImputed("age", p, math.Max(r.min, math.Min(r.max, age)))
+}
+
+func (r *regressionImputer) predictors(p *Passenger) []float64 {
+	x := []float64{
+		indicator(p.Pclass == ClassSecond),
+		indicator(p.Pclass == ClassThird),
+		float64(p.SibSp),
+		float64(p.Parch),
+		math.Log1p(p.Fare),
+	}
+	t := ParseName(p.Name).Title
+	for _, name := range r.titles {
+		x = append(x, indicator(t == name))
+	}
+	return x
+}
+
+// imputationValue reads an imputable field, nil when it is missing.
+func imputationValue(name string, p *Passenger) interface{} {
+	switch name {
+	case "age":
+		return fields[name].value(p)
+	default:
+		if v := fields[name].text(p); len(v) > 0 {
+			return v
+		}
+		return nil
+	}
+}
+
+// setImputed sets an imputable field, nothing is set for a nil value.
+func setImputed(name string, p *Passenger, v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	switch name {
+	case "age":
+		// imputed ages are rounded to two decimals
+		age := math.Round(v.(float64)*100) / 100
+		p.Age = &age
+	case "embarked":
+		embarked := Port(v.(string))
+		p.Embarked = &embarked
+	case "cabin":
+		cabin := v.(string)
+		p.Cabin = &cabin
+	}
+	return true
+}
+
+func indicator(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package passenger
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func createImputationPassengers() []*Passenger {
+	age := func(v float64) *float64 { return &v }
+	port := func(v Port) *Port { return &v }
+	return []*Passenger{
+		{PassengerId: 1, Name: "Doe, Mr. John", Pclass: ClassFirst, Sex: SexMale, Age: age(40), Fare: 80,
+			Embarked: port(PortCherbourg)},
+		{PassengerId: 2, Name: "Doe, Mr. Jack", Pclass: ClassFirst, Sex: SexMale, Age: age(50), Fare: 60,
+			Embarked: port(PortCherbourg)},
+		{PassengerId: 3, Name: "Roe, Mr. Jim", Pclass: ClassThird, Sex: SexMale, Age: age(20), Fare: 8,
+			Embarked: port(PortSouthampton)},
+		{PassengerId: 4, Name: "Roe, Master. Tom", Pclass: ClassThird, Sex: SexMale, Age: age(4), Fare: 20,
+			SibSp: 1, Parch: 2, Embarked: port(PortSouthampton)},
+		{PassengerId: 5, Name: "Roe, Miss. Ann", Pclass: ClassThird, Sex: SexFemale, Age: age(18), Fare: 9,
+			Embarked: port(PortSouthampton)},
+		{PassengerId: 6, Name: "Poe, Mr. Joe", Pclass: ClassFirst, Sex: SexMale, Fare: 70},
+		{PassengerId: 7, Name: "Poe, Master. Sam", Pclass: ClassThird, Sex: SexMale, Fare: 20, SibSp: 1, Parch: 2},
+		{PassengerId: 8, Name: "Poe, Dr. Ed", Pclass: ClassSecond, Sex: SexMale, Fare: 13,
+			Embarked: port(PortQueenstown)},
+	}
+}
+
+func TestNewImputation_Spec_Rules(t *testing.T) {
+	Convey("Test new imputation\n", t, func() {
+		imputation, err := NewImputation("age:mean:title+class, embarked:mode,cabin:mode:class")
+		So(err, ShouldBeNil)
+		So(imputation.Rules, ShouldResemble, []ImputationRule{
+			{Field: "age", Strategy: StrategyMean, GroupBy: []string{"title", "class"}},
+			{Field: "embarked", Strategy: StrategyMode},
+			{Field: "cabin", Strategy: StrategyMode, GroupBy: []string{"class"}},
+		})
+
+		for _, spec := range []string{"", "age", "age:mode", "name:mode", "age:mean:fare", "age:mean:class:sex",
+			"embarked:mode,embarked:mode"} {
+			_, err := NewImputation(spec)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestImputer_DefaultImputation_FilledCopies(t *testing.T) {
+	passengers := createImputationPassengers()
+
+	// when
+	rs := DefaultImputation.Fit(passengers).Apply(passengers)
+
+	// then
+	Convey("Test imputer\n", t, func() {
+		Convey("Complete Passengers Returned As Is", func() {
+			So(rs[0], ShouldEqual, passengers[0])
+			So(rs[0].Imputed, ShouldBeNil)
+		})
+		Convey("Age Median Of Title And Class", func() {
+			So(*rs[5].Age, ShouldEqual, 45)
+			So(*rs[6].Age, ShouldEqual, 4)
+			So(rs[5].Imputed, ShouldResemble, []string{"age", "embarked"})
+		})
+		Convey("Age Median Of All Passengers For Unseen Group", func() {
+			So(*rs[7].Age, ShouldEqual, 20)
+			So(rs[7].Imputed, ShouldResemble, []string{"age"})
+		})
+		Convey("Embarked Mode", func() {
+			So(*rs[5].Embarked, ShouldEqual, PortSouthampton)
+		})
+		Convey("Originals Untouched", func() {
+			So(passengers[5].Age, ShouldBeNil)
+			So(passengers[5].Embarked, ShouldBeNil)
+			So(passengers[5].Imputed, ShouldBeNil)
+		})
+	})
+}
+
+func TestImputer_Regression_LinearAge(t *testing.T) {
+	// ages are exactly linear in the predictors
+	var passengers []*Passenger
+	titles := []string{"Mr", "Mr", "Master", "Miss"}
+	for i := 0; i < 36; i++ {
+		p := &Passenger{PassengerId: i + 1, Name: "Doe, " + titles[i%4] + ". X", Pclass: Class(i%3 + 1),
+			SibSp: i % 2, Parch: (i / 3) % 3, Fare: float64(5 + i)}
+		age := 38 - 28*indicator(titles[i%4] == "Master") - 14*indicator(titles[i%4] == "Miss") +
+			3*indicator(p.Pclass == ClassSecond) - 6*indicator(p.Pclass == ClassThird) + float64(p.SibSp) +
+			2*float64(p.Parch)
+		p.Age = &age
+		passengers = append(passengers, p)
+	}
+	missing := []*Passenger{
+		{Name: "Roe, Master. Y", Pclass: ClassThird, SibSp: 1, Parch: 2, Fare: 20},
+		{Name: "Roe, Mr. Z", Pclass: ClassSecond},
+	}
+	imputation, err := NewImputation("age:regression")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	rs := imputation.Fit(passengers).Apply(missing)
+
+	// then
+	Convey("Test imputer\n", t, func() {
+		So(*rs[0].Age, ShouldAlmostEqual, 9, 1e-6)
+		So(*rs[1].Age, ShouldAlmostEqual, 41, 1e-6)
+		So(rs[0].Imputed, ShouldResemble, []string{"age"})
+	})
+}
+
+func TestImputer_RegressionTooFewAges_MedianAge(t *testing.T) {
+	passengers := createImputationPassengers()
+	imputation, err := NewImputation("age:regression")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	rs := imputation.Fit(passengers).Apply(passengers)
+
+	// then
+	Convey("Test imputer\n", t, func() {
+		for _, p := range rs[5:] {
+			So(p.Imputed, ShouldResemble, []string{"age"})
+			So(*p.Age, ShouldEqual, 20)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package passenger
+
+import (
+	"strconv"
+)
+
+type Sex string
+
+const (
+	SexMale   Sex = "male"
+	SexFemale Sex = "female"
+)
+
+func (s Sex) Valid() bool {
+	return s == SexMale || s == SexFemale
+}
+
+type Class int
+
+const (
+	ClassFirst  Class = 1
+	ClassSecond Class = 2
+	ClassThird  Class = 3
+)
+
+func (c Class) Valid() bool {
+	return c >= ClassFirst && c <= ClassThird
+}
+
+// Port is the embarkation port code.
+type Port string
+
+const (
+	PortSouthampton Port = "S"
+	PortCherbourg   Port = "C"
+	PortQueenstown  Port = "Q"
+)
+
+func (p Port) Valid() bool {
+	return p == PortSouthampton || p == PortCherbourg || p == PortQueenstown
+}
+
+// Passenger is the typed passenger model, nil fields are missing in the dataset.
+type Passenger struct {
+	PassengerId int
+	Survived    int
+	Pclass      Class
+	Name        string
+	Sex         Sex
+	Age         *float64
+	SibSp       int
+	Parch       int
+	Ticket      string
+	Fare        float64
+	Cabin       *string
+	Embarked    *Port
+	// Imputed lists the fields filled by an imputation, see Imputer
+	Imputed []string
+}
+
+// AgeString formats age the way the dataset stores it, empty when missing.
+func (p *Passenger) AgeString() string {
+	if p.Age == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*p.Age, 'f', -1, 64)
+}
+
+// CabinString returns the cabin, empty when missing.
+func (p *Passenger) CabinString() string {
+	if p.Cabin == nil {
+		return ""
+	}
+	return *p.Cabin
+}
+
+// EmbarkedString returns the embarkation port code, empty when missing.
+func (p *Passenger) EmbarkedString() string {
+	if p.Embarked == nil {
+		return ""
+	}
+	return string(*p.Embarked)
+}
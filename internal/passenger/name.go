@@ -0,0 +1,78 @@
+package passenger
+
+import (
+	"strings"
+)
+
+var (
+	// maleTitles are the titles of men, a name in parentheses is an alias for
+	// them rather than a maiden name
+	maleTitles = map[string]bool{"Mr": true, "Master": true, "Sir": true, "Rev": true, "Don": true, "Major": true,
+		"Col": true, "Capt": true, "Jonkheer": true}
+	// nameParts read the parts of a parsed name by their field name
+	nameParts = map[string]func(n Name) string{
+		"surname":     func(n Name) string { return n.Surname },
+		"title":       func(n Name) string { return n.Title },
+		"given-names": func(n Name) string { return n.GivenNames },
+		"nickname":    func(n Name) string { return n.Nickname },
+		"maiden-name": func(n Name) string { return n.MaidenName },
+	}
+)
+
+// Name is a dataset name split into its parts, missing parts are empty.
+type Name struct {
+	Surname    string `json:"surname"`
+	Title      string `json:"title"`
+	GivenNames string `json:"given-names"`
+	Nickname   string `json:"nickname"`
+	MaidenName string `json:"maiden-name"`
+}
+
+// ParseName splits a dataset name of the form
+// `Surname, Title. Given Names "Nickname" (Maiden Name)`, e.g.
+// `Cumings, Mrs. John Bradley (Florence Briggs Thayer)`. A quoted name, in
+// parentheses or not, is the nickname. A name in parentheses is the maiden
+// name of women and the nickname of men when they have none.
+func ParseName(raw string) Name {
+	var n Name
+	rest := strings.TrimSpace(raw)
+	if comma := strings.Index(rest, ","); comma >= 0 {
+		n.Surname, rest = strings.TrimSpace(rest[:comma]), strings.TrimSpace(rest[comma+1:])
+	}
+
+	// the title is the first one or two words when followed by a dot
+	if dot := strings.Index(rest, "."); dot >= 0 && len(strings.Fields(rest[:dot])) <= 2 &&
+		!strings.ContainsAny(rest[:dot], `"(`) {
+		n.Title, rest = strings.TrimSpace(rest[:dot]), strings.TrimSpace(rest[dot+1:])
+		// e.g. Rothes, the Countess. of (Lucy Noel Martha Dyer-Edwards)
+		if strings.HasPrefix(n.Title, "the ") {
+			n.Title = strings.TrimPrefix(n.Title, "the ")
+			rest = strings.TrimPrefix(rest, "of")
+		}
+	}
+
+	if open := strings.Index(rest, `"`); open >= 0 {
+		if end := strings.Index(rest[open+1:], `"`); end >= 0 {
+			n.Nickname = strings.TrimSpace(rest[open+1 : open+1+end])
+			rest = rest[:open] + rest[open+1+end+1:]
+			// drop the parentheses left around a quoted nickname
+			rest = strings.ReplaceAll(rest, "()", "")
+		}
+	}
+
+	if open := strings.Index(rest, "("); open >= 0 {
+		if end := strings.Index(rest[open:], ")"); end >= 0 {
+			inner := strings.TrimSpace(rest[open+1 : open+end])
+			rest = rest[:open] + rest[open+end+1:]
+			switch {
+			case !maleTitles[n.Title]:
+				n.MaidenName = inner
+			case len(n.Nickname) == 0:
+				n.Nickname = inner
+			}
+		}
+	}
+
+	n.GivenNames = strings.Join(strings.Fields(rest), " ")
+	return n
+}
@@ -0,0 +1,36 @@
+package passenger
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseName_DatasetNames_Parts(t *testing.T) {
+	Convey("Test parse name\n", t, func() {
+		tests := map[string]Name{
+			"Braund, Mr. Owen Harris": {Surname: "Braund", Title: "Mr", GivenNames: "Owen Harris"},
+			"Cumings, Mrs. John Bradley (Florence Briggs Thayer)": {Surname: "Cumings", Title: "Mrs",
+				GivenNames: "John Bradley", MaidenName: "Florence Briggs Thayer"},
+			`Johnston, Miss. Catherine Helen "Carrie"`: {Surname: "Johnston", Title: "Miss",
+				GivenNames: "Catherine Helen", Nickname: "Carrie"},
+			"Rothes, the Countess. of (Lucy Noel Martha Dyer-Edwards)": {Surname: "Rothes", Title: "Countess",
+				MaidenName: "Lucy Noel Martha Dyer-Edwards"},
+			`Duff Gordon, Lady. (Lucille Christiana Sutherland) ("Mrs Morgan")`: {Surname: "Duff Gordon",
+				Title: "Lady", Nickname: "Mrs Morgan", MaidenName: "Lucille Christiana Sutherland"},
+			"Pickard, Mr. Berk (Berk Trembisky)": {Surname: "Pickard", Title: "Mr", GivenNames: "Berk",
+				Nickname: "Berk Trembisky"},
+			`Moubarek, Master. Halim Gonios ("William George")`: {Surname: "Moubarek", Title: "Master",
+				GivenNames: "Halim Gonios", Nickname: "William George"},
+			"Nakid, Mrs. (Waika Mary Mowad)": {Surname: "Nakid", Title: "Mrs", MaidenName: "Waika Mary Mowad"},
+			"Uruchurtu, Don. Manuel E":       {Surname: "Uruchurtu", Title: "Don", GivenNames: "Manuel E"},
+			"Doe":                            {GivenNames: "Doe"},
+			"":                               {},
+		}
+		for raw, expected := range tests {
+			Convey("Test parse name '"+raw+"'\n", func() {
+				So(ParseName(raw), ShouldResemble, expected)
+			})
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package passenger
+
+import (
+	"fmt"
+	"sort"
+)
+
+type Outcome string
+
+const (
+	OutcomeAllSurvived  Outcome = "all-survived"
+	OutcomeSomeSurvived Outcome = "some-survived"
+	OutcomeNoneSurvived Outcome = "none-survived"
+)
+
+// Party is a group of passengers travelling together. Fare is the sum of the
+// fares of its tickets, the dataset fare being the price of the whole ticket.
+type Party struct {
+	Tickets       []string `json:"tickets"`
+	Surnames      []string `json:"surnames"`
+	Size          int      `json:"size"`
+	Fare          float64  `json:"fare"`
+	PerPersonFare float64  `json:"per-person-fare"`
+	Survivors     int      `json:"survivors"`
+	SurvivalRate  float64  `json:"survival-rate"`
+	Outcome       Outcome  `json:"outcome"`
+	// Members are rendered by the handler in the passenger shape of the API version
+	Members []*Member `json:"-"`
+}
+
+// Member is a party passenger. FamilySize counts the passenger with its
+// siblings, spouses, parents and children aboard, PerPersonFare is the fare
+// divided by the number of passengers sharing the ticket.
+type Member struct {
+	Passenger     *Passenger
+	FamilySize    int
+	PerPersonFare float64
+}
+
+// Parties splits passengers into travelling parties, parties and their members
+// are ordered by id. Passengers sharing a ticket travel together, so do
+// relatives, which share a surname and a family size greater than one.
+func Parties(passengers []*Passenger) []*Party {
+	parents := make([]int, len(passengers))
+	for i := range parents {
+		parents[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parents[i] != i {
+			parents[i] = root(parents[i])
+		}
+		return parents[i]
+	}
+
+	firsts := make(map[string]int)
+	ticketSizes := make(map[string]int)
+	join := func(key string, i int) {
+		if first, found := firsts[key]; found {
+			parents[root(i)] = root(first)
+			return
+		}
+		firsts[key] = i
+	}
+	for i, p := range passengers {
+		ticketSizes[p.Ticket]++
+		join("ticket:"+p.Ticket, i)
+		if size := familySize(p); size > 1 {
+			join(fmt.Sprintf("family:%s:%d", ParseName(p.Name).Surname, size), i)
+		}
+	}
+
+	byRoot := make(map[int][]*Passenger)
+	var roots []int
+	for i, p := range passengers {
+		r := root(i)
+		if _, found := byRoot[r]; !found {
+			roots = append(roots, r)
+		}
+		byRoot[r] = append(byRoot[r], p)
+	}
+
+	parties := make([]*Party, len(roots))
+	for i, r := range roots {
+		parties[i] = newParty(byRoot[r], ticketSizes)
+	}
+	sort.Slice(parties, func(i, j int) bool {
+		return parties[i].Members[0].Passenger.PassengerId < parties[j].Members[0].Passenger.PassengerId
+	})
+	return parties
+}
+
+// newParty computes the party statistics of passengers, ticketSizes are the
+// number of passengers sharing every ticket.
+func newParty(passengers []*Passenger, ticketSizes map[string]int) *Party {
+	sorted := append([]*Passenger{}, passengers...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PassengerId < sorted[j].PassengerId })
+
+	party := &Party{Tickets: []string{}, Surnames: []string{}, Size: len(sorted)}
+	tickets, surnames := make(map[string]bool), make(map[string]bool)
+	for _, p := range sorted {
+		if !tickets[p.Ticket] {
+			tickets[p.Ticket] = true
+			party.Tickets = append(party.Tickets, p.Ticket)
+			party.Fare += p.Fare
+		}
+		if surname := ParseName(p.Name).Surname; len(surname) > 0 && !surnames[surname] {
+			surnames[surname] = true
+			party.Surnames = append(party.Surnames, surname)
+		}
+		party.Survivors += p.Survived
+		party.Members = append(party.Members, &Member{
+			Passenger:     p,
+			FamilySize:    familySize(p),
+			PerPersonFare: p.Fare / float64(ticketSizes[p.Ticket]),
+		})
+	}
+	sort.Strings(party.Tickets)
+	sort.Strings(party.Surnames)
+
+	if party.Size == 0 {
+		return party
+	}
+	party.PerPersonFare = party.Fare / float64(party.Size)
+	party.SurvivalRate = float64(party.Survivors) / float64(party.Size)
+	switch party.Survivors {
+	case party.Size:
+		party.Outcome = OutcomeAllSurvived
+	case 0:
+		party.Outcome = OutcomeNoneSurvived
+	default:
+		party.Outcome = OutcomeSomeSurvived
+	}
+	return party
+}
+
+func familySize(p *Passenger) int {
+	return p.SibSp + p.Parch + 1
+}
@@ -0,0 +1,60 @@
+package passenger
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func createPartyPassengers() []*Passenger {
+	return []*Passenger{
+		{PassengerId: 1, Name: "Doe, Mr. John", SibSp: 1, Ticket: "A1", Fare: 60, Survived: 0},
+		{PassengerId: 2, Name: "Doe, Mrs. John (Jane Roe)", SibSp: 1, Ticket: "A1", Fare: 60, Survived: 1},
+		// a relative on another ticket joins the family
+		{PassengerId: 3, Name: "Doe, Mr. Jim", SibSp: 1, Ticket: "B2", Fare: 10, Survived: 0},
+		// a maid sharing the family ticket
+		{PassengerId: 4, Name: "Poe, Miss. Ann", Ticket: "A1", Fare: 60, Survived: 1},
+		// same surname but a different family size, travelling alone
+		{PassengerId: 5, Name: "Doe, Mr. Joe", Ticket: "C3", Fare: 8, Survived: 1},
+		{PassengerId: 6, Name: "Roe, Master. Tom", Parch: 2, Ticket: "D4", Fare: 30, Survived: 0},
+	}
+}
+
+func TestParties_Passengers_Groups(t *testing.T) {
+	Convey("Test parties\n", t, func() {
+		parties := Parties(createPartyPassengers())
+		So(len(parties), ShouldEqual, 3)
+
+		Convey("Ticket And Family Are Joined", func() {
+			party := parties[0]
+			So(party.Tickets, ShouldResemble, []string{"A1", "B2"})
+			So(party.Surnames, ShouldResemble, []string{"Doe", "Poe"})
+			So(party.Size, ShouldEqual, 4)
+			So(party.Fare, ShouldEqual, 70)
+			So(party.PerPersonFare, ShouldEqual, 17.5)
+			So(party.Survivors, ShouldEqual, 2)
+			So(party.SurvivalRate, ShouldEqual, 0.5)
+			So(party.Outcome, ShouldEqual, OutcomeSomeSurvived)
+
+			var ids []int
+			for _, m := range party.Members {
+				ids = append(ids, m.Passenger.PassengerId)
+			}
+			So(ids, ShouldResemble, []int{1, 2, 3, 4})
+			So(party.Members[0].FamilySize, ShouldEqual, 2)
+			So(party.Members[0].PerPersonFare, ShouldEqual, 20)
+			So(party.Members[2].PerPersonFare, ShouldEqual, 10)
+			So(party.Members[3].FamilySize, ShouldEqual, 1)
+		})
+		Convey("Single Passengers Are Parties", func() {
+			So(parties[1].Size, ShouldEqual, 1)
+			So(parties[1].Members[0].Passenger.PassengerId, ShouldEqual, 5)
+			So(parties[1].Outcome, ShouldEqual, OutcomeAllSurvived)
+			So(parties[2].Members[0].FamilySize, ShouldEqual, 3)
+			So(parties[2].Outcome, ShouldEqual, OutcomeNoneSurvived)
+		})
+		Convey("No Passengers", func() {
+			So(Parties(nil), ShouldBeEmpty)
+		})
+	})
+}
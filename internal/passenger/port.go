@@ -0,0 +1,81 @@
+package passenger
+
+import (
+	"titanic-api/pkg/geojson"
+)
+
+var (
+	// ports is the catalogue of embarkation ports in boarding order, Queenstown
+	// is today's Cobh. The ship anchored off Cherbourg and Queenstown and
+	// passengers boarded by tender.
+	ports = []*EmbarkationPort{
+		{Code: PortSouthampton, Name: "Southampton", Country: "United Kingdom", Latitude: 50.8998,
+			Longitude: -1.4044, BoardingDate: "1912-04-10"},
+		{Code: PortCherbourg, Name: "Cherbourg", Country: "France", Latitude: 49.6453, Longitude: -1.6222,
+			BoardingDate: "1912-04-10"},
+		{Code: PortQueenstown, Name: "Queenstown", Country: "Ireland", Latitude: 51.8503, Longitude: -8.2943,
+			BoardingDate: "1912-04-11"},
+	}
+)
+
+// EmbarkationPort is a port of the catalogue with the passengers who boarded
+// there. Classes are only set for a single port.
+type EmbarkationPort struct {
+	Code         Port         `json:"code"`
+	Name         string       `json:"name"`
+	Country      string       `json:"country"`
+	Latitude     float64      `json:"latitude"`
+	Longitude    float64      `json:"longitude"`
+	BoardingDate string       `json:"boarding-date"`
+	Passengers   int          `json:"passengers"`
+	Survivors    int          `json:"survivors"`
+	SurvivalRate *float64     `json:"survival-rate"`
+	Classes      []*PortClass `json:"classes,omitempty"`
+}
+
+// PortClass is the passengers of a class who boarded at a port.
+type PortClass struct {
+	Class        Class    `json:"class"`
+	Passengers   int      `json:"passengers"`
+	Survivors    int      `json:"survivors"`
+	SurvivalRate *float64 `json:"survival-rate"`
+}
+
+// lookupPort returns a copy of the catalogue port of code, nil when unknown.
+func lookupPort(code Port) *EmbarkationPort {
+	for _, p := range ports {
+		if p.Code == code {
+			port := *p
+			return &port
+		}
+	}
+	return nil
+}
+
+// setCounts sets the passenger and survivor counts of the port.
+func (p *EmbarkationPort) setCounts(passengers, survivors int) {
+	p.Passengers, p.Survivors, p.SurvivalRate = passengers, survivors, survivalRate(passengers, survivors)
+}
+
+// Feature renders the port as a GeoJSON point, its properties are the port.
+func (p *EmbarkationPort) Feature() *geojson.Feature {
+	return geojson.NewFeature(p.Code, geojson.NewPoint(p.Longitude, p.Latitude), p)
+}
+
+// PortsFeatureCollection renders ports as GeoJSON points.
+func PortsFeatureCollection(ports []*EmbarkationPort) *geojson.FeatureCollection {
+	features := make([]*geojson.Feature, len(ports))
+	for i, p := range ports {
+		features[i] = p.Feature()
+	}
+	return geojson.NewFeatureCollection(features)
+}
+
+// survivalRate is nil without passengers.
+func survivalRate(passengers, survivors int) *float64 {
+	if passengers == 0 {
+		return nil
+	}
+	rate := float64(survivors) / float64(passengers)
+	return &rate
+}
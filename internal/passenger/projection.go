@@ -0,0 +1,150 @@
+package passenger
+
+import (
+	"encoding/json"
+)
+
+// view renders passengers in the response shape of one API version.
+type view struct {
+	render func(p *Passenger) interface{}
+	// attributes read single response values by their json name, it avoids
+	// reflecting over every projected row
+	attributes map[string]func(p *Passenger) interface{}
+	// writable views also register the create, update and delete routes
+	writable bool
+}
+
+var viewV1 = &view{
+	render: func(p *Passenger) interface{} { return convertPassenger(p) },
+	attributes: map[string]func(p *Passenger) interface{}{
+		"id":               func(p *Passenger) interface{} { return p.PassengerId },
+		"survived":         func(p *Passenger) interface{} { return p.Survived },
+		"class":            func(p *Passenger) interface{} { return int(p.Pclass) },
+		"name":             func(p *Passenger) interface{} { return p.Name },
+		"sex":              func(p *Passenger) interface{} { return string(p.Sex) },
+		"age":              func(p *Passenger) interface{} { return p.AgeString() },
+		"siblings-spouses": func(p *Passenger) interface{} { return p.SibSp },
+		"parents-children": func(p *Passenger) interface{} { return p.Parch },
+		"ticket":           func(p *Passenger) interface{} { return p.Ticket },
+		"fare":             func(p *Passenger) interface{} { return p.Fare },
+		"cabin":            func(p *Passenger) interface{} { return p.CabinString() },
+		"embarked":         func(p *Passenger) interface{} { return p.EmbarkedString() },
+		"deck":             func(p *Passenger) interface{} { return cabinDeck(p.CabinString()) },
+		"surname":          nameAttribute("surname"),
+		"title":            nameAttribute("title"),
+		"given-names":      nameAttribute("given-names"),
+		"nickname":         nameAttribute("nickname"),
+		"maiden-name":      nameAttribute("maiden-name"),
+	},
+	writable: true,
+}
+
+var viewV2 = &view{
+	render: func(p *Passenger) interface{} { return convertPassengerV2(p) },
+	attributes: map[string]func(p *Passenger) interface{}{
+		"id":               func(p *Passenger) interface{} { return p.PassengerId },
+		"survived":         func(p *Passenger) interface{} { return p.Survived },
+		"class":            func(p *Passenger) interface{} { return p.Pclass },
+		"name":             func(p *Passenger) interface{} { return p.Name },
+		"sex":              func(p *Passenger) interface{} { return p.Sex },
+		"age":              func(p *Passenger) interface{} { return p.Age },
+		"siblings-spouses": func(p *Passenger) interface{} { return p.SibSp },
+		"parents-children": func(p *Passenger) interface{} { return p.Parch },
+		"ticket":           func(p *Passenger) interface{} { return p.Ticket },
+		"fare":             func(p *Passenger) interface{} { return p.Fare },
+		"cabin":            func(p *Passenger) interface{} { return p.Cabin },
+		"embarked":         func(p *Passenger) interface{} { return p.Embarked },
+		"deck":             func(p *Passenger) interface{} { return cabinDeck(p.CabinString()) },
+		"surname":          nameAttribute("surname"),
+		"title":            nameAttribute("title"),
+		"given-names":      nameAttribute("given-names"),
+		"nickname":         nameAttribute("nickname"),
+		"maiden-name":      nameAttribute("maiden-name"),
+	},
+}
+
+// nameAttribute reads a part of the parsed name, empty when missing in both views.
+func nameAttribute(part string) func(p *Passenger) interface{} {
+	return func(p *Passenger) interface{} { return nameParts[part](ParseName(p.Name)) }
+}
+
+// Projection is a passenger restricted to a set of attributes, encoded in the
+// order the attributes were requested. Projected attributes which were imputed
+// are listed last under imputed.
+type Projection struct {
+	attributes []string
+	view       *view
+	passenger  *Passenger
+}
+
+func (p *Projection) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 0, 32*len(p.attributes))
+	buf = append(buf, '{')
+	for i, a := range p.attributes {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		// attribute names are plain ascii and need no escaping
+		buf = append(buf, '"')
+		buf = append(buf, a...)
+		buf = append(buf, '"', ':')
+
+		value, err := json.Marshal(p.view.attributes[a](p.passenger))
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, value...)
+	}
+
+	var imputed []string
+	for _, a := range p.attributes {
+		for _, name := range p.passenger.Imputed {
+			if a == name {
+				imputed = append(imputed, a)
+			}
+		}
+	}
+	if len(imputed) > 0 {
+		value, err := json.Marshal(imputed)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, `,"imputed":`...)
+		buf = append(buf, value...)
+	}
+	return append(buf, '}'), nil
+}
+
+// Row is a set of named values encoded in order, e.g. the keys and metrics of
+// an aggregation group.
+type Row struct {
+	names  []string
+	values []interface{}
+}
+
+func (r *Row) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 0, 32*len(r.names))
+	buf = append(buf, '{')
+	for i, name := range r.names {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, key...)
+		buf = append(buf, ':')
+
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, value...)
+	}
+	return append(buf, '}'), nil
+}
+
+func (v *view) project(attributes []string, p *Passenger) *Projection {
+	return &Projection{attributes: attributes, view: v, passenger: p}
+}
@@ -0,0 +1,302 @@
+package passenger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInvalidCursor = fmt.Errorf("cursor provided is not valid for this query")
+)
+
+// SortKey orders passengers by a field, see fields for the available names.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Query selects passengers matching Filter ordered by Sort. Passengers are
+// always ordered by id last so the order is total and cursors are stable.
+// Missing values (e.g. blank age) are ordered last in both directions.
+type Query struct {
+	Filter Filter
+	Sort   []SortKey
+	// Limit caps the number of passengers returned, zero means no limit
+	Limit int
+	// Cursor starts the page after (or before) the passenger it was created from
+	Cursor *Cursor
+	// Attributes restricts the passenger fields loaded, empty means all fields
+	Attributes []string
+}
+
+// Cursor is an opaque keyset position in a sorted passenger list.
+type Cursor struct {
+	Sort   string        `json:"s"`
+	Before bool          `json:"b,omitempty"`
+	Values []interface{} `json:"v"`
+}
+
+// sortColumn is a single ordering term, a field may expand into several terms.
+type sortColumn struct {
+	expr  string
+	desc  bool
+	value func(p *Passenger) interface{}
+}
+
+func NewSortKey(name string, desc bool) (SortKey, error) {
+	if _, found := fields[name]; !found {
+		return SortKey{}, fmt.Errorf("unknown sort field provided '%s' in query", name)
+	}
+	return SortKey{Field: name, Desc: desc}, nil
+}
+
+// SortString returns the canonical representation of the query ordering.
+func (q Query) SortString() string {
+	var keys []string
+	for _, k := range q.Sort {
+		switch k.Desc {
+		case true:
+			keys = append(keys, "-"+k.Field)
+		default:
+			keys = append(keys, k.Field)
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// NewCursor returns the cursor positioned at p, paging forward or backward.
+func (q Query) NewCursor(p *Passenger, before bool) *Cursor {
+	return &Cursor{Sort: q.SortString(), Before: before, Values: rowValues(q.columns(), p)}
+}
+
+// Encode returns the opaque string form of the cursor.
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses an encoded cursor and checks it was created for the query ordering.
+func (q Query) DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err = json.Unmarshal(b, &c); err != nil || c.Sort != q.SortString() {
+		return nil, ErrInvalidCursor
+	}
+
+	cols := q.columns()
+	if len(c.Values) != len(cols) {
+		return nil, ErrInvalidCursor
+	}
+	for i, col := range cols {
+		if fmt.Sprintf("%T", c.Values[i]) != fmt.Sprintf("%T", col.value(&Passenger{})) {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &c, nil
+}
+
+func (q Query) columns() []sortColumn {
+	keys := q.Sort
+	var hasID bool
+	for _, k := range keys {
+		hasID = hasID || k.Field == "id"
+	}
+	if !hasID {
+		keys = append(keys[:len(keys):len(keys)], SortKey{Field: "id"})
+	}
+
+	var cols []sortColumn
+	for _, k := range keys {
+		f := fields[k.Field]
+		switch {
+		case f.text != nil:
+			cols = append(cols, sortColumn{expr: f.sqlExpr(), desc: k.Desc,
+				value: func(p *Passenger) interface{} { return f.text(p) }})
+		case len(f.guard) > 0:
+			// order missing values last, then by the value itself
+			cols = append(cols,
+				sortColumn{expr: "(NOT (" + f.guard + "))",
+					value: func(p *Passenger) interface{} {
+						if _, ok := f.number(p); !ok {
+							return float64(1)
+						}
+						return float64(0)
+					}},
+				sortColumn{expr: "IFNULL(" + f.sqlExpr() + ", 0)", desc: k.Desc,
+					value: func(p *Passenger) interface{} {
+						v, _ := f.number(p)
+						return v
+					}})
+		default:
+			cols = append(cols, sortColumn{expr: f.sqlExpr(), desc: k.Desc,
+				value: func(p *Passenger) interface{} {
+					v, _ := f.number(p)
+					return v
+				}})
+		}
+	}
+	return cols
+}
+
+func rowValues(cols []sortColumn, p *Passenger) []interface{} {
+	values := make([]interface{}, len(cols))
+	for i, col := range cols {
+		values[i] = col.value(p)
+	}
+	return values
+}
+
+// compare orders two rows given their column values.
+func compare(cols []sortColumn, a, b []interface{}) int {
+	for i, col := range cols {
+		var rs int
+		switch v := a[i].(type) {
+		case float64:
+			other := b[i].(float64)
+			switch {
+			case v < other:
+				rs = -1
+			case v > other:
+				rs = 1
+			}
+		case string:
+			rs = strings.Compare(v, b[i].(string))
+		}
+		if col.desc {
+			rs = -rs
+		}
+		if rs != 0 {
+			return rs
+		}
+	}
+	return 0
+}
+
+// Apply filters, orders and pages passengers in memory.
+func (q Query) Apply(passengers []*Passenger) []*Passenger {
+	rs := make([]*Passenger, 0, len(passengers))
+	for _, p := range passengers {
+		if q.Filter.Match(p) {
+			rs = append(rs, p)
+		}
+	}
+
+	cols := q.columns()
+	values := make([][]interface{}, len(rs))
+	for i, p := range rs {
+		values[i] = rowValues(cols, p)
+	}
+	sort.Sort(&rowSorter{cols: cols, rows: rs, values: values})
+
+	if q.Cursor != nil {
+		switch q.Cursor.Before {
+		case true:
+			end := sort.Search(len(rs), func(i int) bool { return compare(cols, values[i], q.Cursor.Values) >= 0 })
+			rs = rs[:end]
+		default:
+			start := sort.Search(len(rs), func(i int) bool { return compare(cols, values[i], q.Cursor.Values) > 0 })
+			rs = rs[start:]
+		}
+	}
+
+	if q.Limit > 0 && len(rs) > q.Limit {
+		switch q.Cursor != nil && q.Cursor.Before {
+		case true:
+			rs = rs[len(rs)-q.Limit:]
+		default:
+			rs = rs[:q.Limit]
+		}
+	}
+	return rs
+}
+
+// Columns returns the store columns needed to answer the query, the projected
+// attributes plus the fields used for ordering. Nil means all columns.
+func (q Query) Columns() []string {
+	if len(q.Attributes) == 0 {
+		return nil
+	}
+
+	names := append([]string{"id"}, q.Attributes...)
+	for _, k := range q.Sort {
+		names = append(names, k.Field)
+	}
+
+	var columns []string
+	visited := make(map[string]bool)
+	for _, name := range names {
+		column := fields[name].column
+		if !visited[column] {
+			visited[column] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// OrderSQL returns the order by terms, reversed when paging backward.
+func (q Query) OrderSQL() []string {
+	reverse := q.Cursor != nil && q.Cursor.Before
+
+	var terms []string
+	for _, col := range q.columns() {
+		switch col.desc != reverse {
+		case true:
+			terms = append(terms, col.expr+" DESC")
+		default:
+			terms = append(terms, col.expr+" ASC")
+		}
+	}
+	return terms
+}
+
+// CursorSQL translates the cursor position into a where clause and its arguments.
+func (q Query) CursorSQL() (string, []interface{}) {
+	cols := q.columns()
+
+	var clauses []string
+	var args []interface{}
+	for i, col := range cols {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, cols[j].expr+" = ?")
+			args = append(args, q.Cursor.Values[j])
+		}
+
+		op := ">"
+		if col.desc != q.Cursor.Before {
+			op = "<"
+		}
+		terms = append(terms, col.expr+" "+op+" ?")
+		args = append(args, q.Cursor.Values[i])
+
+		clauses = append(clauses, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+type rowSorter struct {
+	cols   []sortColumn
+	rows   []*Passenger
+	values [][]interface{}
+}
+
+func (s *rowSorter) Len() int {
+	return len(s.rows)
+}
+
+func (s *rowSorter) Less(i, j int) bool {
+	return compare(s.cols, s.values[i], s.values[j]) < 0
+}
+
+func (s *rowSorter) Swap(i, j int) {
+	s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+	s.values[i], s.values[j] = s.values[j], s.values[i]
+}
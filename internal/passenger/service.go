@@ -1,39 +1,415 @@
 package passenger
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"titanic-api/pkg/histogram"
 )
 
+var (
+	ErrInvalidHistogramField = fmt.Errorf("histogram field must be one of age, fare, siblings-spouses, parents-children")
+	ErrInvalidSplitField     = fmt.Errorf("split by field must be one of survived, class, sex, embarked")
+)
+
+var (
+	// histogramFields are the numeric fields a histogram can be built for
+	histogramFields = map[string]bool{"age": true, "fare": true, "siblings-spouses": true, "parents-children": true}
+	// splitFields are the categorical fields a histogram can be split by
+	splitFields = map[string]bool{"survived": true, "class": true, "sex": true, "embarked": true}
+)
+
 type Service interface {
-	Get(pid int) (*Passenger, error)
-	GetAll() ([]*Passenger, error)
-	FarePercentileHistogram() (*histogram.Histogram, error)
+	Get(ctx context.Context, pid int) (*Passenger, error)
+	GetAll(ctx context.Context, query Query) (*Page, error)
+	// Iterate reads the passengers matching query one at a time, with their total
+	Iterate(ctx context.Context, query Query) (PassengerIterator, int, error)
+	Create(ctx context.Context, p *Passenger) error
+	Update(ctx context.Context, p *Passenger) error
+	Delete(ctx context.Context, pid int) error
+	// Import writes the valid rows of an import, rows repeating an id are rejected.
+	// Replace imports are written only when every row is valid, otherwise the
+	// report is returned with ErrImportRejected
+	Import(ctx context.Context, batch Import) (*ImportReport, error)
+	Aggregate(ctx context.Context, aggregation Aggregation) ([]*Group, error)
+	Statistics(ctx context.Context, statistics Statistics) ([]*StatisticsGroup, error)
+	Crosstab(ctx context.Context, crosstab Crosstab) (*Table, error)
+	Correlation(ctx context.Context, correlation Correlation) (*CorrelationMatrix, error)
+	// Impute fills the missing values of passengers, fitting the imputation on every store passenger
+	Impute(ctx context.Context, imputation Imputation, passengers []*Passenger) ([]*Passenger, error)
+	// Fit fits the imputation on every store passenger, to fill passengers read apart
+	Fit(ctx context.Context, imputation Imputation) (*Imputer, error)
+	// Ticket is the party of the passengers sharing ticket
+	Ticket(ctx context.Context, ticket string) (*Party, error)
+	// Party is the travelling party of a passenger, detected over every store passenger
+	Party(ctx context.Context, pid int) (*Party, error)
+	// Decks counts the passengers and survivors of every deck
+	Decks(ctx context.Context) ([]*Deck, error)
+	// Deck counts the passengers and survivors of a deck and of its cabins
+	Deck(ctx context.Context, deck string) (*Deck, error)
+	// Ports are the catalogue ports with the passengers and survivors who boarded there
+	Ports(ctx context.Context) ([]*EmbarkationPort, error)
+	// Port is a catalogue port with its passengers and survivors per class
+	Port(ctx context.Context, code Port) (*EmbarkationPort, error)
+	FarePercentileHistogram(ctx context.Context, percentiles []float64) (*histogram.Histogram, error)
+	Histogram(ctx context.Context, field, splitBy string, opts histogram.Options) (*histogram.Histogram, error)
+}
+
+// Page is a slice of the passengers matching a query. Next and Prev are set
+// when the query is limited and there are more passengers in that direction.
+type Page struct {
+	Passengers []*Passenger
+	Total      int
+	Next       *Cursor
+	Prev       *Cursor
 }
 
 type service struct {
 	store Store
 }
 
-func (s *service) FarePercentileHistogram() (*histogram.Histogram, error) {
-	passengers, err := s.store.GetPassengers()
+func (s *service) FarePercentileHistogram(ctx context.Context, percentiles []float64) (*histogram.Histogram, error) {
+	fares, err := s.fares(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var fares []float64
+	return histogram.Percentile(fares, percentiles)
+}
+
+// Histogram bins the values of a numeric field, passengers missing the value
+// are skipped. When splitBy is set every category gets a series counted over
+// the bins of all values.
+func (s *service) Histogram(ctx context.Context, field, splitBy string, opts histogram.Options) (*histogram.Histogram, error) {
+	switch {
+	case !histogramFields[field]:
+		return nil, ErrInvalidHistogramField
+	case len(splitBy) > 0 && !splitFields[splitBy]:
+		return nil, ErrInvalidSplitField
+	}
+
+	attributes := []string{field}
+	if len(splitBy) > 0 {
+		attributes = append(attributes, splitBy)
+	}
+	passengers, err := s.store.GetPassengers(ctx, Query{Attributes: attributes})
+	if err != nil {
+		return nil, err
+	}
+
+	var values []float64
+	var keys [][]interface{}
+	categories := make(map[interface{}][]float64)
+	for _, p := range passengers {
+		v, ok := fields[field].number(p)
+		if !ok {
+			continue
+		}
+		values = append(values, v)
+
+		if len(splitBy) > 0 {
+			key := fields[splitBy].value(p)
+			if _, found := categories[key]; !found {
+				keys = append(keys, []interface{}{key})
+			}
+			categories[key] = append(categories[key], v)
+		}
+	}
+
+	h, err := histogram.New(values, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return compareKeys(keys[i], keys[j]) < 0 })
+	for _, key := range keys {
+		h.Series = append(h.Series, &histogram.Series{Key: key[0], Entries: h.Count(categories[key[0]])})
+	}
+	return h, nil
+}
+
+func (s *service) fares(ctx context.Context) ([]float64, error) {
+	passengers, err := s.store.GetPassengers(ctx, Query{Attributes: []string{"fare"}})
+	if err != nil {
+		return nil, err
+	}
+
+	fares := make([]float64, 0, len(passengers))
 	for _, p := range passengers {
 		fares = append(fares, p.Fare)
 	}
+	return fares, nil
+}
+
+func (s *service) Aggregate(ctx context.Context, aggregation Aggregation) ([]*Group, error) {
+	return s.store.AggregatePassengers(ctx, aggregation)
+}
+
+func (s *service) Statistics(ctx context.Context, statistics Statistics) ([]*StatisticsGroup, error) {
+	passengers, err := s.store.GetPassengers(ctx, Query{Filter: statistics.Filter, Attributes: statistics.Attributes()})
+	if err != nil {
+		return nil, err
+	}
+
+	return statistics.Apply(passengers), nil
+}
+
+func (s *service) Crosstab(ctx context.Context, crosstab Crosstab) (*Table, error) {
+	passengers, err := s.store.GetPassengers(ctx, Query{
+		Filter:     crosstab.Filter,
+		Attributes: []string{crosstab.Rows, crosstab.Cols},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return crosstab.Apply(passengers), nil
+}
+
+func (s *service) Correlation(ctx context.Context, correlation Correlation) (*CorrelationMatrix, error) {
+	passengers, err := s.store.GetPassengers(ctx, Query{Filter: correlation.Filter, Attributes: correlation.Fields})
+	if err != nil {
+		return nil, err
+	}
+
+	return correlation.Apply(passengers), nil
+}
+
+func (s *service) Impute(ctx context.Context, imputation Imputation, passengers []*Passenger) ([]*Passenger, error) {
+	imputer, err := s.Fit(ctx, imputation)
+	if err != nil {
+		return nil, err
+	}
+
+	return imputer.Apply(passengers), nil
+}
+
+func (s *service) Fit(ctx context.Context, imputation Imputation) (*Imputer, error) {
+	all, err := s.store.GetPassengers(ctx, Query{})
+	if err != nil {
+		return nil, err
+	}
+
+	return imputation.Fit(all), nil
+}
+
+func (s *service) Ticket(ctx context.Context, ticket string) (*Party, error) {
+	condition, err := NewCondition("ticket", OperatorEq, []string{ticket})
+	if err != nil {
+		return nil, err
+	}
+	passengers, err := s.store.GetPassengers(ctx, Query{Filter: Filter{Conditions: []Condition{condition}}})
+	switch {
+	case err != nil:
+		return nil, err
+	case len(passengers) == 0:
+		return nil, ErrTicketNotFound
+	}
+
+	return newParty(passengers, map[string]int{ticket: len(passengers)}), nil
+}
+
+func (s *service) Party(ctx context.Context, pid int) (*Party, error) {
+	passengers, err := s.store.GetPassengers(ctx, Query{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, party := range Parties(passengers) {
+		for _, m := range party.Members {
+			if m.Passenger.PassengerId == pid {
+				return party, nil
+			}
+		}
+	}
+	return nil, ErrPassengerNotFound
+}
+
+func (s *service) Decks(ctx context.Context) ([]*Deck, error) {
+	groups, err := s.store.AggregatePassengers(ctx, Aggregation{GroupBy: []string{"deck"}, Metrics: survivalMetrics})
+	if err != nil {
+		return nil, err
+	}
+
+	return newDecks(groups), nil
+}
+
+func (s *service) Deck(ctx context.Context, deck string) (*Deck, error) {
+	condition, err := NewCondition("deck", OperatorEq, []string{deck})
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.store.AggregatePassengers(ctx, Aggregation{
+		Filter:  Filter{Conditions: []Condition{condition}},
+		GroupBy: []string{"cabin"},
+		Metrics: survivalMetrics,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var cabins []*DeckCabin
+	var passengers, survivors int
+	for _, g := range groups {
+		c := &DeckCabin{Cabin: g.Keys[0].(string)}
+		c.Passengers, c.Survivors = groupCounts(g)
+		passengers, survivors = passengers+c.Passengers, survivors+c.Survivors
+		cabins = append(cabins, c)
+	}
+	if passengers == 0 {
+		return nil, ErrDeckNotFound
+	}
+
+	d := newDeck(deck, passengers, survivors)
+	d.Cabins = cabins
+	return d, nil
+}
+
+func (s *service) Ports(ctx context.Context) ([]*EmbarkationPort, error) {
+	groups, err := s.store.AggregatePassengers(ctx, Aggregation{GroupBy: []string{"embarked"}, Metrics: survivalMetrics})
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]*EmbarkationPort, len(ports))
+	for i, p := range ports {
+		rs[i] = lookupPort(p.Code)
+		rs[i].setCounts(0, 0)
+		for _, g := range groups {
+			if g.Keys[0] == string(p.Code) {
+				rs[i].setCounts(groupCounts(g))
+			}
+		}
+	}
+	return rs, nil
+}
+
+func (s *service) Port(ctx context.Context, code Port) (*EmbarkationPort, error) {
+	port := lookupPort(code)
+	if port == nil {
+		return nil, ErrPortNotFound
+	}
+
+	condition, err := NewCondition("embarked", OperatorEq, []string{string(code)})
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.store.AggregatePassengers(ctx, Aggregation{
+		Filter:  Filter{Conditions: []Condition{condition}},
+		GroupBy: []string{"class"},
+		Metrics: survivalMetrics,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return histogram.Percentile(fares), nil
+	var passengers, survivors int
+	port.Classes = []*PortClass{}
+	for _, g := range groups {
+		c := &PortClass{Class: Class(g.Keys[0].(float64))}
+		c.Passengers, c.Survivors = groupCounts(g)
+		c.SurvivalRate = survivalRate(c.Passengers, c.Survivors)
+		passengers, survivors = passengers+c.Passengers, survivors+c.Survivors
+		port.Classes = append(port.Classes, c)
+	}
+	port.setCounts(passengers, survivors)
+	return port, nil
 }
 
-func (s *service) Get(pid int) (*Passenger, error) {
-	return s.store.GetPassenger(pid)
+func (s *service) Get(ctx context.Context, pid int) (*Passenger, error) {
+	return s.store.GetPassenger(ctx, pid)
+}
+
+func (s *service) GetAll(ctx context.Context, query Query) (*Page, error) {
+	// read one extra passenger to know whether another page follows
+	limited := query
+	if query.Limit > 0 {
+		limited.Limit++
+	}
+
+	passengers, err := s.store.GetPassengers(ctx, limited)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.store.CountPassengers(ctx, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Passengers: passengers, Total: total}
+	if query.Limit == 0 || len(passengers) == 0 {
+		return page, nil
+	}
+
+	backward := query.Cursor != nil && query.Cursor.Before
+	more := len(passengers) > query.Limit
+	if more {
+		switch backward {
+		case true:
+			page.Passengers = passengers[1:]
+		default:
+			page.Passengers = passengers[:query.Limit]
+		}
+	}
+
+	first, last := page.Passengers[0], page.Passengers[len(page.Passengers)-1]
+	switch backward {
+	case true:
+		page.Next = query.NewCursor(last, false)
+		if more {
+			page.Prev = query.NewCursor(first, true)
+		}
+	default:
+		if more {
+			page.Next = query.NewCursor(last, false)
+		}
+		if query.Cursor != nil {
+			page.Prev = query.NewCursor(first, true)
+		}
+	}
+	return page, nil
 }
 
-func (s *service) GetAll() ([]*Passenger, error) {
-	return s.store.GetPassengers()
+func (s *service) Iterate(ctx context.Context, query Query) (PassengerIterator, int, error) {
+	// count first, an open iterator may hold the only store connection
+	total, err := s.store.CountPassengers(ctx, query.Filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	it, err := s.store.IteratePassengers(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	return it, total, nil
+}
+
+func (s *service) Create(ctx context.Context, p *Passenger) error {
+	return s.store.CreatePassenger(ctx, p)
+}
+
+func (s *service) Update(ctx context.Context, p *Passenger) error {
+	return s.store.UpdatePassenger(ctx, p)
+}
+
+func (s *service) Delete(ctx context.Context, pid int) error {
+	return s.store.DeletePassenger(ctx, pid)
+}
+
+func (s *service) Import(ctx context.Context, batch Import) (*ImportReport, error) {
+	batch.validate()
+	if batch.Mode == ImportModeReplace {
+		if report := batch.report(&ImportResult{}); report.Failed > 0 {
+			return report, ErrImportRejected
+		}
+	}
+	result, err := s.store.ImportPassengers(ctx, batch.passengers(), batch.Mode, batch.DryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	return batch.report(result), nil
 }
 
 func NewService(store Store) Service {
@@ -1 +1,31 @@
 package passenger
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServiceImport_ReplaceWithInvalidRows_Rejected(t *testing.T) {
+	Convey("Test service\n", t, func() {
+		path := createStoreFile(t, csvHeader+csvRows)
+		service := NewService(NewStoreCSV(path))
+
+		one, two := 1, 2
+		batch := Import{Mode: ImportModeReplace, Rows: []*ImportRow{
+			{Line: 2, ID: &one, Passenger: &Passenger{PassengerId: 1, Pclass: 3, Name: "Braund", Sex: "male"}},
+			{Line: 3, ID: &two, Errors: []string{"age must be a number"}},
+		}}
+		report, err := service.Import(context.Background(), batch)
+		So(err, ShouldEqual, ErrImportRejected)
+		So(report.Deleted, ShouldEqual, 0)
+		So(report.Failed, ShouldEqual, 1)
+		So(report.Errors[0].Line, ShouldEqual, 3)
+
+		// the passenger of the invalid row is kept
+		stored, err := NewStoreCSV(path).GetPassengers(context.Background(), Query{})
+		So(err, ShouldBeNil)
+		So(len(stored), ShouldEqual, 2)
+	})
+}
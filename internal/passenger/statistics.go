@@ -0,0 +1,109 @@
+package passenger
+
+import (
+	"fmt"
+	"sort"
+	"titanic-api/pkg/stats"
+)
+
+var (
+	// defaultStatisticsFields are summarised when no fields are requested
+	defaultStatisticsFields = []string{"age", "fare", "siblings-spouses", "parents-children"}
+)
+
+// Statistics summarises the numeric Fields of the passengers matching Filter
+// for every group of the GroupBy fields.
+type Statistics struct {
+	Filter  Filter
+	Fields  []string
+	GroupBy []string
+}
+
+// StatisticsGroup holds the summaries of a group, Keys follow GroupBy and
+// Summaries follow Fields.
+type StatisticsGroup struct {
+	Keys      []interface{}
+	Summaries []*stats.Summary
+}
+
+func NewStatistics(filter Filter, fieldNames []string, groupBy []string) (Statistics, error) {
+	if err := validateGroupBy(groupBy); err != nil {
+		return Statistics{}, err
+	}
+
+	visited := make(map[string]bool)
+	for _, name := range fieldNames {
+		f, found := fields[name]
+		switch {
+		case !found:
+			return Statistics{}, fmt.Errorf("unknown statistics field provided '%s' in query", name)
+		case f.number == nil:
+			return Statistics{}, fmt.Errorf("statistics field '%s' is not numeric", name)
+		case visited[name]:
+			return Statistics{}, fmt.Errorf("statistics field '%s' provided multiple times in query", name)
+		}
+		visited[name] = true
+	}
+	if len(fieldNames) == 0 {
+		fieldNames = defaultStatisticsFields
+	}
+
+	return Statistics{Filter: filter, Fields: fieldNames, GroupBy: groupBy}, nil
+}
+
+// Attributes are the passenger attributes read to compute the statistics.
+func (s Statistics) Attributes() []string {
+	return append(append([]string{}, s.Fields...), s.GroupBy...)
+}
+
+// Apply summarises passengers in a single pass, they are expected to match
+// the filter already. Groups are ordered by their keys with missing keys first.
+func (s Statistics) Apply(passengers []*Passenger) []*StatisticsGroup {
+	type bucket struct {
+		keys         []interface{}
+		accumulators []stats.Accumulator
+	}
+
+	buckets := make(map[string]*bucket)
+	var order []*bucket
+	for _, p := range passengers {
+		keys := make([]interface{}, len(s.GroupBy))
+		for i, name := range s.GroupBy {
+			keys[i] = fields[name].value(p)
+		}
+		id := fmt.Sprintf("%#v", keys)
+		b, found := buckets[id]
+		if !found {
+			b = &bucket{keys: keys, accumulators: make([]stats.Accumulator, len(s.Fields))}
+			buckets[id] = b
+			order = append(order, b)
+		}
+
+		for i, name := range s.Fields {
+			switch v, ok := fields[name].number(p); {
+			case ok:
+				b.accumulators[i].Add(v)
+			default:
+				b.accumulators[i].AddMissing()
+			}
+		}
+	}
+
+	if len(s.GroupBy) == 0 && len(order) == 0 {
+		order = append(order, &bucket{keys: []interface{}{}, accumulators: make([]stats.Accumulator, len(s.Fields))})
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return compareKeys(order[i].keys, order[j].keys) < 0
+	})
+
+	groups := make([]*StatisticsGroup, 0, len(order))
+	for _, b := range order {
+		g := &StatisticsGroup{Keys: b.keys, Summaries: make([]*stats.Summary, len(s.Fields))}
+		for i := range s.Fields {
+			g.Summaries[i] = b.accumulators[i].Summary()
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
@@ -1,6 +1,10 @@
 package passenger
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
 
 const (
 	StoreTypeCSV    = "CSV"
@@ -9,24 +13,144 @@ const (
 
 var (
 	ErrPassengerNotFound = fmt.Errorf("passenger not found")
+	ErrPassengerExists   = fmt.Errorf("passenger already exists")
+	ErrTicketNotFound    = fmt.Errorf("ticket not found")
+	ErrDeckNotFound      = fmt.Errorf("deck not found")
+	ErrPortNotFound      = fmt.Errorf("port not found")
 )
 
-type Passenger struct {
-	PassengerId int     `csv:"PassengerId"gorm:"column:id;primary_key"`
-	Survived    int     `csv:"Survived"gorm:"column:survived"`
-	Pclass      int     `csv:"Pclass"gorm:"column:class"`
-	Name        string  `csv:"Name"gorm:"column:name"`
-	Sex         string  `csv:"Sex"gorm:"column:sex"`
-	Age         string  `csv:"Age"gorm:"column:age"`
-	SibSp       int     `csv:"SibSp"gorm:"column:siblings_spouses"`
-	Parch       int     `csv:"Parch"gorm:"column:parents_children"`
-	Ticket      string  `csv:"Ticket"gorm:"column:ticket"`
-	Fare        float64 `csv:"Fare"gorm:"column:fare"`
-	Cabin       string  `csv:"Cabin"gorm:"column:cabin"`
-	Embarked    string  `csv:"Embarked"gorm:"column:embarked"`
+// record is the raw passenger row as stored by both the csv and sqlite stores,
+// missing values are stored as empty strings.
+type record struct {
+	PassengerId int     `csv:"PassengerId" gorm:"column:id;primary_key"`
+	Survived    int     `csv:"Survived" gorm:"column:survived"`
+	Pclass      int     `csv:"Pclass" gorm:"column:class"`
+	Name        string  `csv:"Name" gorm:"column:name"`
+	Sex         string  `csv:"Sex" gorm:"column:sex"`
+	Age         string  `csv:"Age" gorm:"column:age"`
+	SibSp       int     `csv:"SibSp" gorm:"column:siblings_spouses"`
+	Parch       int     `csv:"Parch" gorm:"column:parents_children"`
+	Ticket      string  `csv:"Ticket" gorm:"column:ticket"`
+	Fare        float64 `csv:"Fare" gorm:"column:fare"`
+	Cabin       string  `csv:"Cabin" gorm:"column:cabin"`
+	Embarked    string  `csv:"Embarked" gorm:"column:embarked"`
+}
+
+func (record) TableName() string {
+	return "passengers"
+}
+
+func (r *record) passenger() (*Passenger, error) {
+	p := &Passenger{
+		PassengerId: r.PassengerId,
+		Survived:    r.Survived,
+		Pclass:      Class(r.Pclass),
+		Name:        r.Name,
+		Sex:         Sex(r.Sex),
+		SibSp:       r.SibSp,
+		Parch:       r.Parch,
+		Ticket:      r.Ticket,
+		Fare:        r.Fare,
+	}
+
+	if len(r.Age) > 0 {
+		age, err := strconv.ParseFloat(r.Age, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age '%s' for passenger %d", r.Age, r.PassengerId)
+		}
+		p.Age = &age
+	}
+	if len(r.Cabin) > 0 {
+		cabin := r.Cabin
+		p.Cabin = &cabin
+	}
+	if len(r.Embarked) > 0 {
+		embarked := Port(r.Embarked)
+		p.Embarked = &embarked
+	}
+	return p, nil
+}
+
+func newRecord(p *Passenger) *record {
+	return &record{
+		PassengerId: p.PassengerId,
+		Survived:    p.Survived,
+		Pclass:      int(p.Pclass),
+		Name:        p.Name,
+		Sex:         string(p.Sex),
+		Age:         p.AgeString(),
+		SibSp:       p.SibSp,
+		Parch:       p.Parch,
+		Ticket:      p.Ticket,
+		Fare:        p.Fare,
+		Cabin:       p.CabinString(),
+		Embarked:    p.EmbarkedString(),
+	}
 }
 
 type Store interface {
-	GetPassengers() ([]*Passenger, error)
-	GetPassenger(pid int) (*Passenger, error)
+	GetPassengers(ctx context.Context, query Query) ([]*Passenger, error)
+	// IteratePassengers reads the passengers of GetPassengers one at a time,
+	// the iterator must be closed once done
+	IteratePassengers(ctx context.Context, query Query) (PassengerIterator, error)
+	CountPassengers(ctx context.Context, filter Filter) (int, error)
+	AggregatePassengers(ctx context.Context, aggregation Aggregation) ([]*Group, error)
+	GetPassenger(ctx context.Context, pid int) (*Passenger, error)
+	CreatePassenger(ctx context.Context, p *Passenger) error
+	UpdatePassenger(ctx context.Context, p *Passenger) error
+	DeletePassenger(ctx context.Context, pid int) error
+	// ImportPassengers writes passengers at once according to mode, either all
+	// or none of them. Nothing is written when dryRun is set.
+	ImportPassengers(ctx context.Context, passengers []*Passenger, mode ImportMode, dryRun bool) (*ImportResult, error)
+}
+
+// PassengerIterator reads passengers one at a time:
+//
+//	for it.Next() {
+//		p := it.Passenger()
+//	}
+//	err := it.Err()
+type PassengerIterator interface {
+	// Next advances to the next passenger, false once exhausted or failed
+	Next() bool
+	Passenger() *Passenger
+	// Err is the error which stopped the iteration, nil once exhausted
+	Err() error
+	Close() error
+}
+
+// sliceIterator iterates over passengers held in memory, it stops once ctx is done.
+type sliceIterator struct {
+	ctx        context.Context
+	passengers []*Passenger
+	current    *Passenger
+	err        error
+}
+
+func (it *sliceIterator) Next() bool {
+	if it.err != nil || len(it.passengers) == 0 {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+	it.current, it.passengers = it.passengers[0], it.passengers[1:]
+	return true
+}
+
+func (it *sliceIterator) Passenger() *Passenger {
+	return it.current
+}
+
+func (it *sliceIterator) Err() error {
+	return it.err
+}
+
+func (it *sliceIterator) Close() error {
+	it.passengers = nil
+	return nil
+}
+
+func newSliceIterator(ctx context.Context, passengers []*Passenger) PassengerIterator {
+	return &sliceIterator{ctx: ctx, passengers: passengers}
 }
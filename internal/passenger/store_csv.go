@@ -1,49 +1,477 @@
 package passenger
 
 import (
+	"context"
+	"encoding/csv"
 	"fmt"
+	"github.com/fsnotify/fsnotify"
 	"github.com/gocarina/gocsv"
+	"io"
+	"log"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
+const (
+	// reloadDelay coalesces the burst of events a single save usually produces
+	reloadDelay = 100 * time.Millisecond
+)
+
+// csvSnapshot is an immutable view of the store file indexed by passenger id.
+type csvSnapshot struct {
+	passengers []*Passenger
+	index      map[int]*Passenger
+	// ordered is set when the passengers are stored in ascending id order
+	ordered bool
+}
+
 type csvStore struct {
 	path string
+	// mu guards snapshot, writeMu serializes writes and reloads
+	mu       sync.RWMutex
+	writeMu  sync.Mutex
+	snapshot *csvSnapshot
+}
+
+func (s *csvStore) GetPassenger(ctx context.Context, pid int) (*Passenger, error) {
+	snapshot, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p, found := snapshot.index[pid]
+	if !found {
+		return nil, ErrPassengerNotFound
+	}
+
+	passenger := *p
+	return &passenger, nil
+}
+
+func (s *csvStore) GetPassengers(ctx context.Context, query Query) ([]*Passenger, error) {
+	snapshot, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.Apply(snapshot.passengers), nil
+}
+
+// IteratePassengers decodes the store file row by row when the query reads
+// passengers in id order, which is the file order as written by the store.
+// Other queries are sorted over the loaded snapshot.
+func (s *csvStore) IteratePassengers(ctx context.Context, query Query) (PassengerIterator, error) {
+	snapshot, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(query.Sort) > 0 || query.Cursor != nil || !snapshot.ordered {
+		return newSliceIterator(ctx, query.Apply(snapshot.passengers)), nil
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening store path: %s error: %s", s.path, err.Error())
+	}
+	it := &csvIterator{path: s.path, file: file, query: query}
+	it.unmarshaller, err = gocsv.NewUnmarshaller(csv.NewReader(&contextReader{ctx: ctx, reader: file}), &record{})
+	switch {
+	case err == io.EOF:
+		// an empty file has no passengers
+		it.unmarshaller = nil
+	case err != nil:
+		file.Close()
+		return nil, fmt.Errorf("error loading store data path: %s error: %w", s.path, err)
+	}
+	return it, nil
+}
+
+func (s *csvStore) CountPassengers(ctx context.Context, filter Filter) (int, error) {
+	snapshot, err := s.current(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	for _, p := range snapshot.passengers {
+		if filter.Match(p) {
+			count++
+		}
+	}
+	return count, nil
 }
 
-func (s *csvStore) GetPassenger(pid int) (*Passenger, error) {
-	passengers, err := s.loadPassengers()
+func (s *csvStore) AggregatePassengers(ctx context.Context, aggregation Aggregation) ([]*Group, error) {
+	snapshot, err := s.current(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	return aggregation.Apply(snapshot.passengers), nil
+}
+
+func (s *csvStore) CreatePassenger(ctx context.Context, p *Passenger) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	snapshot, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, found := snapshot.index[p.PassengerId]; found {
+		return ErrPassengerExists
+	}
+
+	// snapshots are shared with readers so keep our own copy of the caller's value
+	stored := *p
+	passengers := make([]*Passenger, len(snapshot.passengers), len(snapshot.passengers)+1)
+	copy(passengers, snapshot.passengers)
+	return s.commit(append(passengers, &stored))
+}
+
+func (s *csvStore) UpdatePassenger(ctx context.Context, p *Passenger) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	snapshot, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, found := snapshot.index[p.PassengerId]; !found {
+		return ErrPassengerNotFound
+	}
+
+	stored := *p
+	passengers := make([]*Passenger, 0, len(snapshot.passengers))
+	for _, existing := range snapshot.passengers {
+		if existing.PassengerId == p.PassengerId {
+			existing = &stored
+		}
+		passengers = append(passengers, existing)
+	}
+	return s.commit(passengers)
+}
+
+func (s *csvStore) DeletePassenger(ctx context.Context, pid int) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	snapshot, err := s.current(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, found := snapshot.index[pid]; !found {
+		return ErrPassengerNotFound
+	}
+
+	passengers := make([]*Passenger, 0, len(snapshot.passengers))
+	for _, existing := range snapshot.passengers {
+		if existing.PassengerId != pid {
+			passengers = append(passengers, existing)
+		}
+	}
+	return s.commit(passengers)
+}
+
+// ImportPassengers writes the imported passengers with a single file replace.
+// Updated passengers keep their position, created ones are appended.
+func (s *csvStore) ImportPassengers(ctx context.Context, passengers []*Passenger, mode ImportMode,
+	dryRun bool) (*ImportResult, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	snapshot, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	var stored []*Passenger
+	if mode == ImportModeReplace {
+		result.Deleted = len(snapshot.index)
+	} else {
+		stored = make([]*Passenger, len(snapshot.passengers), len(snapshot.passengers)+len(passengers))
+		copy(stored, snapshot.passengers)
+	}
+
+	updates := make(map[int]*Passenger)
 	for _, p := range passengers {
-		if p.PassengerId == pid {
-			return p, nil
+		// snapshots are shared with readers so keep our own copy of the caller's value
+		imported := *p
+		_, found := snapshot.index[p.PassengerId]
+		switch {
+		case !found:
+			result.Created++
+			stored = append(stored, &imported)
+		case mode == ImportModeInsert:
+			result.Conflicts = append(result.Conflicts, p.PassengerId)
+		case mode == ImportModeReplace:
+			result.Updated, result.Deleted = result.Updated+1, result.Deleted-1
+			stored = append(stored, &imported)
+		default:
+			result.Updated++
+			updates[p.PassengerId] = &imported
+		}
+	}
+	for i, existing := range stored {
+		if updated, found := updates[existing.PassengerId]; found {
+			stored[i] = updated
 		}
 	}
 
-	return nil, ErrPassengerNotFound
+	if dryRun {
+		return result, nil
+	}
+	if err := s.commit(stored); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func (s *csvStore) GetPassengers() ([]*Passenger, error) {
-	return s.loadPassengers()
+// current returns the loaded snapshot, loading the store file and starting
+// the file watcher on first use.
+func (s *csvStore) current(ctx context.Context) (*csvSnapshot, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	snapshot := s.snapshot
+	s.mu.RUnlock()
+	if snapshot != nil {
+		return snapshot, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snapshot == nil {
+		passengers, err := s.loadPassengers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.snapshot = newCSVSnapshot(passengers)
+		s.watch()
+	}
+	return s.snapshot, nil
 }
 
-func (s *csvStore) loadPassengers() ([]*Passenger, error) {
+// commit persists passengers and publishes them as the current snapshot.
+func (s *csvStore) commit(passengers []*Passenger) error {
+	if err := s.savePassengers(passengers); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.snapshot = newCSVSnapshot(passengers)
+	s.mu.Unlock()
+	return nil
+}
+
+// reload re-reads the store file, keeping the previous snapshot if it fails to parse.
+func (s *csvStore) reload() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	passengers, err := s.loadPassengers(context.Background())
+	if err != nil {
+		log.Printf("csv store reload failed, keeping previous data: %s", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.snapshot = newCSVSnapshot(passengers)
+	s.mu.Unlock()
+}
+
+// watch reloads the store whenever the file changes on disk. The directory is
+// watched rather than the file so replacing the file (rename) is picked up.
+func (s *csvStore) watch() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("csv store failed to create watcher path: %s error: %s", s.path, err)
+		return
+	}
+	if err = watcher.Add(filepath.Dir(s.path)); err != nil {
+		log.Printf("csv store failed to watch path: %s error: %s", s.path, err)
+		watcher.Close()
+		return
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) != filepath.Base(s.path) ||
+					event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				if timer == nil {
+					timer = time.AfterFunc(reloadDelay, s.reload)
+				} else {
+					timer.Reset(reloadDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("csv store watcher error path: %s error: %s", s.path, err)
+			}
+		}
+	}()
+}
+
+func (s *csvStore) loadPassengers(ctx context.Context) ([]*Passenger, error) {
 	file, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE, os.ModePerm)
 	if err != nil {
 		return nil, fmt.Errorf("error opening store path: %s error: %s", s.path, err.Error())
 	}
 	defer file.Close()
 
-	var passengers []*Passenger
-	if err = gocsv.UnmarshalFile(file, &passengers); err != nil {
-		return nil, fmt.Errorf("error loading store data path: %s error: %s", s.path, err.Error())
+	var records []*record
+	if err = gocsv.Unmarshal(&contextReader{ctx: ctx, reader: file}, &records); err != nil {
+		return nil, fmt.Errorf("error loading store data path: %s error: %w", s.path, err)
 	}
 
+	passengers := make([]*Passenger, 0, len(records))
+	for _, r := range records {
+		p, err := r.passenger()
+		if err != nil {
+			return nil, fmt.Errorf("error loading store data path: %s error: %s", s.path, err.Error())
+		}
+		passengers = append(passengers, p)
+	}
 	return passengers, nil
 }
 
+// savePassengers writes passengers into a temporary file next to the store
+// and renames it over the store path, so readers never observe a partial file.
+func (s *csvStore) savePassengers(passengers []*Passenger) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary store file path: %s error: %s", s.path, err.Error())
+	}
+	// cleanup is a no-op once the rename succeeded
+	defer os.Remove(tmp.Name())
+
+	// keep the permissions of the file being replaced
+	if info, err := os.Stat(s.path); err == nil {
+		if err = tmp.Chmod(info.Mode()); err != nil {
+			tmp.Close()
+			return fmt.Errorf("error setting store file mode path: %s error: %s", s.path, err.Error())
+		}
+	}
+
+	records := make([]*record, 0, len(passengers))
+	for _, p := range passengers {
+		records = append(records, newRecord(p))
+	}
+	if err = gocsv.MarshalFile(&records, tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing store data path: %s error: %s", s.path, err.Error())
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing store data path: %s error: %s", s.path, err.Error())
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error closing store data path: %s error: %s", s.path, err.Error())
+	}
+
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("error replacing store data path: %s error: %s", s.path, err.Error())
+	}
+
+	return nil
+}
+
+// contextReader fails reads once ctx is done, which stops the csv parser mid file.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// csvIterator decodes the store file one row at a time, keeping the rows
+// matching the query filter up to its limit.
+type csvIterator struct {
+	path         string
+	file         *os.File
+	unmarshaller *gocsv.Unmarshaller
+	query        Query
+	read         int
+	current      *Passenger
+	err          error
+}
+
+func (it *csvIterator) Next() bool {
+	for it.unmarshaller != nil && it.err == nil && (it.query.Limit == 0 || it.read < it.query.Limit) {
+		value, err := it.unmarshaller.Read()
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			it.err = fmt.Errorf("error loading store data path: %s error: %w", it.path, err)
+			return false
+		}
+
+		p, err := value.(*record).passenger()
+		if err != nil {
+			it.err = fmt.Errorf("error loading store data path: %s error: %s", it.path, err.Error())
+			return false
+		}
+		if it.query.Filter.Match(p) {
+			it.current = p
+			it.read++
+			return true
+		}
+	}
+	return false
+}
+
+func (it *csvIterator) Passenger() *Passenger {
+	return it.current
+}
+
+func (it *csvIterator) Err() error {
+	return it.err
+}
+
+func (it *csvIterator) Close() error {
+	return it.file.Close()
+}
+
+func newCSVSnapshot(passengers []*Passenger) *csvSnapshot {
+	index := make(map[int]*Passenger, len(passengers))
+	ordered := true
+	for i, p := range passengers {
+		if i > 0 && passengers[i-1].PassengerId >= p.PassengerId {
+			ordered = false
+		}
+		// the first row wins on duplicate ids, as a scan would find it first
+		if _, found := index[p.PassengerId]; !found {
+			index[p.PassengerId] = p
+		}
+	}
+	return &csvSnapshot{passengers: passengers, index: index, ordered: ordered}
+}
+
 func NewStoreCSV(path string) Store {
 	return &csvStore{path: path}
 }
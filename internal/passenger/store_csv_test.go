@@ -1 +1,202 @@
 package passenger
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	csvHeader = "PassengerId,Survived,Pclass,Name,Sex,Age,SibSp,Parch,Ticket,Fare,Cabin,Embarked\n"
+	csvRows   = `1,0,3,"Braund, Mr. Owen Harris",male,22,1,0,A/5 21171,7.25,,S
+2,1,1,"Cumings, Mrs. John Bradley (Florence Briggs Thayer)",female,38,1,0,PC 17599,71.2833,C85,C
+`
+)
+
+func createStoreFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "titanic.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func waitForPassengers(store Store, count int) []*Passenger {
+	var passengers []*Passenger
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); {
+		passengers, _ = store.GetPassengers(context.Background(), Query{})
+		if len(passengers) == count {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return passengers
+}
+
+func TestStoreCSV_Lookup(t *testing.T) {
+	store := NewStoreCSV(createStoreFile(t, csvHeader+csvRows))
+
+	Convey("Test csv store\n", t, func() {
+		Convey("Existing Passenger Found", func() {
+			p, err := store.GetPassenger(context.Background(), 2)
+			So(err, ShouldBeNil)
+			So(p.Name, ShouldEqual, "Cumings, Mrs. John Bradley (Florence Briggs Thayer)")
+		})
+		Convey("Missing Passenger Not Found", func() {
+			_, err := store.GetPassenger(context.Background(), 3)
+			So(err, ShouldEqual, ErrPassengerNotFound)
+		})
+	})
+}
+
+func TestStoreCSV_CancelledContext(t *testing.T) {
+	store := NewStoreCSV(createStoreFile(t, csvHeader+csvRows))
+
+	Convey("Test csv store\n", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := store.GetPassengers(ctx, Query{})
+		So(errors.Is(err, context.Canceled), ShouldBeTrue)
+	})
+}
+
+func TestStoreCSV_WriteOperations(t *testing.T) {
+	path := createStoreFile(t, csvHeader+csvRows)
+	store := NewStoreCSV(path)
+
+	Convey("Test csv store\n", t, func() {
+		age := 26.0
+		p := &Passenger{PassengerId: 3, Survived: 1, Pclass: 3, Name: "Heikkinen, Miss. Laina", Sex: "female", Age: &age}
+
+		So(store.CreatePassenger(context.Background(), p), ShouldBeNil)
+		So(store.CreatePassenger(context.Background(), p), ShouldEqual, ErrPassengerExists)
+
+		age = 27
+		So(store.UpdatePassenger(context.Background(), p), ShouldBeNil)
+
+		Convey("Changes Persisted To File", func() {
+			passengers, err := NewStoreCSV(path).GetPassengers(context.Background(), Query{})
+			So(err, ShouldBeNil)
+			So(len(passengers), ShouldEqual, 3)
+			So(*passengers[2].Age, ShouldEqual, 27)
+		})
+
+		So(store.DeletePassenger(context.Background(), 3), ShouldBeNil)
+		So(store.DeletePassenger(context.Background(), 3), ShouldEqual, ErrPassengerNotFound)
+	})
+}
+
+func TestStoreCSV_ReloadOnChange(t *testing.T) {
+	path := createStoreFile(t, csvHeader+csvRows)
+	store := NewStoreCSV(path)
+
+	Convey("Test csv store\n", t, func() {
+		passengers, err := store.GetPassengers(context.Background(), Query{})
+		So(err, ShouldBeNil)
+		So(len(passengers), ShouldEqual, 2)
+
+		// valid file is reloaded
+		row := "3,1,3,\"Heikkinen, Miss. Laina\",female,26,0,0,STON/O2. 3101282,7.925,,S\n"
+		So(os.WriteFile(path, []byte(csvHeader+csvRows+row), 0644), ShouldBeNil)
+		So(len(waitForPassengers(store, 3)), ShouldEqual, 3)
+
+		// invalid file keeps previous data
+		So(os.WriteFile(path, []byte(csvHeader+"not-a-number,0,3,name,male,22,1,0,ticket,7.25,,S\n"), 0644), ShouldBeNil)
+		time.Sleep(3 * reloadDelay)
+		p, err := store.GetPassenger(context.Background(), 3)
+		So(err, ShouldBeNil)
+		So(p.Name, ShouldEqual, "Heikkinen, Miss. Laina")
+	})
+}
+
+func TestStoreCSV_IteratePassengers(t *testing.T) {
+	row := "3,1,3,\"Heikkinen, Miss. Laina\",female,26,0,0,STON/O2. 3101282,7.925,,S\n"
+	store := NewStoreCSV(createStoreFile(t, csvHeader+csvRows+row))
+
+	read := func(query Query) []int {
+		it, err := store.IteratePassengers(context.Background(), query)
+		So(err, ShouldBeNil)
+		defer it.Close()
+
+		var ids []int
+		for it.Next() {
+			ids = append(ids, it.Passenger().PassengerId)
+		}
+		So(it.Err(), ShouldBeNil)
+		return ids
+	}
+
+	Convey("Test csv store\n", t, func() {
+		survived, err := NewCondition("survived", OperatorEq, []string{"1"})
+		So(err, ShouldBeNil)
+
+		Convey("Streamed In File Order", func() {
+			So(read(Query{}), ShouldResemble, []int{1, 2, 3})
+			So(read(Query{Filter: Filter{Conditions: []Condition{survived}}}), ShouldResemble, []int{2, 3})
+			So(read(Query{Filter: Filter{Conditions: []Condition{survived}}, Limit: 1}), ShouldResemble, []int{2})
+		})
+		Convey("Sorted In Memory", func() {
+			So(read(Query{Sort: []SortKey{{Field: "fare", Desc: true}}}), ShouldResemble, []int{2, 3, 1})
+		})
+		Convey("Cancelled Context", func() {
+			c, cancel := context.WithCancel(context.Background())
+			it, err := store.IteratePassengers(c, Query{})
+			So(err, ShouldBeNil)
+			defer it.Close()
+
+			So(it.Next(), ShouldBeTrue)
+			cancel()
+			for it.Next() {
+			}
+			So(errors.Is(it.Err(), context.Canceled), ShouldBeTrue)
+		})
+	})
+}
+
+func TestStoreCSV_ImportPassengers(t *testing.T) {
+	Convey("Test csv store\n", t, func() {
+		// every case starts over from a new file
+		path := createStoreFile(t, csvHeader+csvRows)
+		store := NewStoreCSV(path)
+
+		existing, err := store.GetPassenger(context.Background(), 1)
+		So(err, ShouldBeNil)
+		existing.Survived = 1
+		created := &Passenger{PassengerId: 3, Survived: 1, Pclass: 3, Name: "Heikkinen, Miss. Laina", Sex: "female"}
+		passengers := []*Passenger{existing, created}
+
+		Convey("Dry Run Writes Nothing", func() {
+			rs, err := store.ImportPassengers(context.Background(), passengers, ImportModeReplace, true)
+			So(err, ShouldBeNil)
+			So(rs, ShouldResemble, &ImportResult{Created: 1, Updated: 1, Deleted: 1})
+			stored, err := NewStoreCSV(path).GetPassengers(context.Background(), Query{})
+			So(err, ShouldBeNil)
+			So(len(stored), ShouldEqual, 2)
+		})
+		Convey("Insert Rejects Stored Passengers", func() {
+			rs, err := store.ImportPassengers(context.Background(), passengers, ImportModeInsert, false)
+			So(err, ShouldBeNil)
+			So(rs, ShouldResemble, &ImportResult{Created: 1, Conflicts: []int{1}})
+
+			p, err := NewStoreCSV(path).GetPassenger(context.Background(), 1)
+			So(err, ShouldBeNil)
+			So(p.Survived, ShouldEqual, 0)
+		})
+		Convey("Upsert Updates In Place", func() {
+			rs, err := store.ImportPassengers(context.Background(), passengers, ImportModeUpsert, false)
+			So(err, ShouldBeNil)
+			So(rs, ShouldResemble, &ImportResult{Created: 1, Updated: 1})
+
+			stored, err := NewStoreCSV(path).GetPassengers(context.Background(), Query{})
+			So(err, ShouldBeNil)
+			So(len(stored), ShouldEqual, 3)
+			So(stored[0].Survived, ShouldEqual, 1)
+		})
+	})
+}
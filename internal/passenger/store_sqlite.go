@@ -1,45 +1,396 @@
 package passenger
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"strconv"
+	"strings"
+)
+
+const (
+	// importBatchSize keeps inserts under the sqlite limit of bound variables
+	importBatchSize = 50
+	// iterateChunkSize is the number of passengers read at once by iterators
+	iterateChunkSize = 200
+)
+
+var (
+	errDryRun = fmt.Errorf("dry run")
 )
 
 type sqliteStore struct {
 	connector Connector
 }
 
-func (s *sqliteStore) GetPassenger(pid int) (*Passenger, error) {
-	db, err := s.connector.Get()
+func (s *sqliteStore) GetPassenger(ctx context.Context, pid int) (*Passenger, error) {
+	db, err := s.connector.Get(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var passenger Passenger
-	if err = db.Where("id = ?", pid).First(&passenger).Error; err != nil {
+	var r record
+	if err = db.Where("id = ?", pid).First(&r).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrPassengerNotFound
 		}
 		return nil, err
 	}
-	return &passenger, nil
+	return r.passenger()
 }
 
-func (s *sqliteStore) GetPassengers() ([]*Passenger, error) {
-	db, err := s.connector.Get()
+func (s *sqliteStore) GetPassengers(ctx context.Context, query Query) ([]*Passenger, error) {
+	db, err := s.connector.Get(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var passengers []*Passenger
-	if err := db.Find(&passengers).Error; err != nil {
-		// Handle the error
-		fmt.Println("Error:", err.Error())
+	var records []*record
+	if err := s.find(db, query).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("error loading passengers: %w", err)
+	}
+
+	passengers := make([]*Passenger, 0, len(records))
+	for _, r := range records {
+		p, err := r.passenger()
+		if err != nil {
+			return nil, err
+		}
+		passengers = append(passengers, p)
+	}
+
+	// backward pages are read in reverse order
+	if query.Cursor != nil && query.Cursor.Before {
+		for i, j := 0, len(passengers)-1; i < j; i, j = i+1, j-1 {
+			passengers[i], passengers[j] = passengers[j], passengers[i]
+		}
 	}
 	return passengers, nil
 }
 
+// IteratePassengers reads the passengers of the query in keyset pages of
+// iterateChunkSize. Backward pages are read in reverse order, being limited
+// they are loaded whole instead.
+func (s *sqliteStore) IteratePassengers(ctx context.Context, query Query) (PassengerIterator, error) {
+	if query.Cursor != nil && query.Cursor.Before {
+		passengers, err := s.GetPassengers(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return newSliceIterator(ctx, passengers), nil
+	}
+
+	it := &chunkIterator{ctx: ctx, store: s, query: query, remaining: query.Limit}
+	// the first page is read now so query errors are returned before streaming
+	if !it.fetch() && it.err != nil {
+		return nil, it.err
+	}
+	return it, nil
+}
+
+// find selects the passengers of query, ordered and limited.
+func (s *sqliteStore) find(db *gorm.DB, query Query) *gorm.DB {
+	tx := s.where(db, query.Filter)
+	if columns := query.Columns(); len(columns) > 0 {
+		tx = tx.Select(columns)
+	}
+	if query.Cursor != nil {
+		clause, args := query.CursorSQL()
+		tx = tx.Where(clause, args...)
+	}
+	for _, term := range query.OrderSQL() {
+		tx = tx.Order(term)
+	}
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+	return tx
+}
+
+// chunkIterator reads passengers a page at a time, each page after the last
+// passenger of the previous one. The connection is only held while a page is
+// read, so slow readers do not block the other store requests.
+type chunkIterator struct {
+	ctx   context.Context
+	store *sqliteStore
+	query Query
+	// remaining is the number of passengers left under the query limit, zero
+	// when the query is not limited
+	remaining int
+	page      []*Passenger
+	current   *Passenger
+	done      bool
+	err       error
+}
+
+func (it *chunkIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.page) == 0 && (it.done || !it.fetch()) {
+		return false
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// fetch reads the next page, false once the passengers are exhausted or on errors.
+func (it *chunkIterator) fetch() bool {
+	query := it.query
+	query.Limit = iterateChunkSize
+	if it.query.Limit > 0 && it.remaining < iterateChunkSize {
+		query.Limit = it.remaining
+	}
+
+	page, err := it.store.GetPassengers(it.ctx, query)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.done = len(page) < query.Limit
+	if it.query.Limit > 0 {
+		it.remaining -= len(page)
+		it.done = it.done || it.remaining == 0
+	}
+	if len(page) > 0 {
+		it.query.Cursor = it.query.NewCursor(page[len(page)-1], false)
+	}
+	it.page = page
+	return len(page) > 0
+}
+
+func (it *chunkIterator) Passenger() *Passenger {
+	return it.current
+}
+
+func (it *chunkIterator) Err() error {
+	return it.err
+}
+
+func (it *chunkIterator) Close() error {
+	it.page, it.done = nil, true
+	return nil
+}
+
+func (s *sqliteStore) CountPassengers(ctx context.Context, filter Filter) (int, error) {
+	db, err := s.connector.Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := s.where(db, filter).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error counting passengers: %w", err)
+	}
+	return int(count), nil
+}
+
+func (s *sqliteStore) AggregatePassengers(ctx context.Context, aggregation Aggregation) ([]*Group, error) {
+	db, err := s.connector.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var selects, groups []string
+	for _, name := range aggregation.GroupBy {
+		selects = append(selects, fields[name].sqlValue())
+		groups = append(groups, fields[name].sqlValue())
+	}
+	for _, m := range aggregation.Metrics {
+		selects = append(selects, m.SQL())
+	}
+
+	tx := s.where(db, aggregation.Filter).Select(strings.Join(selects, ", "))
+	if len(groups) > 0 {
+		// missing keys are NULL and sort first
+		tx = tx.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+	}
+
+	rows, err := tx.Rows()
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating passengers: %w", err)
+	}
+	defer rows.Close()
+
+	var rs []*Group
+	for rows.Next() {
+		values := make([]interface{}, len(selects))
+		dest := make([]interface{}, len(selects))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("error aggregating passengers: %w", err)
+		}
+
+		g := &Group{
+			Keys:   make([]interface{}, len(aggregation.GroupBy)),
+			Values: make([]*float64, len(aggregation.Metrics)),
+		}
+		for i, name := range aggregation.GroupBy {
+			g.Keys[i] = scanValue(fields[name], values[i])
+		}
+		for i := range aggregation.Metrics {
+			if v, ok := scanValue(field{}, values[len(g.Keys)+i]).(float64); ok {
+				g.Values[i] = &v
+			}
+		}
+		rs = append(rs, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error aggregating passengers: %w", err)
+	}
+	return rs, nil
+}
+
+// scanValue converts a scanned column into the value type of f, a float64
+// unless f is a text field.
+func scanValue(f field, v interface{}) interface{} {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case []byte:
+		if f.text != nil {
+			return string(v)
+		}
+		n, _ := strconv.ParseFloat(string(v), 64)
+		return n
+	case string:
+		if f.text != nil {
+			return v
+		}
+		n, _ := strconv.ParseFloat(v, 64)
+		return n
+	}
+	return nil
+}
+
+func (s *sqliteStore) where(db *gorm.DB, filter Filter) *gorm.DB {
+	tx := db.Model(&record{})
+	for _, c := range filter.Conditions {
+		clause, args := c.SQL()
+		tx = tx.Where(clause, args...)
+	}
+	return tx
+}
+
+func (s *sqliteStore) CreatePassenger(ctx context.Context, p *Passenger) error {
+	db, err := s.connector.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&record{}).Where("id = ?", p.PassengerId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrPassengerExists
+		}
+		return tx.Create(newRecord(p)).Error
+	})
+}
+
+func (s *sqliteStore) UpdatePassenger(ctx context.Context, p *Passenger) error {
+	db, err := s.connector.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	// select all columns so zero values (e.g. survived = 0) are written as well
+	rs := db.Model(&record{}).Where("id = ?", p.PassengerId).Select("*").Updates(newRecord(p))
+	if rs.Error != nil {
+		return rs.Error
+	}
+	if rs.RowsAffected == 0 {
+		return ErrPassengerNotFound
+	}
+	return nil
+}
+
+func (s *sqliteStore) DeletePassenger(ctx context.Context, pid int) error {
+	db, err := s.connector.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	rs := db.Where("id = ?", pid).Delete(&record{})
+	if rs.Error != nil {
+		return rs.Error
+	}
+	if rs.RowsAffected == 0 {
+		return ErrPassengerNotFound
+	}
+	return nil
+}
+
+func (s *sqliteStore) ImportPassengers(ctx context.Context, passengers []*Passenger, mode ImportMode,
+	dryRun bool) (*ImportResult, error) {
+	db, err := s.connector.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var ids []int
+		if err := tx.Model(&record{}).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		stored := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			stored[id] = true
+		}
+
+		if mode == ImportModeReplace {
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&record{}).Error; err != nil {
+				return err
+			}
+			result.Deleted = len(stored)
+		}
+
+		var records []*record
+		for _, p := range passengers {
+			switch {
+			case !stored[p.PassengerId]:
+				result.Created++
+				records = append(records, newRecord(p))
+			case mode == ImportModeInsert:
+				result.Conflicts = append(result.Conflicts, p.PassengerId)
+			case mode == ImportModeReplace:
+				result.Updated, result.Deleted = result.Updated+1, result.Deleted-1
+				records = append(records, newRecord(p))
+			default:
+				result.Updated++
+				// select all columns so zero values (e.g. survived = 0) are written as well
+				err := tx.Model(&record{}).Where("id = ?", p.PassengerId).Select("*").Updates(newRecord(p)).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if len(records) > 0 {
+			if err := tx.CreateInBatches(records, importBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		// dry runs roll the transaction back
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, fmt.Errorf("error importing passengers: %w", err)
+	}
+	return result, nil
+}
+
 func NewStoreSQLite(connector Connector) Store {
 	return &sqliteStore{connector: connector}
 }
@@ -1 +1,220 @@
 package passenger
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const sqliteSchema = `CREATE TABLE passengers (
+	id INTEGER PRIMARY KEY,
+	survived INTEGER,
+	class INTEGER,
+	name TEXT,
+	sex TEXT,
+	age TEXT,
+	siblings_spouses INTEGER,
+	parents_children INTEGER,
+	ticket TEXT,
+	fare REAL,
+	cabin TEXT,
+	embarked TEXT
+)`
+
+// createStoreDB creates a sqlite store in a new database holding records.
+func createStoreDB(t *testing.T, records []*record) Store {
+	connector := NewConnector(filepath.Join(t.TempDir(), "titanic.db"))
+	db, err := connector.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Exec(sqliteSchema).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(records) > 0 {
+		if err = db.CreateInBatches(records, importBatchSize).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewStoreSQLite(connector)
+}
+
+// createRecords creates size records with ids from 1, every other one survived.
+func createRecords(size int) []*record {
+	var records []*record
+	for i := 1; i <= size; i++ {
+		records = append(records, &record{PassengerId: i, Survived: i % 2, Pclass: 3, Name: "Doe, Mr. John",
+			Sex: "male", Age: "30", Ticket: "A123", Fare: float64(i), Embarked: "S"})
+	}
+	return records
+}
+
+func TestStoreSQLite_IteratePassengers(t *testing.T) {
+	Convey("Test sqlite store\n", t, func() {
+		store := createStoreDB(t, createRecords(2*iterateChunkSize+10))
+		survived, err := NewCondition("survived", OperatorEq, []string{"1"})
+		So(err, ShouldBeNil)
+
+		for _, query := range []Query{
+			{},
+			{Filter: Filter{Conditions: []Condition{survived}}},
+			{Sort: []SortKey{{Field: "fare", Desc: true}}, Limit: iterateChunkSize + 5},
+			{Sort: []SortKey{{Field: "fare", Desc: true}}, Attributes: []string{"name"}},
+			// every name is the same, pages follow on the id
+			{Sort: []SortKey{{Field: "name"}, {Field: "survived", Desc: true}}},
+		} {
+			expected, err := store.GetPassengers(context.Background(), query)
+			So(err, ShouldBeNil)
+
+			it, err := store.IteratePassengers(context.Background(), query)
+			So(err, ShouldBeNil)
+			var passengers []*Passenger
+			for it.Next() {
+				passengers = append(passengers, it.Passenger())
+			}
+			So(it.Err(), ShouldBeNil)
+			So(it.Close(), ShouldBeNil)
+			So(passengers, ShouldResemble, expected)
+		}
+	})
+}
+
+func TestStoreSQLite_IteratePassengers_ConnectionReleased(t *testing.T) {
+	Convey("Test sqlite store\n", t, func() {
+		store := createStoreDB(t, createRecords(2*iterateChunkSize))
+
+		it, err := store.IteratePassengers(context.Background(), Query{})
+		So(err, ShouldBeNil)
+		defer it.Close()
+		So(it.Next(), ShouldBeTrue)
+
+		// the open iterator does not hold the only connection
+		c, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		p, err := store.GetPassenger(c, 2)
+		So(err, ShouldBeNil)
+		So(p.PassengerId, ShouldEqual, 2)
+
+		count := 1
+		for it.Next() {
+			count++
+		}
+		So(it.Err(), ShouldBeNil)
+		So(count, ShouldEqual, 2*iterateChunkSize)
+	})
+}
+
+func TestStoreSQLite_AggregatePassengers_CountText(t *testing.T) {
+	Convey("Test sqlite store\n", t, func() {
+		records := createRecords(4)
+		records[0].Cabin = "C85"
+		records[1].Embarked = ""
+		store := createStoreDB(t, records)
+		// missing text values may also be NULL
+		db, err := store.(*sqliteStore).connector.Get(context.Background())
+		So(err, ShouldBeNil)
+		So(db.Exec("UPDATE passengers SET cabin = NULL, embarked = NULL WHERE id = 3").Error, ShouldBeNil)
+
+		aggregation, err := NewAggregation(Filter{}, nil, []string{"count", "count(cabin)", "count(embarked)"})
+		So(err, ShouldBeNil)
+		groups, err := store.AggregatePassengers(context.Background(), aggregation)
+		So(err, ShouldBeNil)
+		So(len(groups), ShouldEqual, 1)
+		So(*groups[0].Values[0], ShouldEqual, 4)
+		So(*groups[0].Values[1], ShouldEqual, 1)
+		So(*groups[0].Values[2], ShouldEqual, 2)
+	})
+}
+
+func TestStoreSQLite_ImportPassengers(t *testing.T) {
+	for _, tc := range []struct {
+		mode     ImportMode
+		expected *ImportResult
+		// stored is the number of passengers once written
+		stored int
+	}{
+		{mode: ImportModeUpsert, expected: &ImportResult{Created: importBatchSize + 1, Updated: 1}, stored: importBatchSize + 3},
+		{mode: ImportModeInsert, expected: &ImportResult{Created: importBatchSize + 1, Conflicts: []int{1}},
+			stored: importBatchSize + 3},
+		{mode: ImportModeReplace, expected: &ImportResult{Created: importBatchSize + 1, Updated: 1, Deleted: 1},
+			stored: importBatchSize + 2},
+	} {
+		Convey("Test sqlite store "+string(tc.mode)+"\n", t, func() {
+			// every case starts over from a new database
+			store := createStoreDB(t, createRecords(2))
+
+			existing, err := store.GetPassenger(context.Background(), 1)
+			So(err, ShouldBeNil)
+			existing.Survived = 0
+			// more passengers than a single insert batch are created
+			passengers := []*Passenger{existing}
+			for _, r := range createRecords(importBatchSize + 3)[2:] {
+				p, err := r.passenger()
+				So(err, ShouldBeNil)
+				passengers = append(passengers, p)
+			}
+
+			Convey("Dry Run Writes Nothing", func() {
+				rs, err := store.ImportPassengers(context.Background(), passengers, tc.mode, true)
+				So(err, ShouldBeNil)
+				So(rs, ShouldResemble, tc.expected)
+
+				stored, err := store.GetPassengers(context.Background(), Query{})
+				So(err, ShouldBeNil)
+				So(len(stored), ShouldEqual, 2)
+				So(stored[0].Survived, ShouldEqual, 1)
+			})
+			Convey("Import Is Written", func() {
+				rs, err := store.ImportPassengers(context.Background(), passengers, tc.mode, false)
+				So(err, ShouldBeNil)
+				So(rs, ShouldResemble, tc.expected)
+
+				stored, err := store.GetPassengers(context.Background(), Query{})
+				So(err, ShouldBeNil)
+				So(len(stored), ShouldEqual, tc.stored)
+				// inserts keep the stored passenger
+				So(stored[0].Survived == 1, ShouldEqual, tc.mode == ImportModeInsert)
+
+				_, err = store.GetPassenger(context.Background(), 2)
+				if tc.mode == ImportModeReplace {
+					So(err, ShouldEqual, ErrPassengerNotFound)
+				} else {
+					So(err, ShouldBeNil)
+				}
+			})
+		})
+	}
+}
+
+func TestStoreSQLite_Functions(t *testing.T) {
+	Convey("Test sqlite store\n", t, func() {
+		records := createRecords(3)
+		records[0].Name, records[0].Cabin = "Cumings, Mrs. John Bradley (Florence Briggs Thayer)", "C85"
+		records[1].Name, records[1].Cabin = "Heikkinen, Miss. Laina", "F G73"
+		records[2].Name = "Futrelle, Mrs. Jacques Heath (Lily May Peel)"
+		store := createStoreDB(t, records)
+
+		Convey("Name Parts Are Filtered", func() {
+			title, err := NewCondition("title", OperatorEq, []string{"Mrs"})
+			So(err, ShouldBeNil)
+			passengers, err := store.GetPassengers(context.Background(), Query{Filter: Filter{Conditions: []Condition{title}},
+				Sort: []SortKey{{Field: "surname"}}})
+			So(err, ShouldBeNil)
+			So(len(passengers), ShouldEqual, 2)
+			So(passengers[0].PassengerId, ShouldEqual, 1)
+			So(passengers[1].PassengerId, ShouldEqual, 3)
+		})
+		Convey("Decks Are Grouped", func() {
+			aggregation, err := NewAggregation(Filter{}, []string{"deck"}, []string{"count"})
+			So(err, ShouldBeNil)
+			groups, err := store.AggregatePassengers(context.Background(), aggregation)
+			So(err, ShouldBeNil)
+			So(len(groups), ShouldEqual, 3)
+			So(groups[1].Keys, ShouldResemble, []interface{}{"C"})
+			So(groups[2].Keys, ShouldResemble, []interface{}{"F"})
+		})
+	})
+}
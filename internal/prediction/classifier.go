@@ -0,0 +1,126 @@
+package prediction
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"titanic-api/internal/passenger"
+)
+
+// childAge is the age below which a passenger is a child for the baselines.
+const childAge = 16
+
+// Classifier scores the survival of passengers after being fitted to
+// passengers of known survival.
+type Classifier interface {
+	Fit(passengers []*passenger.Passenger) error
+	// Probability is the survival probability of p
+	Probability(p *passenger.Passenger) float64
+}
+
+const (
+	ClassifierLogistic      = "logistic"
+	ClassifierMajority      = "majority"
+	ClassifierSex           = "sex"
+	ClassifierWomenChildren = "women-children-first"
+	ClassifierSexClass      = "sex-class"
+)
+
+// classifiers create the available classifiers by name, baselines predict the
+// survival rate of the fitting passengers in the same group.
+var classifiers = map[string]func() Classifier{
+	ClassifierLogistic: func() Classifier { return &logisticClassifier{} },
+	ClassifierMajority: func() Classifier {
+		return &groupClassifier{group: func(p *passenger.Passenger) string { return "" }}
+	},
+	ClassifierSex: func() Classifier {
+		return &groupClassifier{group: func(p *passenger.Passenger) string { return string(p.Sex) }}
+	},
+	ClassifierWomenChildren: func() Classifier {
+		return &groupClassifier{group: func(p *passenger.Passenger) string {
+			if p.Sex == passenger.SexFemale || (p.Age != nil && *p.Age < childAge) {
+				return "women-children"
+			}
+			return "men"
+		}}
+	},
+	ClassifierSexClass: func() Classifier {
+		return &groupClassifier{group: func(p *passenger.Passenger) string {
+			return fmt.Sprintf("%s-%d", p.Sex, p.Pclass)
+		}}
+	},
+}
+
+// NewClassifier creates an unfitted classifier by name.
+func NewClassifier(name string) (Classifier, error) {
+	create, found := classifiers[name]
+	if !found {
+		return nil, fmt.Errorf("unknown classifier '%s', must be one of %s", name, strings.Join(ClassifierNames(), ", "))
+	}
+	return create(), nil
+}
+
+// ClassifierNames are the names of the available classifiers in order.
+func ClassifierNames() []string {
+	var names []string
+	for name := range classifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// logisticClassifier is the survival model served by the predict endpoint.
+type logisticClassifier struct {
+	model *Model
+}
+
+func (c *logisticClassifier) Fit(passengers []*passenger.Passenger) error {
+	m, err := train(passengers)
+	if err != nil {
+		return err
+	}
+	c.model = m
+	return nil
+}
+
+func (c *logisticClassifier) Probability(p *passenger.Passenger) float64 {
+	return c.model.predict(p).Probability
+}
+
+// groupClassifier predicts the survival rate of the group of a passenger,
+// a group unseen while fitting gets the overall survival rate.
+type groupClassifier struct {
+	group func(p *passenger.Passenger) string
+	rates map[string]float64
+	rate  float64
+}
+
+func (c *groupClassifier) Fit(passengers []*passenger.Passenger) error {
+	if len(passengers) == 0 {
+		return fmt.Errorf("no passengers provided to fit on")
+	}
+
+	survived, counts := make(map[string]int), make(map[string]int)
+	var total int
+	for _, p := range passengers {
+		g := c.group(p)
+		survived[g] += p.Survived
+		counts[g]++
+		total += p.Survived
+	}
+
+	c.rates = make(map[string]float64, len(counts))
+	for g, n := range counts {
+		c.rates[g] = float64(survived[g]) / float64(n)
+	}
+	c.rate = float64(total) / float64(len(passengers))
+	return nil
+}
+
+func (c *groupClassifier) Probability(p *passenger.Passenger) float64 {
+	if rate, found := c.rates[c.group(p)]; found {
+		return rate
+	}
+	return c.rate
+}
@@ -0,0 +1,132 @@
+package prediction
+
+import (
+	"fmt"
+	"titanic-api/internal/passenger"
+	"titanic-api/pkg/evaluation"
+)
+
+type Method string
+
+const (
+	MethodSplit      Method = "split"
+	MethodKFold      Method = "kfold"
+	MethodStratified Method = "stratified"
+)
+
+const (
+	DefaultFolds    = 5
+	DefaultTestSize = 0.25
+	DefaultSeed     = 1
+)
+
+// Evaluation scores a classifier on held out passengers, either a single
+// train/test split or k folds each held out in turn.
+type Evaluation struct {
+	Classifier string
+	Method     Method
+	Folds      int
+	TestSize   float64
+	Seed       int64
+}
+
+// Report holds the metrics of the predictions pooled over every held out
+// passenger, with the metrics of each fold when cross-validating.
+type Report struct {
+	Classifier   string                `json:"classifier"`
+	Method       Method                `json:"method"`
+	Folds        int                   `json:"folds,omitempty"`
+	TestSize     float64               `json:"test-size,omitempty"`
+	Seed         int64                 `json:"seed"`
+	Metrics      *evaluation.Metrics   `json:"metrics"`
+	FoldsMetrics []*evaluation.Metrics `json:"folds-metrics,omitempty"`
+	ROC          []evaluation.Point    `json:"roc"`
+}
+
+// NewEvaluation validates an evaluation, the folds only apply to cross-validation
+// and the test size to a split.
+func NewEvaluation(classifier string, method Method, folds int, testSize float64, seed int64) (Evaluation, error) {
+	if _, err := NewClassifier(classifier); err != nil {
+		return Evaluation{}, err
+	}
+
+	e := Evaluation{Classifier: classifier, Method: method, Seed: seed}
+	switch method {
+	case MethodSplit:
+		if testSize <= 0 || testSize >= 1 {
+			return Evaluation{}, evaluation.ErrInvalidTestSize
+		}
+		e.TestSize = testSize
+	case MethodKFold, MethodStratified:
+		if folds < 2 {
+			return Evaluation{}, evaluation.ErrInvalidFolds
+		}
+		e.Folds = folds
+	default:
+		return Evaluation{}, fmt.Errorf("unknown evaluation method '%s', must be one of split, kfold, stratified", method)
+	}
+	return e, nil
+}
+
+// Apply fits a new classifier for every held out set of passengers, their
+// survival is the label.
+func (e Evaluation) Apply(passengers []*passenger.Passenger) (*Report, error) {
+	labels := make([]int, len(passengers))
+	for i, p := range passengers {
+		labels[i] = p.Survived
+	}
+
+	var tests [][]int
+	var err error
+	switch e.Method {
+	case MethodSplit:
+		var test []int
+		_, test, err = evaluation.Split(len(passengers), e.TestSize, e.Seed)
+		tests = [][]int{test}
+	case MethodKFold:
+		tests, err = evaluation.KFold(len(passengers), e.Folds, e.Seed)
+	case MethodStratified:
+		tests, err = evaluation.StratifiedKFold(labels, e.Folds, e.Seed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &Report{Classifier: e.Classifier, Method: e.Method, Folds: e.Folds, TestSize: e.TestSize, Seed: e.Seed}
+	var pooledLabels []int
+	var pooledScores []float64
+	for _, test := range tests {
+		held := make(map[int]bool, len(test))
+		for _, index := range test {
+			held[index] = true
+		}
+		var fit []*passenger.Passenger
+		for i, p := range passengers {
+			if !held[i] {
+				fit = append(fit, p)
+			}
+		}
+
+		classifier, err := NewClassifier(e.Classifier)
+		if err != nil {
+			return nil, err
+		}
+		if err := classifier.Fit(fit); err != nil {
+			return nil, err
+		}
+
+		foldLabels, foldScores := make([]int, len(test)), make([]float64, len(test))
+		for i, index := range test {
+			foldLabels[i], foldScores[i] = labels[index], classifier.Probability(passengers[index])
+		}
+		if e.Method != MethodSplit {
+			rs.FoldsMetrics = append(rs.FoldsMetrics, evaluation.Evaluate(foldLabels, foldScores,
+				evaluation.DefaultThreshold))
+		}
+		pooledLabels, pooledScores = append(pooledLabels, foldLabels...), append(pooledScores, foldScores...)
+	}
+
+	rs.Metrics = evaluation.Evaluate(pooledLabels, pooledScores, evaluation.DefaultThreshold)
+	rs.ROC = evaluation.ROC(pooledLabels, pooledScores)
+	return rs, nil
+}
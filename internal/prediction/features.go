@@ -0,0 +1,86 @@
+package prediction
+
+import (
+	"math"
+	"titanic-api/internal/passenger"
+)
+
+// feature is a model input, Field is the passenger field it is derived from.
+// Missing ages are filled with the training median and flagged by age-missing.
+type feature struct {
+	name  string
+	field string
+	value func(p *passenger.Passenger, ageMedian float64) float64
+}
+
+var features = []feature{
+	{name: "class-2", field: "class", value: func(p *passenger.Passenger, _ float64) float64 {
+		return indicator(p.Pclass == passenger.ClassSecond)
+	}},
+	{name: "class-3", field: "class", value: func(p *passenger.Passenger, _ float64) float64 {
+		return indicator(p.Pclass == passenger.ClassThird)
+	}},
+	{name: "male", field: "sex", value: func(p *passenger.Passenger, _ float64) float64 {
+		return indicator(p.Sex == passenger.SexMale)
+	}},
+	{name: "age", field: "age", value: func(p *passenger.Passenger, ageMedian float64) float64 {
+		if p.Age == nil {
+			return ageMedian
+		}
+		return *p.Age
+	}},
+	{name: "age-missing", field: "age", value: func(p *passenger.Passenger, _ float64) float64 {
+		return indicator(p.Age == nil)
+	}},
+	{name: "siblings-spouses", field: "siblings-spouses", value: func(p *passenger.Passenger, _ float64) float64 {
+		return float64(p.SibSp)
+	}},
+	{name: "parents-children", field: "parents-children", value: func(p *passenger.Passenger, _ float64) float64 {
+		return float64(p.Parch)
+	}},
+	{name: "log-fare", field: "fare", value: func(p *passenger.Passenger, _ float64) float64 {
+		return math.Log1p(p.Fare)
+	}},
+	{name: "embarked-c", field: "embarked", value: func(p *passenger.Passenger, _ float64) float64 {
+		return indicator(p.Embarked != nil && *p.Embarked == passenger.PortCherbourg)
+	}},
+	{name: "embarked-q", field: "embarked", value: func(p *passenger.Passenger, _ float64) float64 {
+		return indicator(p.Embarked != nil && *p.Embarked == passenger.PortQueenstown)
+	}},
+}
+
+// fields are the passenger fields the features are derived from, in feature order.
+var fields = func() []string {
+	var names []string
+	visited := make(map[string]bool)
+	for _, f := range features {
+		if !visited[f.field] {
+			visited[f.field] = true
+			names = append(names, f.field)
+		}
+	}
+	return names
+}()
+
+func featureNames() []string {
+	names := make([]string, len(features))
+	for i, f := range features {
+		names[i] = f.name
+	}
+	return names
+}
+
+func encode(p *passenger.Passenger, ageMedian float64) []float64 {
+	x := make([]float64, len(features))
+	for i, f := range features {
+		x[i] = f.value(p, ageMedian)
+	}
+	return x
+}
+
+func indicator(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
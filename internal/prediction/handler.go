@@ -0,0 +1,236 @@
+package prediction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"titanic-api/internal/passenger"
+	"titanic-api/pkg/evaluation"
+	"titanic-api/pkg/response"
+)
+
+var (
+	ErrInvalidBody = fmt.Errorf("request body is not a valid passenger")
+)
+
+// Request is the passenger to predict, in the typed passenger representation.
+// Other passenger attributes, e.g. name, are accepted and ignored.
+type Request struct {
+	Pclass   passenger.Class `json:"class"`
+	Sex      passenger.Sex   `json:"sex"`
+	Age      *float64        `json:"age"`
+	SibSp    int             `json:"siblings-spouses"`
+	Parch    int             `json:"parents-children"`
+	Fare     float64         `json:"fare"`
+	Embarked *passenger.Port `json:"embarked"`
+}
+
+type Handler struct {
+	service Service
+}
+
+func (h *Handler) RegisterHandler() *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/", h.Predict)
+	router.Get("/model", h.Model)
+	router.Post("/model", h.Train)
+	router.Get("/evaluate", h.Evaluate)
+	return router
+}
+
+// Package 	godoc
+// @Summary Predict passenger survival
+// @Description Predict the survival probability of a passenger with the logistic regression model, the
+// @Description log odds are split into the base log odds of the average passenger and a contribution per field
+// @Tags    predict
+// @ID 		predict
+// @Accept  json
+// @Produce json
+// @Param passenger body Request true "Passenger"
+// @Success 200 {object} Prediction
+// @Failure 400 {object} response.Error
+// @Failure 503 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /predict [post]
+func (h *Handler) Predict(w http.ResponseWriter, r *http.Request) {
+	var rs Request
+	if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+		response.SendError(r, w, http.StatusBadRequest, fmt.Sprintf("%s: %s", ErrInvalidBody.Error(), err.Error()))
+		return
+	}
+	if err := validateRequest(&rs); err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	prediction, err := h.service.Predict(r.Context(), convertRequest(&rs))
+	switch {
+	case err == nil:
+		response.SendBody(r, w, http.StatusOK, prediction)
+	case err == ErrModelNotTrained:
+		response.SendError(r, w, http.StatusServiceUnavailable, err.Error())
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to predict survival: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Get survival model
+// @Description Get the survival model currently served, its features and training accuracy
+// @Tags    predict
+// @ID 		predict-model
+// @Produce json
+// @Success 200 {object} Model
+// @Failure 503 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /predict/model [get]
+func (h *Handler) Model(w http.ResponseWriter, r *http.Request) {
+	m, err := h.service.Model(r.Context())
+	switch {
+	case err == nil:
+		response.SendBody(r, w, http.StatusOK, m)
+	case err == ErrModelNotTrained:
+		response.SendError(r, w, http.StatusServiceUnavailable, err.Error())
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to get survival model: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Train survival model
+// @Description Train the survival model on the passengers currently in the store, persist it and serve it
+// @Tags    predict
+// @ID 		predict-train
+// @Produce json
+// @Success 200 {object} Model
+// @Failure 406 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /predict/model [post]
+func (h *Handler) Train(w http.ResponseWriter, r *http.Request) {
+	// the format is checked before the model is replaced
+	if response.SendNotAcceptable(r, w) {
+		return
+	}
+
+	m, err := h.service.Train(r.Context())
+	switch {
+	case err == nil:
+		response.SendBody(r, w, http.StatusOK, m)
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to train survival model: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+// Package 	godoc
+// @Summary Evaluate survival classifier
+// @Description Score a survival classifier on held out store passengers with a seeded train/test split or
+// @Description (stratified) k-fold cross-validation. Metrics and the ROC curve are computed over the pooled
+// @Description held out predictions, a passenger is predicted to survive from a probability of 0.5
+// @Tags    predict
+// @ID 		predict-evaluate
+// @Produce json
+// @Param classifier query string false "One of logistic (default), majority, sex, sex-class, women-children-first"
+// @Param method query string false "One of stratified (default), kfold, split"
+// @Param folds query int false "Number of folds for kfold and stratified, defaults to 5"
+// @Param testSize query number false "Held out fraction for split, defaults to 0.25"
+// @Param seed query int false "Shuffling seed, defaults to 1"
+// @Success 200 {object} Report
+// @Failure 400 {object} response.Error
+// @Failure 500 {object} response.Error
+// @Router  /predict/evaluate [get]
+func (h *Handler) Evaluate(w http.ResponseWriter, r *http.Request) {
+	e, err := parseEvaluation(r.URL.Query())
+	if err != nil {
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.service.Evaluate(r.Context(), e)
+	switch {
+	case err == nil:
+		response.SendBody(r, w, http.StatusOK, report)
+	case errors.Is(err, evaluation.ErrInvalidFolds), errors.Is(err, evaluation.ErrInvalidTestSize):
+		response.SendError(r, w, http.StatusBadRequest, err.Error())
+	default:
+		log.Println(fmt.Sprintf("request id: %s failed to evaluate classifier: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+		response.SendFailure(r, w, err)
+	}
+}
+
+func parseEvaluation(values url.Values) (Evaluation, error) {
+	classifier, method := ClassifierLogistic, MethodStratified
+	folds, testSize, seed := DefaultFolds, DefaultTestSize, int64(DefaultSeed)
+	if param := values.Get("classifier"); len(param) > 0 {
+		classifier = param
+	}
+	if param := values.Get("method"); len(param) > 0 {
+		method = Method(param)
+	}
+
+	var err error
+	if param := values.Get("folds"); len(param) > 0 {
+		if folds, err = strconv.Atoi(param); err != nil {
+			return Evaluation{}, evaluation.ErrInvalidFolds
+		}
+	}
+	if param := values.Get("testSize"); len(param) > 0 {
+		if testSize, err = strconv.ParseFloat(param, 64); err != nil {
+			return Evaluation{}, evaluation.ErrInvalidTestSize
+		}
+	}
+	if param := values.Get("seed"); len(param) > 0 {
+		if seed, err = strconv.ParseInt(param, 10, 64); err != nil {
+			return Evaluation{}, fmt.Errorf("seed must be an integer")
+		}
+	}
+	return NewEvaluation(classifier, method, folds, testSize, seed)
+}
+
+func validateRequest(rs *Request) error {
+	switch {
+	case !rs.Pclass.Valid():
+		return fmt.Errorf("class must be 1, 2 or 3")
+	case !rs.Sex.Valid():
+		return fmt.Errorf("sex must be 'male' or 'female'")
+	case rs.Age != nil && *rs.Age < 0:
+		return fmt.Errorf("age must be null or a non-negative number")
+	case rs.SibSp < 0:
+		return fmt.Errorf("siblings-spouses must not be negative")
+	case rs.Parch < 0:
+		return fmt.Errorf("parents-children must not be negative")
+	case rs.Fare < 0:
+		return fmt.Errorf("fare must not be negative")
+	case rs.Embarked != nil && !rs.Embarked.Valid():
+		return fmt.Errorf("embarked must be one of 'S', 'C', 'Q' or null")
+	}
+	return nil
+}
+
+func convertRequest(source *Request) *passenger.Passenger {
+	return &passenger.Passenger{
+		Pclass:   source.Pclass,
+		Sex:      source.Sex,
+		Age:      source.Age,
+		SibSp:    source.SibSp,
+		Parch:    source.Parch,
+		Fare:     source.Fare,
+		Embarked: source.Embarked,
+	}
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
@@ -0,0 +1,320 @@
+package prediction
+
+import (
+	"bytes"
+	ctx "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"titanic-api/internal/passenger"
+	"titanic-api/pkg/evaluation"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/mock"
+)
+
+/*
+Test objects
+*/
+var (
+	mService *MockService
+	handler  *Handler
+)
+
+// pre test setup function
+func setup() {
+	mService = &MockService{}
+	handler = NewHandler(mService)
+}
+
+type MockService struct {
+	mock.Mock
+}
+
+func (ms *MockService) Load(_ ctx.Context) error {
+	args := ms.Called()
+	return args.Error(0)
+}
+
+func (ms *MockService) Train(_ ctx.Context) (*Model, error) {
+	args := ms.Called()
+	var res *Model
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Model)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Model(_ ctx.Context) (*Model, error) {
+	args := ms.Called()
+	var res *Model
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Model)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Predict(_ ctx.Context, p *passenger.Passenger) (*Prediction, error) {
+	args := ms.Called(p)
+	var res *Prediction
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Prediction)
+	}
+	return res, args.Error(1)
+}
+
+func (ms *MockService) Evaluate(_ ctx.Context, e Evaluation) (*Report, error) {
+	args := ms.Called(e)
+	var res *Report
+	if args.Get(0) != nil {
+		res = args.Get(0).(*Report)
+	}
+	return res, args.Error(1)
+}
+
+func TestHandlerPredict_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	prediction := &Prediction{Probability: 0.75, Survived: 1, LogOdds: 1.1, BaseLogOdds: -0.5,
+		Contributions: []*Contribution{{Field: "sex", LogOdds: 1.6}}}
+
+	// given
+	body := `{"id": 1, "name": "Jane Doe", "class": 1, "sex": "female", "age": null, "fare": 80, "embarked": "C"}`
+	r, err := http.NewRequest("POST", "/predict", bytes.NewReader([]byte(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Predict", mock.MatchedBy(func(p *passenger.Passenger) bool {
+		return p.Pclass == passenger.ClassFirst && p.Sex == passenger.SexFemale && p.Age == nil &&
+			p.Fare == 80 && *p.Embarked == passenger.PortCherbourg
+	})).Return(prediction, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Predict(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs *Prediction
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs, ShouldResemble, prediction)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerPredict_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, body := range []string{
+		`{`,
+		`{"class": 4, "sex": "female"}`,
+		`{"class": 1, "sex": "other"}`,
+		`{"class": 1, "sex": "male", "age": -1}`,
+		`{"class": 1, "sex": "male", "age": "22"}`,
+		`{"class": 1, "sex": "male", "fare": -5}`,
+		`{"class": 1, "sex": "male", "siblings-spouses": -1}`,
+		`{"class": 1, "sex": "male", "embarked": "X"}`,
+	} {
+		// given
+		r, err := http.NewRequest("POST", "/predict", bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Predict(w, r)
+
+		// then
+		Convey("Test handler "+body+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerPredict_ModelNotTrained_ResponseServiceUnavailable(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("POST", "/predict", bytes.NewReader([]byte(`{"class": 3, "sex": "male"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Predict", mock.Anything).Return(nil, ErrModelNotTrained)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Predict(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 503", func() {
+			So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerTrain_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	model := &Model{Version: modelVersion, Samples: 891, Accuracy: 0.8, Features: featureNames()}
+
+	// given
+	r, err := http.NewRequest("POST", "/predict/model", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Train").Return(model, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Train(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs *Model
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs.Samples, ShouldEqual, 891)
+			So(rs.Features, ShouldResemble, featureNames())
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerTrain_NotAcceptable_ModelKept(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("POST", "/predict/model", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Accept", "text/html")
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Train(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 406", func() {
+			So(w.Code, ShouldEqual, http.StatusNotAcceptable)
+		})
+	})
+
+	mService.AssertNotCalled(t, "Train")
+}
+
+func TestHandlerEvaluate_ValidRequest_ResponseOk(t *testing.T) {
+	setup()
+
+	accuracy := 0.78
+	report := &Report{Classifier: ClassifierSex, Method: MethodKFold, Folds: 10, Seed: 3,
+		Metrics: &evaluation.Metrics{Samples: 891, Accuracy: &accuracy}}
+
+	// given
+	r, err := http.NewRequest("GET", "/predict/evaluate?classifier=sex&method=kfold&folds=10&seed=3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Evaluate", Evaluation{Classifier: ClassifierSex, Method: MethodKFold, Folds: 10, Seed: 3}).
+		Return(report, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Evaluate(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+		Convey("Response As Expected", func() {
+			var rs *Report
+			err := json.NewDecoder(w.Body).Decode(&rs)
+			So(err, ShouldBeNil)
+			So(rs, ShouldResemble, report)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerEvaluate_DefaultRequest_StratifiedLogistic(t *testing.T) {
+	setup()
+
+	// given
+	r, err := http.NewRequest("GET", "/predict/evaluate", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mService.On("Evaluate", Evaluation{Classifier: ClassifierLogistic, Method: MethodStratified, Folds: DefaultFolds,
+		Seed: DefaultSeed}).Return(&Report{}, nil /* error */)
+
+	w := httptest.NewRecorder()
+
+	// when
+	handler.Evaluate(w, r)
+
+	// then
+	Convey("Test handler\n", t, func() {
+		Convey("Status Code Should Be 200", func() {
+			So(w.Code, ShouldEqual, http.StatusOK)
+		})
+	})
+
+	mService.AssertExpectations(t)
+}
+
+func TestHandlerEvaluate_InvalidRequest_ResponseBadRequest(t *testing.T) {
+	setup()
+
+	for _, rawQuery := range []string{"classifier=unknown", "method=bootstrap", "folds=1", "folds=a",
+		"method=split&testSize=1", "method=split&testSize=a", "seed=1.5"} {
+		// given
+		r, err := http.NewRequest("GET", "/predict/evaluate?"+rawQuery, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+
+		// when
+		handler.Evaluate(w, r)
+
+		// then
+		Convey("Test handler "+rawQuery+"\n", t, func() {
+			Convey("Status Code Should Be 400", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	}
+
+	mService.AssertExpectations(t)
+}
@@ -0,0 +1,159 @@
+package prediction
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+	"titanic-api/internal/passenger"
+	"titanic-api/pkg/logistic"
+	"titanic-api/pkg/stats"
+)
+
+const modelVersion = 1
+
+var (
+	ErrModelNotTrained = fmt.Errorf("survival model is not trained yet")
+	ErrStaleModel      = fmt.Errorf("survival model file does not match the current features")
+)
+
+// Model is the persisted survival model, a logistic regression over the
+// encoded passenger features.
+type Model struct {
+	Version   int       `json:"version"`
+	TrainedAt time.Time `json:"trained-at"`
+	Samples   int       `json:"samples"`
+	// Accuracy is the share of training passengers classified correctly
+	Accuracy   float64         `json:"accuracy"`
+	Features   []string        `json:"features"`
+	AgeMedian  float64         `json:"age-median"`
+	Regression *logistic.Model `json:"regression"`
+}
+
+// Prediction is the survival probability of a passenger. The log odds are
+// the base log odds, those of the mean training passenger, plus the
+// contributions of the passenger fields.
+type Prediction struct {
+	Probability   float64         `json:"probability"`
+	Survived      int             `json:"survived"`
+	LogOdds       float64         `json:"log-odds"`
+	BaseLogOdds   float64         `json:"base-log-odds"`
+	Contributions []*Contribution `json:"contributions"`
+}
+
+// Contribution is the log odds a passenger field adds to the base log odds.
+type Contribution struct {
+	Field   string  `json:"field"`
+	LogOdds float64 `json:"log-odds"`
+}
+
+// train fits a model to passengers, their survival is the label.
+func train(passengers []*passenger.Passenger) (*Model, error) {
+	var ages []float64
+	for _, p := range passengers {
+		if p.Age != nil {
+			ages = append(ages, *p.Age)
+		}
+	}
+	sort.Float64s(ages)
+	m := &Model{Version: modelVersion, Samples: len(passengers), Features: featureNames()}
+	if len(ages) > 0 {
+		m.AgeMedian = stats.Quantile(ages, 0.5)
+	}
+
+	x, y := make([][]float64, len(passengers)), make([]float64, len(passengers))
+	for i, p := range passengers {
+		x[i], y[i] = encode(p, m.AgeMedian), float64(p.Survived)
+	}
+	regression, err := logistic.Train(x, y, logistic.DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	m.Regression = regression
+
+	var correct int
+	for i, p := range passengers {
+		if m.predict(p).Survived == int(y[i]) {
+			correct++
+		}
+	}
+	m.Accuracy = float64(correct) / float64(len(passengers))
+	m.TrainedAt = time.Now().UTC()
+	return m, nil
+}
+
+func (m *Model) predict(p *passenger.Passenger) *Prediction {
+	x := encode(p, m.AgeMedian)
+	probability := m.Regression.Probability(x)
+	rs := &Prediction{
+		Probability: probability,
+		LogOdds:     m.Regression.LogOdds(x),
+		BaseLogOdds: m.Regression.Bias,
+	}
+	if probability >= 0.5 {
+		rs.Survived = 1
+	}
+
+	byField := make(map[string]float64)
+	for i, c := range m.Regression.Contributions(x) {
+		byField[features[i].field] += c
+	}
+	for _, name := range fields {
+		rs.Contributions = append(rs.Contributions, &Contribution{Field: name, LogOdds: byField[name]})
+	}
+	return rs
+}
+
+// loadModel reads a model file, ErrStaleModel is returned when it was
+// trained on other features.
+func loadModel(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid survival model file %s: %w", path, err)
+	}
+
+	names := featureNames()
+	if m.Version != modelVersion || m.Regression == nil || len(m.Features) != len(names) ||
+		len(m.Regression.Weights) != len(names) {
+		return nil, ErrStaleModel
+	}
+	for i, name := range names {
+		if m.Features[i] != name {
+			return nil, ErrStaleModel
+		}
+	}
+	return &m, nil
+}
+
+// save writes the model file atomically, through a temporary file renamed
+// over the previous model.
+func (m *Model) save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,133 @@
+package prediction
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"titanic-api/internal/passenger"
+	"titanic-api/pkg/evaluation"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func createPassengers() []*passenger.Passenger {
+	var passengers []*passenger.Passenger
+	for i := 0; i < 40; i++ {
+		sex, survived := passenger.SexMale, 0
+		if i%2 == 0 {
+			sex, survived = passenger.SexFemale, 1
+		}
+		// a few passengers survive against their sex
+		if i%10 == 3 || i%10 == 4 {
+			survived = 1 - survived
+		}
+		embarked := passenger.PortSouthampton
+		p := &passenger.Passenger{PassengerId: i + 1, Survived: survived, Pclass: passenger.Class(i%3 + 1),
+			Sex: sex, SibSp: i % 2, Fare: float64(10 + i), Embarked: &embarked}
+		if i%5 != 0 {
+			age := float64(20 + i)
+			p.Age = &age
+		}
+		passengers = append(passengers, p)
+	}
+	return passengers
+}
+
+func TestTrain_Passengers_Model(t *testing.T) {
+	passengers := createPassengers()
+
+	// when
+	m, err := train(passengers)
+
+	// then
+	Convey("Test train\n", t, func() {
+		So(err, ShouldBeNil)
+		So(m.Samples, ShouldEqual, len(passengers))
+		So(m.Accuracy, ShouldBeGreaterThanOrEqualTo, 0.8)
+		So(m.AgeMedian, ShouldEqual, 40)
+
+		Convey("Prediction As Expected", func() {
+			female, male := *passengers[0], *passengers[0]
+			male.Sex = passenger.SexMale
+			rsFemale, rsMale := m.predict(&female), m.predict(&male)
+			So(rsFemale.Probability, ShouldBeGreaterThan, rsMale.Probability)
+			So(rsFemale.Survived, ShouldEqual, 1)
+
+			sum := rsFemale.BaseLogOdds
+			var names []string
+			for _, c := range rsFemale.Contributions {
+				sum += c.LogOdds
+				names = append(names, c.Field)
+			}
+			So(sum, ShouldAlmostEqual, rsFemale.LogOdds, 1e-9)
+			So(names, ShouldResemble, []string{"class", "sex", "age", "siblings-spouses", "parents-children",
+				"fare", "embarked"})
+		})
+	})
+}
+
+func TestModel_SaveLoad_SameModel(t *testing.T) {
+	m, err := train(createPassengers())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "model", "survival.json")
+
+	// when
+	saveErr := m.save(path)
+	loaded, loadErr := loadModel(path)
+
+	// then
+	Convey("Test save and load\n", t, func() {
+		So(saveErr, ShouldBeNil)
+		So(loadErr, ShouldBeNil)
+		So(loaded.Regression, ShouldResemble, m.Regression)
+		So(loaded.TrainedAt.Equal(m.TrainedAt), ShouldBeTrue)
+
+		Convey("Stale Model Rejected", func() {
+			m.Features = m.Features[1:]
+			So(m.save(path), ShouldBeNil)
+			_, err := loadModel(path)
+			So(err, ShouldEqual, ErrStaleModel)
+		})
+		Convey("Missing Model", func() {
+			_, err := loadModel(filepath.Join(t.TempDir(), "missing.json"))
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+}
+
+func TestEvaluation_Classifiers_Report(t *testing.T) {
+	passengers := createPassengers()
+
+	Convey("Test evaluation\n", t, func() {
+		for _, name := range []string{ClassifierLogistic, ClassifierMajority, ClassifierSex, ClassifierWomenChildren,
+			ClassifierSexClass} {
+			e, err := NewEvaluation(name, MethodStratified, 4, 0, 1)
+			So(err, ShouldBeNil)
+			rs, err := e.Apply(passengers)
+			So(err, ShouldBeNil)
+			So(rs.Metrics.Samples, ShouldEqual, len(passengers))
+			So(len(rs.FoldsMetrics), ShouldEqual, 4)
+		}
+
+		Convey("Sex Baseline Learns The Survival Rates", func() {
+			e, _ := NewEvaluation(ClassifierSex, MethodKFold, 5, 0, 1)
+			rs, err := e.Apply(passengers)
+			So(err, ShouldBeNil)
+			So(*rs.Metrics.Accuracy, ShouldEqual, 0.8)
+		})
+		Convey("Split Holds Out Test Size", func() {
+			e, _ := NewEvaluation(ClassifierMajority, MethodSplit, 0, 0.25, 1)
+			rs, err := e.Apply(passengers)
+			So(err, ShouldBeNil)
+			So(rs.Metrics.Samples, ShouldEqual, 10)
+			So(rs.FoldsMetrics, ShouldBeNil)
+		})
+		Convey("Too Many Folds", func() {
+			e, _ := NewEvaluation(ClassifierMajority, MethodKFold, 100, 0, 1)
+			_, err := e.Apply(passengers)
+			So(err, ShouldEqual, evaluation.ErrInvalidFolds)
+		})
+	})
+}
@@ -0,0 +1,100 @@
+package prediction
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"sync"
+	"titanic-api/internal/passenger"
+)
+
+type Service interface {
+	// Load reads the persisted model, a missing or stale model is trained from the store
+	Load(ctx context.Context) error
+	// Train trains a model from the store and persists it
+	Train(ctx context.Context) (*Model, error)
+	Model(ctx context.Context) (*Model, error)
+	Predict(ctx context.Context, p *passenger.Passenger) (*Prediction, error)
+	// Evaluate scores a classifier on the store passengers
+	Evaluate(ctx context.Context, e Evaluation) (*Report, error)
+}
+
+type service struct {
+	store passenger.Store
+	path  string
+
+	mu    sync.RWMutex
+	model *Model
+}
+
+func (s *service) Load(ctx context.Context) error {
+	m, err := loadModel(s.path)
+	switch {
+	case err == nil:
+		s.setModel(m)
+		return nil
+	case errors.Is(err, fs.ErrNotExist), err == ErrStaleModel:
+		log.Printf("survival model %s not usable (%v), training a new one", s.path, err)
+	default:
+		return err
+	}
+
+	_, err = s.Train(ctx)
+	return err
+}
+
+func (s *service) Train(ctx context.Context) (*Model, error) {
+	passengers, err := s.store.GetPassengers(ctx, passenger.Query{})
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := train(passengers)
+	if err != nil {
+		return nil, err
+	}
+	// a model which cannot be persisted is still served until the next restart
+	if err := m.save(s.path); err != nil {
+		log.Printf("failed to save survival model %s: %v", s.path, err)
+	}
+
+	s.setModel(m)
+	return m, nil
+}
+
+func (s *service) Model(_ context.Context) (*Model, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.model == nil {
+		return nil, ErrModelNotTrained
+	}
+	return s.model, nil
+}
+
+func (s *service) Predict(ctx context.Context, p *passenger.Passenger) (*Prediction, error) {
+	m, err := s.Model(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.predict(p), nil
+}
+
+func (s *service) Evaluate(ctx context.Context, e Evaluation) (*Report, error) {
+	passengers, err := s.store.GetPassengers(ctx, passenger.Query{})
+	if err != nil {
+		return nil, err
+	}
+	return e.Apply(passengers)
+}
+
+func (s *service) setModel(m *Model) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.model = m
+}
+
+// NewService returns a service without a model, call Load to read or train it.
+func NewService(store passenger.Store, path string) Service {
+	return &service{store: store, path: path}
+}
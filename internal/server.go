@@ -17,6 +17,7 @@ import (
 	"titanic-api/internal/config"
 	"titanic-api/internal/healthcheck"
 	"titanic-api/internal/passenger"
+	"titanic-api/internal/prediction"
 	"titanic-api/internal/web"
 )
 
@@ -68,10 +69,18 @@ func (s *server) Start() {
 }
 
 func (s *server) router() (*chi.Mux, error) {
-	service, err := s.initService()
+	store, err := newStore(s.conf.GetStoreType(), s.conf.GetStorePath())
 	if err != nil {
 		return nil, err
 	}
+	service := passenger.NewService(store)
+
+	predictionService := prediction.NewService(store, s.conf.GetModelPath())
+	// the passenger API is served without a model, predictions fail until the
+	// model is retrained e.g. once passengers are imported
+	if err := predictionService.Load(context.Background()); err != nil {
+		log.Printf("failed to load survival model, predictions are unavailable: %v", err)
+	}
 
 	router := chi.NewRouter()
 
@@ -83,7 +92,9 @@ func (s *server) router() (*chi.Mux, error) {
 		middleware.Timeout(time.Second*60),
 		cors.Handler(cors.Options{
 			AllowedOrigins: []string{"*"},
-			AllowedMethods: []string{"GET"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Content-Type"},
+			ExposedHeaders: []string{"Link", "X-Total-Count"},
 			MaxAge:         300,
 		}),
 	)
@@ -105,28 +116,41 @@ func (s *server) router() (*chi.Mux, error) {
 	// setup api routes
 	router.Route("/api/v1", func(r chi.Router) {
 		// setup passenger routes
-		r.Mount("/passenger", passenger.NewHandler(service).RegisterHandler())
+		passengerHandler := passenger.NewHandler(service)
+		r.Mount("/passenger", passengerHandler.RegisterHandler())
+		// setup ticket routes
+		r.Mount("/ticket", passengerHandler.RegisterTicketHandler())
+		// setup deck routes
+		r.Mount("/deck", passengerHandler.RegisterDeckHandler())
+		// setup port routes
+		r.Mount("/port", passengerHandler.RegisterPortHandler())
+		// setup prediction routes
+		r.Mount("/predict", prediction.NewHandler(predictionService).RegisterHandler())
 		// setup health check routes
 		r.Mount("/health", healthcheck.NewHandler().RegisterHandler())
 	})
 
+	// setup typed api routes
+	router.Route("/api/v2", func(r chi.Router) {
+		// setup passenger routes
+		passengerHandler := passenger.NewHandlerV2(service)
+		r.Mount("/passenger", passengerHandler.RegisterHandler())
+		// setup ticket routes
+		r.Mount("/ticket", passengerHandler.RegisterTicketHandler())
+	})
+
 	return router, nil
 }
 
-func (s *server) initService() (passenger.Service, error) {
-	var store passenger.Store
-	storeType := s.conf.GetStoreType()
+func newStore(storeType, path string) (passenger.Store, error) {
 	switch storeType {
 	case passenger.StoreTypeCSV:
-		store = passenger.NewStoreCSV(s.conf.GetStorePath())
+		return passenger.NewStoreCSV(path), nil
 	case passenger.StoreTypeSQLite:
-		store = passenger.NewStoreSQLite(passenger.NewConnector(s.conf.GetStorePath()))
+		return passenger.NewStoreSQLite(passenger.NewConnector(path)), nil
 	default:
 		return nil, fmt.Errorf("store type provided not supported")
 	}
-
-	return passenger.NewService(store), nil
-
 }
 
 func NewServer() Server {
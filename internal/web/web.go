@@ -45,7 +45,7 @@ func (h *Handler) Passenger(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := h.service.Get(pid)
+	p, err := h.service.Get(r.Context(), pid)
 	if err == nil {
 		data.Passengers = []*passenger.Passenger{p}
 	}
@@ -72,9 +72,9 @@ func (h *Handler) Passengers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var data Data
-	p, err := h.service.GetAll()
+	page, err := h.service.GetAll(r.Context(), passenger.Query{})
 	if err == nil {
-		data.Passengers = p
+		data.Passengers = page.Passengers
 	}
 
 	tmpl.Execute(w, data)
@@ -87,7 +87,7 @@ func (h *Handler) Histogram(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var data Data
-	pc, err := h.service.FarePercentileHistogram()
+	pc, err := h.service.FarePercentileHistogram(r.Context(), histogram.DefaultPercentiles)
 	if err == nil {
 		data.Histogram = pc.Entries
 	}
@@ -0,0 +1,136 @@
+package evaluation
+
+import (
+	"sort"
+)
+
+// DefaultThreshold is the score from which a sample is classified positive.
+const DefaultThreshold = 0.5
+
+// Confusion counts the classifications of binary labels.
+type Confusion struct {
+	TruePositive  int `json:"true-positive"`
+	FalsePositive int `json:"false-positive"`
+	TrueNegative  int `json:"true-negative"`
+	FalseNegative int `json:"false-negative"`
+}
+
+// Metrics score a binary classifier. Ratios which are undefined for the
+// sample, e.g. the precision without positive classifications, are nil.
+type Metrics struct {
+	Samples   int       `json:"samples"`
+	Confusion Confusion `json:"confusion"`
+	Accuracy  *float64  `json:"accuracy"`
+	Precision *float64  `json:"precision"`
+	Recall    *float64  `json:"recall"`
+	F1        *float64  `json:"f1"`
+	AUC       *float64  `json:"auc"`
+}
+
+// Point is a point of the ROC curve, the rates of the samples classified
+// positive from Threshold. The first point has a nil threshold and classifies
+// every sample negative.
+type Point struct {
+	Threshold         *float64 `json:"threshold"`
+	FalsePositiveRate float64  `json:"false-positive-rate"`
+	TruePositiveRate  float64  `json:"true-positive-rate"`
+}
+
+// NewConfusion classifies the scores from threshold against labels, a label
+// is positive when it is 1.
+func NewConfusion(labels []int, scores []float64, threshold float64) Confusion {
+	var c Confusion
+	for i, label := range labels {
+		switch positive := scores[i] >= threshold; {
+		case positive && label == 1:
+			c.TruePositive++
+		case positive:
+			c.FalsePositive++
+		case label == 1:
+			c.FalseNegative++
+		default:
+			c.TrueNegative++
+		}
+	}
+	return c
+}
+
+// Evaluate scores the classification of scores from threshold against labels.
+func Evaluate(labels []int, scores []float64, threshold float64) *Metrics {
+	c := NewConfusion(labels, scores, threshold)
+	m := &Metrics{Samples: len(labels), Confusion: c}
+	m.Accuracy = ratio(c.TruePositive+c.TrueNegative, len(labels))
+	m.Precision = ratio(c.TruePositive, c.TruePositive+c.FalsePositive)
+	m.Recall = ratio(c.TruePositive, c.TruePositive+c.FalseNegative)
+	if m.Precision != nil && m.Recall != nil && *m.Precision+*m.Recall > 0 {
+		f1 := 2 * *m.Precision * *m.Recall / (*m.Precision + *m.Recall)
+		m.F1 = &f1
+	}
+	if points := ROC(labels, scores); points != nil {
+		auc := AUC(points)
+		m.AUC = &auc
+	}
+	return m
+}
+
+// ROC computes the ROC curve of scores against labels, a point per distinct
+// score in decreasing order. The curve is undefined (nil) unless both labels
+// are present.
+func ROC(labels []int, scores []float64) []Point {
+	var positives, negatives int
+	for _, label := range labels {
+		if label == 1 {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	if positives == 0 || negatives == 0 {
+		return nil
+	}
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+	points := []Point{{}}
+	var tp, fp int
+	for i, index := range order {
+		if labels[index] == 1 {
+			tp++
+		} else {
+			fp++
+		}
+		// tied scores are a single threshold
+		if i+1 < len(order) && scores[order[i+1]] == scores[index] {
+			continue
+		}
+		threshold := scores[index]
+		points = append(points, Point{
+			Threshold:         &threshold,
+			FalsePositiveRate: float64(fp) / float64(negatives),
+			TruePositiveRate:  float64(tp) / float64(positives),
+		})
+	}
+	return points
+}
+
+// AUC is the area under a ROC curve by the trapezoidal rule.
+func AUC(points []Point) float64 {
+	var area float64
+	for i := 1; i < len(points); i++ {
+		width := points[i].FalsePositiveRate - points[i-1].FalsePositiveRate
+		area += width * (points[i].TruePositiveRate + points[i-1].TruePositiveRate) / 2
+	}
+	return area
+}
+
+func ratio(a, b int) *float64 {
+	if b == 0 {
+		return nil
+	}
+	v := float64(a) / float64(b)
+	return &v
+}
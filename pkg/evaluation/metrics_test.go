@@ -0,0 +1,60 @@
+package evaluation
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEvaluate_Scores_Metrics(t *testing.T) {
+	labels := []int{1, 0, 1, 1, 0}
+	scores := []float64{0.9, 0.8, 0.7, 0.4, 0.2}
+
+	// when
+	m := Evaluate(labels, scores, DefaultThreshold)
+
+	// then
+	Convey("Test evaluate\n", t, func() {
+		So(m.Samples, ShouldEqual, 5)
+		So(m.Confusion, ShouldResemble, Confusion{TruePositive: 2, FalsePositive: 1, TrueNegative: 1, FalseNegative: 1})
+		So(*m.Accuracy, ShouldAlmostEqual, 0.6, 1e-12)
+		So(*m.Precision, ShouldAlmostEqual, 2.0/3, 1e-12)
+		So(*m.Recall, ShouldAlmostEqual, 2.0/3, 1e-12)
+		So(*m.F1, ShouldAlmostEqual, 2.0/3, 1e-12)
+		// 4 of the 6 positive and negative pairs are ranked correctly
+		So(*m.AUC, ShouldAlmostEqual, 4.0/6, 1e-12)
+	})
+}
+
+func TestROC_TiedScores_SinglePoint(t *testing.T) {
+	// when
+	points := ROC([]int{1, 0, 1, 0}, []float64{0.8, 0.8, 0.5, 0.1})
+
+	// then
+	Convey("Test ROC\n", t, func() {
+		So(len(points), ShouldEqual, 4)
+		So(points[0].Threshold, ShouldBeNil)
+		So(*points[1].Threshold, ShouldEqual, 0.8)
+		So(points[1].FalsePositiveRate, ShouldEqual, 0.5)
+		So(points[1].TruePositiveRate, ShouldEqual, 0.5)
+		So(points[3].FalsePositiveRate, ShouldEqual, 1)
+		So(points[3].TruePositiveRate, ShouldEqual, 1)
+		// a tied pair counts half
+		So(AUC(points), ShouldAlmostEqual, 0.625, 1e-12)
+	})
+}
+
+func TestEvaluate_SingleLabel_UndefinedMetrics(t *testing.T) {
+	// when
+	m := Evaluate([]int{0, 0, 0}, []float64{0.1, 0.2, 0.3}, DefaultThreshold)
+
+	// then
+	Convey("Test evaluate\n", t, func() {
+		So(*m.Accuracy, ShouldEqual, 1)
+		So(m.Precision, ShouldBeNil)
+		So(m.Recall, ShouldBeNil)
+		So(m.F1, ShouldBeNil)
+		So(m.AUC, ShouldBeNil)
+		So(ROC([]int{0, 0, 0}, []float64{0.1, 0.2, 0.3}), ShouldBeNil)
+	})
+}
@@ -0,0 +1,68 @@
+package evaluation
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+var (
+	ErrInvalidFolds    = fmt.Errorf("folds must be at least 2 and at most the number of samples")
+	ErrInvalidTestSize = fmt.Errorf("test size must be between 0 and 1 and leave samples on both sides")
+)
+
+// Split shuffles the indices of n samples with seed and splits them into a
+// train and a test set, the test set holds the testSize fraction rounded.
+func Split(n int, testSize float64, seed int64) (train, test []int, err error) {
+	size := int(testSize*float64(n) + 0.5)
+	if testSize <= 0 || testSize >= 1 || size < 1 || size >= n {
+		return nil, nil, ErrInvalidTestSize
+	}
+
+	order := rand.New(rand.NewSource(seed)).Perm(n)
+	return order[size:], order[:size], nil
+}
+
+// KFold shuffles the indices of n samples with seed and deals them into k
+// folds whose sizes differ by one at most.
+func KFold(n, k int, seed int64) ([][]int, error) {
+	if k < 2 || k > n {
+		return nil, ErrInvalidFolds
+	}
+
+	folds := make([][]int, k)
+	for i, index := range rand.New(rand.NewSource(seed)).Perm(n) {
+		folds[i%k] = append(folds[i%k], index)
+	}
+	return folds, nil
+}
+
+// StratifiedKFold is KFold keeping the label proportions of every fold close
+// to those of the whole sample.
+func StratifiedKFold(labels []int, k int, seed int64) ([][]int, error) {
+	if k < 2 || k > len(labels) {
+		return nil, ErrInvalidFolds
+	}
+
+	// deal the shuffled samples of each label in turn, continuing from the
+	// fold the previous label stopped at so fold sizes stay balanced
+	order := rand.New(rand.NewSource(seed)).Perm(len(labels))
+	var keys []int
+	byLabel := make(map[int][]int)
+	for _, index := range order {
+		label := labels[index]
+		if _, found := byLabel[label]; !found {
+			keys = append(keys, label)
+		}
+		byLabel[label] = append(byLabel[label], index)
+	}
+
+	folds := make([][]int, k)
+	var next int
+	for _, label := range keys {
+		for _, index := range byLabel[label] {
+			folds[next%k] = append(folds[next%k], index)
+			next++
+		}
+	}
+	return folds, nil
+}
@@ -0,0 +1,78 @@
+package evaluation
+
+import (
+	"sort"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSplit_Seed_Partition(t *testing.T) {
+	Convey("Test split\n", t, func() {
+		train, test, err := Split(10, 0.25, 7)
+		So(err, ShouldBeNil)
+		So(len(test), ShouldEqual, 3)
+		So(len(train), ShouldEqual, 7)
+		So(sorted(append(append([]int{}, train...), test...)), ShouldResemble, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+		Convey("Same Seed Same Split", func() {
+			again, _, _ := Split(10, 0.25, 7)
+			So(again, ShouldResemble, train)
+		})
+		Convey("Invalid Test Size", func() {
+			for _, size := range []float64{0, 1, -0.5, 0.01, 0.99} {
+				_, _, err := Split(10, size, 7)
+				So(err, ShouldEqual, ErrInvalidTestSize)
+			}
+		})
+	})
+}
+
+func TestKFold_Seed_Folds(t *testing.T) {
+	Convey("Test k-fold\n", t, func() {
+		folds, err := KFold(11, 3, 1)
+		So(err, ShouldBeNil)
+		var all []int
+		for _, fold := range folds {
+			So(len(fold), ShouldBeBetweenOrEqual, 3, 4)
+			all = append(all, fold...)
+		}
+		So(sorted(all), ShouldResemble, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+
+		_, err = KFold(3, 4, 1)
+		So(err, ShouldEqual, ErrInvalidFolds)
+		_, err = KFold(3, 1, 1)
+		So(err, ShouldEqual, ErrInvalidFolds)
+	})
+}
+
+func TestStratifiedKFold_Labels_BalancedFolds(t *testing.T) {
+	labels := make([]int, 30)
+	for i := 0; i < 10; i++ {
+		labels[i] = 1
+	}
+
+	// when
+	folds, err := StratifiedKFold(labels, 5, 3)
+
+	// then
+	Convey("Test stratified k-fold\n", t, func() {
+		So(err, ShouldBeNil)
+		var all []int
+		for _, fold := range folds {
+			var positives int
+			for _, index := range fold {
+				positives += labels[index]
+			}
+			So(len(fold), ShouldEqual, 6)
+			So(positives, ShouldEqual, 2)
+			all = append(all, fold...)
+		}
+		So(len(sorted(all)), ShouldEqual, 30)
+	})
+}
+
+func sorted(values []int) []int {
+	sort.Ints(values)
+	return values
+}
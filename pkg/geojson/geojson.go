@@ -0,0 +1,46 @@
+package geojson
+
+// ContentType is the GeoJSON media type (RFC 7946).
+const ContentType = "application/geo+json"
+
+const (
+	TypeFeatureCollection = "FeatureCollection"
+	TypeFeature           = "Feature"
+	TypePoint             = "Point"
+)
+
+type FeatureCollection struct {
+	Type     string     `json:"type"`
+	Features []*Feature `json:"features"`
+}
+
+// Feature is a geometry with its properties, any value encoding to a JSON
+// object or null.
+type Feature struct {
+	Type       string      `json:"type"`
+	ID         interface{} `json:"id,omitempty"`
+	Geometry   *Geometry   `json:"geometry"`
+	Properties interface{} `json:"properties"`
+}
+
+// Geometry is a GeoJSON geometry, only points are supported.
+type Geometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+// NewPoint returns a point, GeoJSON positions are longitude first.
+func NewPoint(longitude, latitude float64) *Geometry {
+	return &Geometry{Type: TypePoint, Coordinates: []float64{longitude, latitude}}
+}
+
+func NewFeature(id interface{}, geometry *Geometry, properties interface{}) *Feature {
+	return &Feature{Type: TypeFeature, ID: id, Geometry: geometry, Properties: properties}
+}
+
+func NewFeatureCollection(features []*Feature) *FeatureCollection {
+	if features == nil {
+		features = []*Feature{}
+	}
+	return &FeatureCollection{Type: TypeFeatureCollection, Features: features}
+}
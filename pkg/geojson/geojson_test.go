@@ -0,0 +1,27 @@
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNewFeatureCollection_Points_Encoded(t *testing.T) {
+	Convey("Test feature collection\n", t, func() {
+		Convey("Points Are Longitude First", func() {
+			fc := NewFeatureCollection([]*Feature{
+				NewFeature("S", NewPoint(-1.4044, 50.8998), map[string]string{"name": "Southampton"}),
+			})
+			b, err := json.Marshal(fc)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, `{"type":"FeatureCollection","features":[{"type":"Feature","id":"S",`+
+				`"geometry":{"type":"Point","coordinates":[-1.4044,50.8998]},"properties":{"name":"Southampton"}}]}`)
+		})
+		Convey("Empty Collection Has Features", func() {
+			b, err := json.Marshal(NewFeatureCollection(nil))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, `{"type":"FeatureCollection","features":[]}`)
+		})
+	})
+}
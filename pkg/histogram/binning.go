@@ -0,0 +1,226 @@
+package histogram
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"titanic-api/pkg/stats"
+)
+
+type Strategy string
+
+const (
+	StrategyPercentile       Strategy = "percentile"
+	StrategyEqualWidth       Strategy = "equal-width"
+	StrategySturges          Strategy = "sturges"
+	StrategyScott            Strategy = "scott"
+	StrategyFreedmanDiaconis Strategy = "freedman-diaconis"
+	StrategyLog              Strategy = "log"
+)
+
+const (
+	// DefaultBins is the equal-width bin count used when neither bins nor width are set
+	DefaultBins = 10
+	MaxBins     = 1000
+)
+
+var (
+	ErrInvalidStrategy = fmt.Errorf("histogram strategy must be one of percentile, equal-width, sturges, scott, freedman-diaconis, log")
+	ErrInvalidBins     = fmt.Errorf("bins must be an integer between 1 and %d", MaxBins)
+	ErrInvalidWidth    = fmt.Errorf("width must be a positive number")
+	ErrTooManyBins     = fmt.Errorf("histogram would have more than %d bins", MaxBins)
+	ErrNegativeData    = fmt.Errorf("log histogram data must not be negative")
+)
+
+// Options select how a histogram is binned. Bins applies to the equal-width
+// and log strategies, Width to equal-width and Percentiles to percentile.
+type Options struct {
+	Strategy    Strategy
+	Bins        int
+	Width       float64
+	Percentiles []float64
+}
+
+// Validate checks the options independently of the data.
+func (o Options) Validate() error {
+	switch o.Strategy {
+	case StrategyPercentile, StrategyEqualWidth, StrategySturges, StrategyScott, StrategyFreedmanDiaconis, StrategyLog:
+	default:
+		return ErrInvalidStrategy
+	}
+
+	switch {
+	case o.Bins < 0 || o.Bins > MaxBins:
+		return ErrInvalidBins
+	case o.Width < 0 || math.IsNaN(o.Width) || math.IsInf(o.Width, 0):
+		return ErrInvalidWidth
+	case o.Bins > 0 && o.Strategy != StrategyEqualWidth && o.Strategy != StrategyLog:
+		return fmt.Errorf("%s histogram strategy does not accept bins", o.Strategy)
+	case o.Width > 0 && o.Strategy != StrategyEqualWidth:
+		return fmt.Errorf("%s histogram strategy does not accept width", o.Strategy)
+	case o.Bins > 0 && o.Width > 0:
+		return fmt.Errorf("either bins or width can be provided, not both")
+	case len(o.Percentiles) > 0 && o.Strategy != StrategyPercentile:
+		return fmt.Errorf("%s histogram strategy does not accept percentiles", o.Strategy)
+	case o.Strategy == StrategyPercentile && len(o.Percentiles) > 0:
+		return ValidatePercentiles(o.Percentiles)
+	}
+	return nil
+}
+
+// New bins data according to opts. Percentile bins are described by Percentile,
+// the other strategies produce bins holding the values in [Lower, Upper), the
+// last bin also holds values equal to Upper. Bin is then the bin index.
+func New(data []float64, opts Options) (*Histogram, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Strategy == StrategyPercentile {
+		percentiles := opts.Percentiles
+		if len(percentiles) == 0 {
+			percentiles = DefaultPercentiles
+		}
+		return Percentile(data, percentiles)
+	}
+
+	edges, err := Edges(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return FromEdges(data, edges), nil
+}
+
+// Edges computes the bin edges of a width based strategy, n bins have n+1
+// increasing edges spanning the data.
+func Edges(data []float64, opts Options) ([]float64, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Strategy == StrategyPercentile {
+		return nil, ErrInvalidStrategy
+	}
+
+	sorted, err := sortData(data)
+	if err != nil {
+		return nil, err
+	}
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	n := float64(len(sorted))
+
+	var bins int
+	var width float64
+	switch opts.Strategy {
+	case StrategyEqualWidth:
+		bins, width = opts.Bins, opts.Width
+		if bins == 0 && width == 0 {
+			bins = DefaultBins
+		}
+	case StrategySturges:
+		bins = sturges(len(sorted))
+	case StrategyScott:
+		width = 3.49 * stdDev(sorted) * math.Pow(n, -1.0/3)
+	case StrategyFreedmanDiaconis:
+		width = 2 * (stats.Quantile(sorted, 0.75) - stats.Quantile(sorted, 0.25)) * math.Pow(n, -1.0/3)
+	case StrategyLog:
+		if lo < 0 {
+			return nil, ErrNegativeData
+		}
+		bins = opts.Bins
+		if bins == 0 {
+			bins = sturges(len(sorted))
+		}
+		return logEdges(lo, hi, bins), nil
+	}
+
+	// a single value, or a spread too small for the rule, fits one bin
+	if hi == lo || bins == 0 && width == 0 {
+		return []float64{lo, hi}, nil
+	}
+	if bins == 0 {
+		count := math.Ceil((hi - lo) / width)
+		if count > MaxBins {
+			return nil, ErrTooManyBins
+		}
+		bins = int(math.Max(count, 1))
+		edges := make([]float64, bins+1)
+		for i := range edges {
+			edges[i] = lo + float64(i)*width
+		}
+		// avoid rounding the maximum out of the last bin
+		edges[bins] = math.Max(edges[bins], hi)
+		return edges, nil
+	}
+
+	edges := make([]float64, bins+1)
+	for i := range edges {
+		edges[i] = lo + (hi-lo)*float64(i)/float64(bins)
+	}
+	// avoid rounding the maximum out of the last bin
+	edges[bins] = hi
+	return edges, nil
+}
+
+// FromEdges counts data into the bins delimited by edges, values outside the
+// edges are not counted.
+func FromEdges(data []float64, edges []float64) *Histogram {
+	var histogram Histogram
+	for i := 0; i+1 < len(edges); i++ {
+		histogram.Entries = append(histogram.Entries, &Entry{Bin: float64(i), Lower: edges[i], Upper: edges[i+1]})
+	}
+	histogram.Entries = histogram.Count(data)
+	return &histogram
+}
+
+// logEdges spaces edges evenly on a log(1+x) scale so zero values fit the first bin.
+func logEdges(lo, hi float64, bins int) []float64 {
+	if hi == lo {
+		return []float64{lo, hi}
+	}
+
+	from, to := math.Log1p(lo), math.Log1p(hi)
+	edges := make([]float64, bins+1)
+	for i := range edges {
+		edges[i] = math.Expm1(from + (to-from)*float64(i)/float64(bins))
+	}
+	edges[0], edges[bins] = lo, hi
+	return edges
+}
+
+// sortData returns a sorted copy of data, rejecting empty and NaN input.
+func sortData(data []float64) ([]float64, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyData
+	}
+
+	sorted := make([]float64, len(data))
+	copy(sorted, data)
+	for _, v := range sorted {
+		if math.IsNaN(v) {
+			return nil, ErrInvalidData
+		}
+	}
+	sort.Float64s(sorted)
+	return sorted, nil
+}
+
+func sturges(n int) int {
+	return int(math.Ceil(math.Log2(float64(n)))) + 1
+}
+
+func stdDev(data []float64) float64 {
+	if len(data) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range data {
+		mean += v
+	}
+	mean /= float64(len(data))
+
+	var sum float64
+	for _, v := range data {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(data)-1))
+}
@@ -0,0 +1,99 @@
+package histogram
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNew_EqualWidth_Bins(t *testing.T) {
+	data := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 10}
+
+	Convey("Test equal width\n", t, func() {
+		Convey("Bin Count", func() {
+			histogram, err := New(data, Options{Strategy: StrategyEqualWidth, Bins: 2})
+			So(err, ShouldBeNil)
+			So(len(histogram.Entries), ShouldEqual, 2)
+			So(*histogram.Entries[0], ShouldResemble, Entry{Bin: 0, Lower: 0, Upper: 5, Count: 5})
+			So(*histogram.Entries[1], ShouldResemble, Entry{Bin: 1, Lower: 5, Upper: 10, Count: 5})
+		})
+		Convey("Bin Width", func() {
+			histogram, err := New(data, Options{Strategy: StrategyEqualWidth, Width: 4})
+			So(err, ShouldBeNil)
+			So(len(histogram.Entries), ShouldEqual, 3)
+			So(*histogram.Entries[2], ShouldResemble, Entry{Bin: 2, Lower: 8, Upper: 12, Count: 2})
+		})
+		Convey("Bin Width Rounding", func() {
+			// 2.9 + 24*1.2 rounds to 31.699999999999996, below the maximum
+			histogram, err := New([]float64{2.9, 31.7}, Options{Strategy: StrategyEqualWidth, Width: 1.2})
+			So(err, ShouldBeNil)
+			last := histogram.Entries[len(histogram.Entries)-1]
+			So(last.Upper, ShouldBeGreaterThanOrEqualTo, 31.7)
+			So(histogram.Entries[0].Count+last.Count, ShouldEqual, 2)
+		})
+		Convey("Single Value", func() {
+			histogram, err := New([]float64{3, 3}, Options{Strategy: StrategyScott})
+			So(err, ShouldBeNil)
+			So(len(histogram.Entries), ShouldEqual, 1)
+			So(histogram.Entries[0].Count, ShouldEqual, 2)
+		})
+		Convey("Too Many Bins", func() {
+			_, err := New(data, Options{Strategy: StrategyEqualWidth, Width: 0.001})
+			So(err, ShouldEqual, ErrTooManyBins)
+		})
+	})
+}
+
+func TestNew_AutomaticRules_CountEveryValue(t *testing.T) {
+	var data []float64
+	for i := 0; i < 100; i++ {
+		data = append(data, float64(i*i))
+	}
+
+	Convey("Test automatic rules\n", t, func() {
+		for _, strategy := range []Strategy{StrategySturges, StrategyScott, StrategyFreedmanDiaconis, StrategyLog} {
+			histogram, err := New(data, Options{Strategy: strategy})
+			So(err, ShouldBeNil)
+
+			var total int
+			for i, e := range histogram.Entries {
+				So(e.Lower, ShouldBeLessThan, e.Upper)
+				if i > 0 {
+					So(e.Lower, ShouldEqual, histogram.Entries[i-1].Upper)
+				}
+				total += e.Count
+			}
+			So(total, ShouldEqual, len(data))
+		}
+		Convey("Sturges Bin Count", func() {
+			histogram, _ := New(data, Options{Strategy: StrategySturges})
+			So(len(histogram.Entries), ShouldEqual, 8)
+		})
+		Convey("Log Bins Grow", func() {
+			histogram, _ := New(data, Options{Strategy: StrategyLog, Bins: 4})
+			So(len(histogram.Entries), ShouldEqual, 4)
+			So(histogram.Entries[3].Upper-histogram.Entries[3].Lower, ShouldBeGreaterThan,
+				histogram.Entries[0].Upper-histogram.Entries[0].Lower)
+		})
+	})
+}
+
+func TestNew_InvalidOptions_Error(t *testing.T) {
+	Convey("Test invalid options\n", t, func() {
+		for _, opts := range []Options{
+			{Strategy: "unknown"},
+			{Strategy: StrategyEqualWidth, Bins: MaxBins + 1},
+			{Strategy: StrategyEqualWidth, Bins: 2, Width: 1},
+			{Strategy: StrategySturges, Bins: 2},
+			{Strategy: StrategyLog, Width: 1},
+			{Strategy: StrategyScott, Percentiles: []float64{50}},
+		} {
+			_, err := New([]float64{1, 2}, opts)
+			So(err, ShouldNotBeNil)
+		}
+		Convey("Negative Log Data", func() {
+			_, err := New([]float64{-1, 2}, Options{Strategy: StrategyLog})
+			So(err, ShouldEqual, ErrNegativeData)
+		})
+	})
+}
@@ -1,45 +1,119 @@
 package histogram
 
 import (
+	"fmt"
+	"math"
 	"sort"
 )
 
 var (
-	percentiles = []int{25, 50, 75, 100}
+	// DefaultPercentiles are the quartile cut points
+	DefaultPercentiles = []float64{25, 50, 75, 100}
+)
+
+var (
+	ErrEmptyData          = fmt.Errorf("histogram data is empty")
+	ErrInvalidData        = fmt.Errorf("histogram data contains NaN values")
+	ErrInvalidPercentiles = fmt.Errorf("percentiles must be increasing values between 0 and 100")
 )
 
 type Histogram struct {
-	Entries []*Entry `json:"entries"`
+	Entries []*Entry  `json:"entries"`
+	Series  []*Series `json:"series,omitempty"`
+	// upperClosed bins hold (Lower, Upper] rather than [Lower, Upper)
+	upperClosed bool
 }
 
+// Series counts the values of a single category over the bins of the histogram.
+type Series struct {
+	Key     interface{} `json:"key"`
+	Entries []*Entry    `json:"entries"`
+}
+
+// Entry is a histogram bin, see Percentile and New for the values each bin
+// holds. Bin is the percentile or the index of the bin.
 type Entry struct {
-	Bin   int `json:"bin"`
-	Count int `json:"count"`
+	Bin   float64 `json:"bin"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
 }
 
-func Percentile(data []float64) *Histogram {
-	sort.Float64s(data)
+// ValidatePercentiles checks that percentiles are strictly increasing cut
+// points in (0, 100].
+func ValidatePercentiles(percentiles []float64) error {
+	if len(percentiles) == 0 {
+		return ErrInvalidPercentiles
+	}
+	for i, p := range percentiles {
+		if math.IsNaN(p) || p <= 0 || p > 100 || i > 0 && p <= percentiles[i-1] {
+			return ErrInvalidPercentiles
+		}
+	}
+	return nil
+}
 
-	percentileMax := make(map[int]float64)
+// Percentile bins data by the given percentile cut points, ordered by
+// percentile. Every bin holds the values in (Lower, Upper], the first bin
+// also holds values equal to Lower. A final 100th percentile bin is added
+// when missing so every value is counted. The data slice is not modified.
+func Percentile(data []float64, percentiles []float64) (*Histogram, error) {
+	if err := ValidatePercentiles(percentiles); err != nil {
+		return nil, err
+	}
+	sorted, err := sortData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if percentiles[len(percentiles)-1] != 100 {
+		percentiles = append(percentiles[:len(percentiles):len(percentiles)], 100)
+	}
+
+	histogram := Histogram{upperClosed: true}
+	lower, counted := sorted[0], 0
 	for _, p := range percentiles {
-		idx := (len(data) - 1) * p / 100
-		percentileMax[p] = data[idx]
+		upper := sorted[int(float64(len(sorted)-1)*p/100)]
+		// number of values less than or equal to the upper bound
+		n := sort.Search(len(sorted), func(i int) bool { return sorted[i] > upper })
+		histogram.Entries = append(histogram.Entries, &Entry{Bin: p, Lower: lower, Upper: upper, Count: n - counted})
+		lower, counted = upper, n
+	}
+
+	return &histogram, nil
+}
+
+// Count bins data over the bins of h, e.g. to split a histogram by category.
+// Values outside the bins are not counted.
+func (h *Histogram) Count(data []float64) []*Entry {
+	entries := make([]*Entry, len(h.Entries))
+	for i, e := range h.Entries {
+		entry := *e
+		entry.Count = 0
+		entries[i] = &entry
 	}
 
-	rs := make(map[int]int)
-	for _, fare := range data {
-		for _, p := range percentiles {
-			if fare <= percentileMax[p] {
-				rs[p]++
-				break
-			}
+	for _, v := range data {
+		if i := h.find(v); i >= 0 {
+			entries[i].Count++
 		}
 	}
+	return entries
+}
 
-	var histogram Histogram
-	for k, v := range rs {
-		histogram.Entries = append(histogram.Entries, &Entry{Bin: k, Count: v})
+// find returns the index of the bin holding v, -1 when no bin holds it.
+func (h *Histogram) find(v float64) int {
+	n := len(h.Entries)
+	if n == 0 || math.IsNaN(v) || v < h.Entries[0].Lower || v > h.Entries[n-1].Upper {
+		return -1
 	}
 
-	return &histogram
+	if h.upperClosed {
+		return sort.Search(n, func(i int) bool { return h.Entries[i].Upper >= v })
+	}
+	// the maximum goes to the last bin
+	if i := sort.Search(n, func(i int) bool { return h.Entries[i].Upper > v }); i < n {
+		return i
+	}
+	return n - 1
 }
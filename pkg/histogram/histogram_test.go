@@ -1 +1,75 @@
 package histogram
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPercentile_ValidData_OrderedBins(t *testing.T) {
+	data := []float64{10, 1, 9, 2, 8, 3, 7, 4, 6, 5, 5}
+
+	// when
+	histogram, err := Percentile(data, []float64{10, 50, 90})
+
+	// then
+	Convey("Test percentile\n", t, func() {
+		Convey("Error Should Be Nil", func() {
+			So(err, ShouldBeNil)
+		})
+		Convey("Bins As Expected", func() {
+			So(len(histogram.Entries), ShouldEqual, 4)
+			So(*histogram.Entries[0], ShouldResemble, Entry{Bin: 10, Lower: 1, Upper: 2, Count: 2})
+			So(*histogram.Entries[1], ShouldResemble, Entry{Bin: 50, Lower: 2, Upper: 5, Count: 4})
+			So(*histogram.Entries[2], ShouldResemble, Entry{Bin: 90, Lower: 5, Upper: 9, Count: 4})
+			So(*histogram.Entries[3], ShouldResemble, Entry{Bin: 100, Lower: 9, Upper: 10, Count: 1})
+		})
+		Convey("Data Should Not Be Modified", func() {
+			So(data[0], ShouldEqual, 10)
+		})
+	})
+}
+
+func TestPercentile_InvalidInput_Error(t *testing.T) {
+	Convey("Test percentile\n", t, func() {
+		Convey("Empty Data", func() {
+			_, err := Percentile(nil, DefaultPercentiles)
+			So(err, ShouldEqual, ErrEmptyData)
+		})
+		Convey("NaN Data", func() {
+			_, err := Percentile([]float64{1, math.NaN()}, DefaultPercentiles)
+			So(err, ShouldEqual, ErrInvalidData)
+		})
+		Convey("Invalid Percentiles", func() {
+			for _, percentiles := range [][]float64{nil, {0}, {50, 25}, {50, 50}, {101}, {math.NaN()}} {
+				_, err := Percentile([]float64{1}, percentiles)
+				So(err, ShouldEqual, ErrInvalidPercentiles)
+			}
+		})
+	})
+}
+
+func TestCount_SharedBins_SeriesCounts(t *testing.T) {
+	data := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	Convey("Test count\n", t, func() {
+		Convey("Percentile Bins", func() {
+			histogram, err := Percentile(data, []float64{50})
+			So(err, ShouldBeNil)
+			entries := histogram.Count([]float64{1, 5, 6, 11})
+			So(len(entries), ShouldEqual, 2)
+			So(entries[0].Count, ShouldEqual, 2)
+			So(entries[1].Count, ShouldEqual, 1)
+			So(histogram.Entries[0].Count, ShouldEqual, 5)
+		})
+		Convey("Width Bins", func() {
+			histogram, err := New(data, Options{Strategy: StrategyEqualWidth, Bins: 3})
+			So(err, ShouldBeNil)
+			entries := histogram.Count([]float64{0, 1, 4, 10})
+			So(entries[0].Count, ShouldEqual, 1)
+			So(entries[1].Count, ShouldEqual, 1)
+			So(entries[2].Count, ShouldEqual, 1)
+		})
+	})
+}
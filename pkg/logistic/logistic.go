@@ -0,0 +1,176 @@
+package logistic
+
+import (
+	"fmt"
+	"math"
+)
+
+var (
+	ErrEmptyData    = fmt.Errorf("no samples provided to train on")
+	ErrInvalidData  = fmt.Errorf("samples must have the same number of finite features")
+	ErrInvalidLabel = fmt.Errorf("labels must be 0 or 1, one per sample")
+)
+
+// Options tune the gradient descent, zero Iterations, LearningRate and
+// Tolerance take the defaults.
+type Options struct {
+	// Iterations caps the number of full batch gradient steps
+	Iterations int
+	// LearningRate is the step size over standardized features
+	LearningRate float64
+	// L2 is the ridge penalty of the weights, the bias is not penalised
+	L2 float64
+	// Tolerance stops the descent once every gradient component is smaller
+	Tolerance float64
+}
+
+var DefaultOptions = Options{Iterations: 10000, LearningRate: 0.5, L2: 1e-3, Tolerance: 1e-9}
+
+// Model is a logistic regression over standardized features, a feature is
+// centered on Means and divided by Scales before being weighted.
+type Model struct {
+	Means   []float64 `json:"means"`
+	Scales  []float64 `json:"scales"`
+	Weights []float64 `json:"weights"`
+	Bias    float64   `json:"bias"`
+}
+
+// Train fits a model of y given x by minimising the mean log loss.
+func Train(x [][]float64, y []float64, opts Options) (*Model, error) {
+	switch {
+	case len(x) == 0:
+		return nil, ErrEmptyData
+	case len(y) != len(x):
+		return nil, ErrInvalidLabel
+	}
+	for i, row := range x {
+		if len(row) != len(x[0]) {
+			return nil, ErrInvalidData
+		}
+		for _, v := range row {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, ErrInvalidData
+			}
+		}
+		if y[i] != 0 && y[i] != 1 {
+			return nil, ErrInvalidLabel
+		}
+	}
+	opts = opts.withDefaults()
+
+	m := standardize(x)
+	z := make([][]float64, len(x))
+	for i, row := range x {
+		z[i] = m.scale(row)
+	}
+
+	n := float64(len(x))
+	gradient := make([]float64, len(m.Weights))
+	for it := 0; it < opts.Iterations; it++ {
+		for j := range gradient {
+			gradient[j] = opts.L2 * m.Weights[j]
+		}
+		var biasGradient float64
+		for i, row := range z {
+			residual := (sigmoid(m.linear(row)) - y[i]) / n
+			biasGradient += residual
+			for j, v := range row {
+				gradient[j] += residual * v
+			}
+		}
+
+		largest := math.Abs(biasGradient)
+		m.Bias -= opts.LearningRate * biasGradient
+		for j, g := range gradient {
+			largest = math.Max(largest, math.Abs(g))
+			m.Weights[j] -= opts.LearningRate * g
+		}
+		if largest < opts.Tolerance {
+			break
+		}
+	}
+	return m, nil
+}
+
+// LogOdds is the log odds of a positive label for the features x.
+func (m *Model) LogOdds(x []float64) float64 {
+	return m.linear(m.scale(x))
+}
+
+// Probability is the probability of a positive label for the features x.
+func (m *Model) Probability(x []float64) float64 {
+	return sigmoid(m.LogOdds(x))
+}
+
+// Contributions splits the log odds of x into a term per feature, they add
+// up to the log odds minus the bias, the log odds of the mean sample.
+func (m *Model) Contributions(x []float64) []float64 {
+	z := m.scale(x)
+	contributions := make([]float64, len(z))
+	for j, v := range z {
+		contributions[j] = m.Weights[j] * v
+	}
+	return contributions
+}
+
+func (m *Model) scale(x []float64) []float64 {
+	z := make([]float64, len(x))
+	for j, v := range x {
+		z[j] = (v - m.Means[j]) / m.Scales[j]
+	}
+	return z
+}
+
+func (m *Model) linear(z []float64) float64 {
+	v := m.Bias
+	for j, w := range m.Weights {
+		v += w * z[j]
+	}
+	return v
+}
+
+func (o Options) withDefaults() Options {
+	if o.Iterations <= 0 {
+		o.Iterations = DefaultOptions.Iterations
+	}
+	if o.LearningRate <= 0 {
+		o.LearningRate = DefaultOptions.LearningRate
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = DefaultOptions.Tolerance
+	}
+	return o
+}
+
+// standardize returns an untrained model holding the feature means and
+// standard deviations, constant features keep a unit scale.
+func standardize(x [][]float64) *Model {
+	features := len(x[0])
+	m := &Model{
+		Means:   make([]float64, features),
+		Scales:  make([]float64, features),
+		Weights: make([]float64, features),
+	}
+	n := float64(len(x))
+	for _, row := range x {
+		for j, v := range row {
+			m.Means[j] += v / n
+		}
+	}
+	for _, row := range x {
+		for j, v := range row {
+			m.Scales[j] += (v - m.Means[j]) * (v - m.Means[j]) / n
+		}
+	}
+	for j, s := range m.Scales {
+		m.Scales[j] = math.Sqrt(s)
+		if m.Scales[j] == 0 {
+			m.Scales[j] = 1
+		}
+	}
+	return m
+}
+
+func sigmoid(v float64) float64 {
+	return 1 / (1 + math.Exp(-v))
+}
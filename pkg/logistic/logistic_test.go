@@ -0,0 +1,60 @@
+package logistic
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTrain_Samples_Model(t *testing.T) {
+	// the label follows the first feature 80% of the time, the second is noise
+	var x [][]float64
+	var y []float64
+	for i := 0; i < 100; i++ {
+		a, b := float64(i%2), float64(i%7)
+		label := a
+		if i%10 < 2 {
+			label = 1 - a
+		}
+		x, y = append(x, []float64{a, b}), append(y, label)
+	}
+
+	// when
+	m, err := Train(x, y, Options{L2: 0})
+
+	// then
+	Convey("Test train\n", t, func() {
+		So(err, ShouldBeNil)
+		Convey("Maximum Likelihood Probabilities", func() {
+			// an unpenalised fit of a binary feature recovers the empirical rates
+			So(m.Probability([]float64{1, 3}), ShouldAlmostEqual, 0.8, 0.02)
+			So(m.Probability([]float64{0, 3}), ShouldAlmostEqual, 0.2, 0.02)
+			So(math.Abs(m.Weights[1]), ShouldBeLessThan, math.Abs(m.Weights[0])/5)
+		})
+		Convey("Contributions Add Up To Log Odds", func() {
+			sample := []float64{1, 5}
+			sum := m.Bias
+			for _, c := range m.Contributions(sample) {
+				sum += c
+			}
+			So(sum, ShouldAlmostEqual, m.LogOdds(sample), 1e-12)
+			So(m.Contributions(m.Means), ShouldResemble, []float64{0, 0})
+		})
+	})
+}
+
+func TestTrain_InvalidSamples_Error(t *testing.T) {
+	Convey("Test train\n", t, func() {
+		_, err := Train(nil, nil, DefaultOptions)
+		So(err, ShouldEqual, ErrEmptyData)
+		_, err = Train([][]float64{{1}, {1, 2}}, []float64{0, 1}, DefaultOptions)
+		So(err, ShouldEqual, ErrInvalidData)
+		_, err = Train([][]float64{{1}, {math.NaN()}}, []float64{0, 1}, DefaultOptions)
+		So(err, ShouldEqual, ErrInvalidData)
+		_, err = Train([][]float64{{1}, {2}}, []float64{0, 2}, DefaultOptions)
+		So(err, ShouldEqual, ErrInvalidLabel)
+		_, err = Train([][]float64{{1}, {2}}, []float64{0}, DefaultOptions)
+		So(err, ShouldEqual, ErrInvalidLabel)
+	})
+}
@@ -0,0 +1,305 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+)
+
+var (
+	ErrNotTabular = errors.New("response cannot be represented as csv")
+)
+
+// Recorder is a body with its own CSV rendering, the header record first.
+// Other bodies are rendered from their JSON encoding, see csvRecords.
+type Recorder interface {
+	Records() [][]string
+}
+
+// List is a list body which keeps its CSV header when it has no items, the
+// header is then rendered from Template, e.g. an item of zero values. Other
+// formats encode Items alone.
+type List struct {
+	Items    []interface{}
+	Template interface{}
+}
+
+func (l *List) MarshalJSON() ([]byte, error) {
+	if l.Items == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l.Items)
+}
+
+// object is a JSON object which keeps the order of its keys.
+type object struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decode returns the JSON encoding of body as objects, slices, strings,
+// numbers, booleans and nil, objects keep the order of their keys.
+func decode(body interface{}) (interface{}, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		o := &object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			o.keys, o.values = append(o.keys, key.(string)), append(o.values, value)
+		}
+		// closing delimiter
+		_, err := dec.Token()
+		return o, err
+	case json.Delim('['):
+		items := []interface{}{}
+		for dec.More() {
+			item, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := dec.Token()
+		return items, err
+	}
+	return token, nil
+}
+
+// csvRecords renders body as CSV records. A list of objects has a record per
+// object and a single object a single record, the header is the union of the
+// object keys in order of appearance. Nested objects are flattened with dotted
+// keys, e.g. name-parts.title, nested lists are JSON encoded cells and null is
+// an empty cell. Empty lists have no records unless they are a List.
+func csvRecords(body interface{}) ([][]string, error) {
+	if recorder, ok := body.(Recorder); ok {
+		return recorder.Records(), nil
+	}
+	if list, ok := body.(*List); ok {
+		if len(list.Items) > 0 {
+			return csvRecords(list.Items)
+		}
+		records, err := csvRecords([]interface{}{list.Template})
+		if len(records) > 1 {
+			records = records[:1]
+		}
+		return records, err
+	}
+
+	value, err := decode(body)
+	if err != nil {
+		return nil, err
+	}
+	var rows []interface{}
+	switch value := value.(type) {
+	case []interface{}:
+		rows = value
+	case *object:
+		rows = []interface{}{value}
+	default:
+		return nil, ErrNotTabular
+	}
+
+	var header []string
+	columns := make(map[string]int)
+	cells := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		o, ok := row.(*object)
+		if !ok {
+			return nil, ErrNotTabular
+		}
+		cells[i] = make(map[string]string)
+		err := flatten("", o, func(key, cell string) {
+			if _, found := columns[key]; !found {
+				columns[key] = len(header)
+				header = append(header, key)
+			}
+			cells[i][key] = cell
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(header) == 0 {
+		return nil, nil
+	}
+
+	records := [][]string{header}
+	for _, row := range cells {
+		record := make([]string, len(header))
+		for key, cell := range row {
+			record[columns[key]] = cell
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func flatten(prefix string, o *object, add func(key, cell string)) error {
+	for i, key := range o.keys {
+		if nested, ok := o.values[i].(*object); ok {
+			if err := flatten(prefix+key+".", nested, add); err != nil {
+				return err
+			}
+			continue
+		}
+		c, err := cell(o.values[i])
+		if err != nil {
+			return err
+		}
+		add(prefix+key, c)
+	}
+	return nil
+}
+
+// cell renders a scalar as text, lists and objects as JSON.
+func cell(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case json.Number:
+		return value.String(), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// writeNDJSON writes body as newline delimited JSON, a line per list item or a
+// single line for other bodies.
+func writeNDJSON(w io.Writer, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		items = []json.RawMessage{data}
+	}
+
+	for _, item := range items {
+		if _, err := w.Write(append(item, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeXML writes body under a response element. Object keys are element
+// names, made valid by replacing other characters with underscores, and list
+// items are item elements. Null values are empty elements.
+func writeXML(w io.Writer, body interface{}) error {
+	value, err := decode(body)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := encodeXML(enc, "response", value); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func encodeXML(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case *object:
+		for i, key := range value.keys {
+			if err := encodeXML(enc, key, value.values[i]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if err := encodeXML(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		text, err := cell(value)
+		if err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName makes name a valid XML element name, e.g. avg(fare) is avg_fare_.
+func xmlName(name string) string {
+	buf := make([]byte, 0, len(name)+1)
+	// names start with a letter or an underscore
+	if len(name) == 0 || name[0] >= '0' && name[0] <= '9' || name[0] == '-' || name[0] == '.' {
+		buf = append(buf, '_')
+	}
+	for _, c := range []byte(name) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-', c == '.':
+			buf = append(buf, c)
+		default:
+			buf = append(buf, '_')
+		}
+	}
+	return string(buf)
+}
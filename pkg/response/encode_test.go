@@ -0,0 +1,115 @@
+package response
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testItem struct {
+	ID     int       `json:"id"`
+	Name   string    `json:"name"`
+	Parts  *testName `json:"name-parts,omitempty"`
+	Tags   []string  `json:"tags"`
+	Rating *float64  `json:"rating"`
+}
+
+type testName struct {
+	Title string `json:"title"`
+}
+
+type testRecorder struct{}
+
+func (testRecorder) Records() [][]string {
+	return [][]string{{"a"}, {"1"}}
+}
+
+func createTestItems() []*testItem {
+	rating := 4.5
+	return []*testItem{
+		{ID: 1, Name: "Doe, Mr. John", Tags: []string{"a", "b"}, Rating: &rating},
+		{ID: 2, Name: "Roe <Jane>", Parts: &testName{Title: "Mrs"}},
+	}
+}
+
+func TestCSVRecords_Body_Records(t *testing.T) {
+	Convey("Test csv records\n", t, func() {
+		Convey("List Of Objects", func() {
+			records, err := csvRecords(createTestItems())
+			So(err, ShouldBeNil)
+			So(records, ShouldResemble, [][]string{
+				{"id", "name", "tags", "rating", "name-parts.title"},
+				{"1", "Doe, Mr. John", `["a","b"]`, "4.5", ""},
+				{"2", "Roe <Jane>", "", "", "Mrs"},
+			})
+		})
+		Convey("Single Object", func() {
+			records, err := csvRecords(map[string]int{"count": 3})
+			So(err, ShouldBeNil)
+			So(records, ShouldResemble, [][]string{{"count"}, {"3"}})
+		})
+		Convey("Recorder", func() {
+			records, err := csvRecords(testRecorder{})
+			So(err, ShouldBeNil)
+			So(records, ShouldResemble, [][]string{{"a"}, {"1"}})
+		})
+		Convey("Empty List", func() {
+			records, err := csvRecords([]*testItem{})
+			So(err, ShouldBeNil)
+			So(records, ShouldBeEmpty)
+		})
+		Convey("List", func() {
+			records, err := csvRecords(&List{Items: []interface{}{createTestItems()[0]}, Template: &testItem{}})
+			So(err, ShouldBeNil)
+			So(len(records), ShouldEqual, 2)
+
+			// empty lists keep the header of the template
+			records, err = csvRecords(&List{Template: &testItem{}})
+			So(err, ShouldBeNil)
+			So(records, ShouldResemble, [][]string{{"id", "name", "tags", "rating"}})
+
+			var buf bytes.Buffer
+			So(writeNDJSON(&buf, &List{Template: &testItem{}}), ShouldBeNil)
+			So(buf.String(), ShouldBeEmpty)
+		})
+		Convey("Not Tabular", func() {
+			for _, body := range []interface{}{[]int{1, 2}, "text", 3} {
+				_, err := csvRecords(body)
+				So(err, ShouldEqual, ErrNotTabular)
+			}
+		})
+	})
+}
+
+func TestWriteNDJSON_Body_Lines(t *testing.T) {
+	Convey("Test write ndjson\n", t, func() {
+		// html characters are escaped like json responses
+		var buf bytes.Buffer
+		So(writeNDJSON(&buf, createTestItems()), ShouldBeNil)
+		So(buf.String(), ShouldEqual,
+			`{"id":1,"name":"Doe, Mr. John","tags":["a","b"],"rating":4.5}`+"\n"+
+				`{"id":2,"name":"Roe \u003cJane\u003e","name-parts":{"title":"Mrs"},"tags":null,"rating":null}`+"\n")
+
+		buf.Reset()
+		So(writeNDJSON(&buf, map[string]int{"count": 3}), ShouldBeNil)
+		So(buf.String(), ShouldEqual, `{"count":3}`+"\n")
+	})
+}
+
+func TestWriteXML_Body_Elements(t *testing.T) {
+	Convey("Test write xml\n", t, func() {
+		var buf bytes.Buffer
+		So(writeXML(&buf, createTestItems()), ShouldBeNil)
+		So(buf.String(), ShouldEqual, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<response>`+
+			`<item><id>1</id><name>Doe, Mr. John</name><tags><item>a</item><item>b</item></tags><rating>4.5</rating></item>`+
+			`<item><id>2</id><name>Roe &lt;Jane&gt;</name><name-parts><title>Mrs</title></name-parts><tags></tags>`+
+			`<rating></rating></item></response>`)
+	})
+	Convey("Test xml name\n", t, func() {
+		So(xmlName("avg(fare)"), ShouldEqual, "avg_fare_")
+		So(xmlName("siblings-spouses"), ShouldEqual, "siblings-spouses")
+		So(xmlName("1st"), ShouldEqual, "_1st")
+		So(xmlName(""), ShouldEqual, "_")
+	})
+}
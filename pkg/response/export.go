@@ -0,0 +1,214 @@
+package response
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"titanic-api/pkg/xlsx"
+)
+
+const (
+	FormatXLSX = "xlsx"
+)
+
+var (
+	ErrExportFormat = errors.New("export format must be one of csv, ndjson, xlsx")
+)
+
+// tableWriter writes the rows of an export file.
+type tableWriter interface {
+	WriteRow(values []interface{}) error
+	Flush() error
+	Close() error
+}
+
+// Export writes a table as a file download a row at a time, in csv, ndjson
+// (an object per row keyed by column) or xlsx (a sheet named after the file).
+// Like Stream, the status and headers are sent with the first row and rows are
+// flushed every streamFlushSize rows.
+type Export struct {
+	r       *http.Request
+	w       http.ResponseWriter
+	format  string
+	name    string
+	columns []string
+	table   tableWriter
+	written int
+}
+
+// NewExport starts an export of columns named after name, e.g. passengers.csv.
+// Formats other than csv, ndjson and xlsx return ErrExportFormat.
+func NewExport(r *http.Request, w http.ResponseWriter, format, name string, columns []string) (*Export, error) {
+	switch format {
+	case FormatCSV, FormatNDJSON, FormatXLSX:
+	default:
+		return nil, ErrExportFormat
+	}
+	return &Export{r: r, w: w, format: format, name: name, columns: columns}, nil
+}
+
+// Started reports whether the status and headers were sent.
+func (e *Export) Started() bool {
+	return e.table != nil
+}
+
+// Write writes the next row, values are in column order. It fails once the
+// client is gone, which ends the request context, so callers stop reading rows.
+func (e *Export) Write(values []interface{}) error {
+	if err := e.r.Context().Err(); err != nil {
+		return err
+	}
+	if err := e.start(); err != nil {
+		return err
+	}
+	if err := e.table.WriteRow(values); err != nil {
+		return err
+	}
+
+	e.written++
+	if e.written%streamFlushSize == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// Close ends the file, an export which failed midway is left unterminated so
+// Close is only called once every row is written.
+func (e *Export) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if err := e.table.Close(); err != nil {
+		return err
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func (e *Export) start() error {
+	if e.table != nil {
+		return nil
+	}
+
+	contentType := contentTypes[e.format]
+	if e.format == FormatXLSX {
+		contentType = xlsx.ContentType
+	}
+	e.w.Header().Set("Content-Type", contentType)
+	e.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": e.name + "." + e.format}))
+	e.w.WriteHeader(http.StatusOK)
+
+	var err error
+	switch e.format {
+	case FormatCSV:
+		e.table, err = newCSVTable(e.w, e.columns)
+	case FormatNDJSON:
+		e.table = &ndjsonTable{w: e.w, columns: e.columns}
+	default:
+		e.table, err = xlsx.NewWriter(e.w, e.name, e.columns)
+	}
+	return err
+}
+
+func (e *Export) flush() error {
+	if err := e.table.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// csvTable writes the header record then a record per row.
+type csvTable struct {
+	writer *csv.Writer
+}
+
+func newCSVTable(w io.Writer, columns []string) (*csvTable, error) {
+	t := &csvTable{writer: csv.NewWriter(w)}
+	if err := t.writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *csvTable) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		c, err := exportCell(value)
+		if err != nil {
+			return err
+		}
+		record[i] = c
+	}
+	return t.writer.Write(record)
+}
+
+func (t *csvTable) Flush() error {
+	t.writer.Flush()
+	return t.writer.Error()
+}
+
+func (t *csvTable) Close() error {
+	return t.Flush()
+}
+
+// ndjsonTable writes a JSON object per row with the columns as keys.
+type ndjsonTable struct {
+	w       io.Writer
+	columns []string
+}
+
+func (t *ndjsonTable) WriteRow(values []interface{}) error {
+	data, err := json.Marshal(&object{keys: t.columns, values: values})
+	if err != nil {
+		return err
+	}
+	_, err = t.w.Write(append(data, '\n'))
+	return err
+}
+
+func (t *ndjsonTable) Flush() error {
+	return nil
+}
+
+func (t *ndjsonTable) Close() error {
+	return nil
+}
+
+// exportCell renders a scalar as text, pointers as the value they point to,
+// nil as an empty cell and lists and objects as JSON.
+func exportCell(value interface{}) (string, error) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.String:
+		return v.String(), nil
+	}
+	data, err := json.Marshal(v.Interface())
+	return string(data), err
+}
@@ -0,0 +1,88 @@
+package response
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"titanic-api/pkg/xlsx"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExport_Rows_Body(t *testing.T) {
+	Convey("Test export\n", t, func() {
+		age := 22.5
+		for _, tc := range []struct {
+			format, contentType, body string
+		}{
+			{format: "csv", contentType: "text/csv", body: "id,name,age\n1,\"Braund, Mr. Owen\",22.5\n2,Cumings,\n"},
+			{format: "ndjson", contentType: "application/x-ndjson",
+				body: `{"id":1,"name":"Braund, Mr. Owen","age":22.5}` + "\n" + `{"id":2,"name":"Cumings","age":null}` + "\n"},
+		} {
+			Convey("Test export '"+tc.format+"'\n", func() {
+				r, err := http.NewRequest("GET", "/passenger/export", nil)
+				So(err, ShouldBeNil)
+
+				w := httptest.NewRecorder()
+				e, err := NewExport(r, w, tc.format, "passengers", []string{"id", "name", "age"})
+				So(err, ShouldBeNil)
+				So(e.Started(), ShouldBeFalse)
+				So(e.Write([]interface{}{1, "Braund, Mr. Owen", &age}), ShouldBeNil)
+				So(e.Started(), ShouldBeTrue)
+				So(e.Write([]interface{}{2, "Cumings", (*float64)(nil)}), ShouldBeNil)
+				So(e.Close(), ShouldBeNil)
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldStartWith, tc.contentType)
+				So(w.Header().Get("Content-Disposition"), ShouldEqual, "attachment; filename=passengers."+tc.format)
+				So(w.Body.String(), ShouldEqual, tc.body)
+				So(w.Flushed, ShouldBeTrue)
+			})
+		}
+		Convey("Test export 'xlsx'\n", func() {
+			r, err := http.NewRequest("GET", "/passenger/export", nil)
+			So(err, ShouldBeNil)
+
+			w := httptest.NewRecorder()
+			e, err := NewExport(r, w, "xlsx", "passengers", []string{"id"})
+			So(err, ShouldBeNil)
+			So(e.Close(), ShouldBeNil)
+			So(w.Header().Get("Content-Type"), ShouldEqual, xlsx.ContentType)
+			So(w.Header().Get("Content-Disposition"), ShouldEqual, "attachment; filename=passengers.xlsx")
+
+			_, err = zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestExport_UnsupportedFormat_Error(t *testing.T) {
+	Convey("Test export\n", t, func() {
+		r, err := http.NewRequest("GET", "/passenger/export", nil)
+		So(err, ShouldBeNil)
+
+		for _, format := range []string{"json", "xml", ""} {
+			_, err = NewExport(r, httptest.NewRecorder(), format, "passengers", []string{"id"})
+			So(err, ShouldEqual, ErrExportFormat)
+		}
+	})
+}
+
+func TestExport_ClientGone_Error(t *testing.T) {
+	Convey("Test export\n", t, func() {
+		c, cancel := context.WithCancel(context.Background())
+		r, err := http.NewRequestWithContext(c, "GET", "/passenger/export", nil)
+		So(err, ShouldBeNil)
+
+		w := httptest.NewRecorder()
+		e, err := NewExport(r, w, "ndjson", "passengers", []string{"id"})
+		So(err, ShouldBeNil)
+		So(e.Write([]interface{}{1}), ShouldBeNil)
+
+		cancel()
+		So(e.Write([]interface{}{2}), ShouldEqual, context.Canceled)
+		So(w.Body.String(), ShouldEqual, `{"id":1}`+"\n")
+	})
+}
@@ -0,0 +1,126 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXML    = "xml"
+)
+
+var (
+	ErrNotAcceptable = errors.New("response format is not supported, supported formats are json (application/json), " +
+		"csv (text/csv), ndjson (application/x-ndjson) and xml (application/xml)")
+)
+
+var (
+	// contentTypes are the response content types of every format
+	contentTypes = map[string]string{
+		FormatJSON:   "application/json; charset=utf-8",
+		FormatCSV:    "text/csv; charset=utf-8",
+		FormatNDJSON: "application/x-ndjson",
+		FormatXML:    "application/xml; charset=utf-8",
+	}
+	// mediaTypes are the accepted media types of every format, wildcards match
+	// JSON whatever their type
+	mediaTypes = map[string]string{
+		"*/*":                  FormatJSON,
+		"application/*":        FormatJSON,
+		"application/json":     FormatJSON,
+		"text/*":               FormatJSON,
+		"text/csv":             FormatCSV,
+		"application/x-ndjson": FormatNDJSON,
+		"application/ndjson":   FormatNDJSON,
+		"application/xml":      FormatXML,
+		"text/xml":             FormatXML,
+	}
+	// htmlTypes are the media types browsers list first, they are answered with
+	// JSON when it is acceptable rather than the other formats they list
+	htmlTypes = map[string]bool{"text/html": true, "application/xhtml+xml": true}
+)
+
+// Negotiate returns the response format of r. The format query parameter
+// overrides the Accept header, otherwise the supported media type with the
+// highest quality is chosen, the first one listed on ties. Wildcards match
+// JSON, which wins unless a listed media type has a strictly higher quality.
+// Browsers, which list html, get JSON whenever they accept it. Requests
+// without either get JSON.
+func Negotiate(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); len(format) > 0 {
+		if _, found := contentTypes[format]; !found {
+			return "", ErrNotAcceptable
+		}
+		return format, nil
+	}
+
+	accept := strings.TrimSpace(strings.Join(r.Header.Values("Accept"), ","))
+	if len(accept) == 0 {
+		return FormatJSON, nil
+	}
+
+	type candidate struct {
+		format   string
+		quality  float64
+		wildcard bool
+	}
+	var candidates []candidate
+	var html, acceptsJSON bool
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil {
+				quality = q
+			}
+		}
+		// a zero quality rules the media type out
+		if quality <= 0 {
+			continue
+		}
+
+		html = html || htmlTypes[mediaType]
+		format, found := mediaTypes[mediaType]
+		if !found {
+			continue
+		}
+		acceptsJSON = acceptsJSON || format == FormatJSON
+		candidates = append(candidates, candidate{format: format, quality: quality,
+			wildcard: strings.HasSuffix(mediaType, "/*")})
+	}
+	if len(candidates) == 0 {
+		return "", ErrNotAcceptable
+	}
+	if html && acceptsJSON {
+		return FormatJSON, nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	best := candidates[0]
+	if best.wildcard {
+		return FormatJSON, nil
+	}
+	// a listed media type only wins over an equally good wildcard when it is JSON
+	for _, c := range candidates[1:] {
+		if c.quality < best.quality {
+			break
+		}
+		if c.wildcard {
+			return FormatJSON, nil
+		}
+	}
+	return best.format, nil
+}
@@ -0,0 +1,55 @@
+package response
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNegotiate_Request_Format(t *testing.T) {
+	Convey("Test negotiate\n", t, func() {
+		tests := []struct {
+			query, accept, format string
+		}{
+			{query: "", accept: "", format: FormatJSON},
+			{query: "", accept: "*/*", format: FormatJSON},
+			{query: "", accept: "text/csv", format: FormatCSV},
+			{query: "", accept: "Text/CSV; charset=utf-8", format: FormatCSV},
+			{query: "", accept: "application/x-ndjson", format: FormatNDJSON},
+			{query: "", accept: "text/xml", format: FormatXML},
+			// browsers get json whatever else they list
+			{query: "", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", format: FormatJSON},
+			{query: "", accept: "text/html, text/*;q=0.1", format: FormatJSON},
+			{query: "", accept: "text/*", format: FormatJSON},
+			{query: "", accept: "application/json;q=0.5, text/csv", format: FormatCSV},
+			{query: "", accept: "text/csv, application/json", format: FormatCSV},
+			{query: "", accept: "application/xml, */*;q=0.8", format: FormatXML},
+			{query: "", accept: "application/xml, */*", format: FormatJSON},
+			{query: "format=ndjson", accept: "text/csv", format: FormatNDJSON},
+		}
+		for _, tc := range tests {
+			Convey("Test negotiate '"+tc.query+"' '"+tc.accept+"'\n", func() {
+				r, err := http.NewRequest("GET", "/passenger?"+tc.query, nil)
+				So(err, ShouldBeNil)
+				r.Header.Set("Accept", tc.accept)
+
+				format, err := Negotiate(r)
+				So(err, ShouldBeNil)
+				So(format, ShouldEqual, tc.format)
+			})
+		}
+
+		for _, tc := range [][2]string{{"format=yaml", ""}, {"", "image/png"}, {"", "text/csv;q=0"},
+			{"", "text/html"}} {
+			Convey("Test negotiate unsupported '"+tc[0]+"' '"+tc[1]+"'\n", func() {
+				r, err := http.NewRequest("GET", "/passenger?"+tc[0], nil)
+				So(err, ShouldBeNil)
+				r.Header.Set("Accept", tc[1])
+
+				_, err = Negotiate(r)
+				So(err, ShouldEqual, ErrNotAcceptable)
+			})
+		}
+	})
+}
@@ -1,13 +1,23 @@
 package response
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
+	"log"
 	"net/http"
+	"titanic-api/pkg/geojson"
 )
 
 var (
 	ErrInternalFailure = errors.New("something went wrong, please try again")
+	ErrTimeout         = errors.New("request timed out, please try again")
+	ErrUnavailable     = errors.New("request was cancelled, please try again")
 )
 
 // Error represents the structure of an error response.
@@ -26,8 +36,105 @@ func SendError(r *http.Request, w http.ResponseWriter, code int, message string)
 	render.JSON(w, r, err)
 }
 
-// SendBody sends response in JSON format.
+// SendFailure sends an error response for an unexpected failure, requests which
+// ran out of time get 504 and cancelled requests get 503.
+func SendFailure(r *http.Request, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(r.Context().Err(), context.DeadlineExceeded):
+		SendError(r, w, http.StatusGatewayTimeout, ErrTimeout.Error())
+	case errors.Is(err, context.Canceled), errors.Is(r.Context().Err(), context.Canceled):
+		SendError(r, w, http.StatusServiceUnavailable, ErrUnavailable.Error())
+	default:
+		SendError(r, w, http.StatusInternalServerError, ErrInternalFailure.Error())
+	}
+}
+
+// SendBody sends body in the format negotiated with the request, see
+// Negotiate. Unsupported formats and bodies which cannot be represented in the
+// format get 406.
 func SendBody(r *http.Request, w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Add("Vary", "Accept")
+	format, err := Negotiate(r)
+	if err != nil {
+		SendError(r, w, http.StatusNotAcceptable, err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatCSV:
+		records, err := csvRecords(body)
+		switch {
+		case err == ErrNotTabular:
+			SendError(r, w, http.StatusNotAcceptable, err.Error())
+		case err != nil:
+			sendEncodingFailure(r, w, format, err)
+		default:
+			SendCSV(r, w, code, records)
+		}
+		return
+	case FormatNDJSON:
+		err = writeNDJSON(&buf, body)
+	case FormatXML:
+		err = writeXML(&buf, body)
+	default:
+		w.Header().Set("Content-Type", contentTypes[FormatJSON])
+		w.WriteHeader(code)
+		render.JSON(w, r, body)
+		return
+	}
+
+	if err != nil {
+		sendEncodingFailure(r, w, format, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
 	w.WriteHeader(code)
-	render.JSON(w, r, body)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Println(fmt.Sprintf("request id: %s failed to write %s response: %v",
+			middleware.GetReqID(r.Context()), format, err.Error()))
+	}
+}
+
+func sendEncodingFailure(r *http.Request, w http.ResponseWriter, format string, err error) {
+	log.Println(fmt.Sprintf("request id: %s failed to encode %s response: %v",
+		middleware.GetReqID(r.Context()), format, err.Error()))
+	SendError(r, w, http.StatusInternalServerError, ErrInternalFailure.Error())
+}
+
+// SendNotAcceptable sends 406 when the response format of r is not supported
+// and reports whether it did. Handlers which change state call it first, as
+// SendBody only refuses the format once the change is made.
+func SendNotAcceptable(r *http.Request, w http.ResponseWriter) bool {
+	if _, err := Negotiate(r); err != nil {
+		w.Header().Add("Vary", "Accept")
+		SendError(r, w, http.StatusNotAcceptable, err.Error())
+		return true
+	}
+	return false
+}
+
+// SendStatus sends response with status code and no body.
+func SendStatus(r *http.Request, w http.ResponseWriter, code int) {
+	w.WriteHeader(code)
+}
+
+// SendCSV sends records in CSV format.
+func SendCSV(r *http.Request, w http.ResponseWriter, code int, records [][]string) {
+	w.Header().Set("Content-Type", contentTypes[FormatCSV])
+	w.WriteHeader(code)
+	if err := csv.NewWriter(w).WriteAll(records); err != nil {
+		log.Println(fmt.Sprintf("request id: %s failed to write csv response: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+	}
+}
+
+// SendGeoJSON sends a GeoJSON object.
+func SendGeoJSON(r *http.Request, w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", geojson.ContentType)
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println(fmt.Sprintf("request id: %s failed to write geojson response: %v",
+			middleware.GetReqID(r.Context()), err.Error()))
+	}
 }
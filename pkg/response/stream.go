@@ -0,0 +1,113 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const (
+	// streamFlushSize is the number of items written between flushes
+	streamFlushSize = 100
+)
+
+var (
+	ErrNotStreamable = errors.New("response format cannot be streamed")
+)
+
+// Stream writes a list body one item at a time, as a JSON array or as newline
+// delimited JSON, flushing the items written so far every streamFlushSize
+// items. The status and headers are sent with the first item, until then a
+// failure can still be sent as an error response.
+type Stream struct {
+	r       *http.Request
+	w       http.ResponseWriter
+	code    int
+	format  string
+	started bool
+	written int
+}
+
+// NewStream starts a list body in the format negotiated with r, see Negotiate.
+// Formats which cannot be streamed return ErrNotStreamable, the body is then
+// sent whole with SendBody, and unsupported formats return ErrNotAcceptable.
+func NewStream(r *http.Request, w http.ResponseWriter, code int) (*Stream, error) {
+	format, err := Negotiate(r)
+	switch {
+	case err != nil:
+		return nil, err
+	case format != FormatJSON && format != FormatNDJSON:
+		return nil, ErrNotStreamable
+	}
+
+	w.Header().Add("Vary", "Accept")
+	return &Stream{r: r, w: w, code: code, format: format}, nil
+}
+
+// Started reports whether the status and headers were sent.
+func (s *Stream) Started() bool {
+	return s.started
+}
+
+// Write writes the next item. It fails once the client is gone, which ends
+// the request context, so callers stop reading items.
+func (s *Stream) Write(item interface{}) error {
+	if err := s.r.Context().Err(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case s.format == FormatNDJSON:
+		data = append(data, '\n')
+	case s.written == 0:
+		data = append([]byte{'['}, data...)
+	default:
+		data = append([]byte{','}, data...)
+	}
+	s.start()
+	if _, err := s.w.Write(data); err != nil {
+		return err
+	}
+
+	s.written++
+	if s.written%streamFlushSize == 0 {
+		s.flush()
+	}
+	return nil
+}
+
+// Close ends the body, an unterminated JSON array tells clients a stream
+// failed midway so Close is only called once every item is written.
+func (s *Stream) Close() error {
+	s.start()
+	if s.format == FormatJSON {
+		end := "]\n"
+		if s.written == 0 {
+			end = "[]\n"
+		}
+		if _, err := s.w.Write([]byte(end)); err != nil {
+			return err
+		}
+	}
+	s.flush()
+	return nil
+}
+
+func (s *Stream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", contentTypes[s.format])
+	s.w.WriteHeader(s.code)
+}
+
+func (s *Stream) flush() {
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,90 @@
+package response
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStream_Items_Body(t *testing.T) {
+	Convey("Test stream\n", t, func() {
+		for _, tc := range []struct {
+			query, contentType, body, empty string
+		}{
+			{query: "", contentType: "application/json", body: `[{"id":1},{"id":2}]` + "\n", empty: "[]\n"},
+			{query: "format=ndjson", contentType: "application/x-ndjson", body: `{"id":1}` + "\n" + `{"id":2}` + "\n"},
+		} {
+			Convey("Test stream '"+tc.query+"'\n", func() {
+				r, err := http.NewRequest("GET", "/passenger?"+tc.query, nil)
+				So(err, ShouldBeNil)
+
+				w := httptest.NewRecorder()
+				s, err := NewStream(r, w, http.StatusOK)
+				So(err, ShouldBeNil)
+				So(s.Started(), ShouldBeFalse)
+				So(s.Write(map[string]int{"id": 1}), ShouldBeNil)
+				So(s.Write(map[string]int{"id": 2}), ShouldBeNil)
+				So(s.Close(), ShouldBeNil)
+				So(w.Code, ShouldEqual, http.StatusOK)
+				So(w.Header().Get("Content-Type"), ShouldStartWith, tc.contentType)
+				So(w.Body.String(), ShouldEqual, tc.body)
+				So(w.Flushed, ShouldBeTrue)
+
+				w = httptest.NewRecorder()
+				s, err = NewStream(r, w, http.StatusOK)
+				So(err, ShouldBeNil)
+				So(s.Close(), ShouldBeNil)
+				So(w.Body.String(), ShouldEqual, tc.empty)
+			})
+		}
+	})
+	Convey("Test stream flush\n", t, func() {
+		r, err := http.NewRequest("GET", "/passenger", nil)
+		So(err, ShouldBeNil)
+
+		w := httptest.NewRecorder()
+		s, err := NewStream(r, w, http.StatusOK)
+		So(err, ShouldBeNil)
+		for i := 0; i < streamFlushSize; i++ {
+			So(s.Write(i), ShouldBeNil)
+		}
+		So(w.Flushed, ShouldBeTrue)
+		So(strings.Count(w.Body.String(), ","), ShouldEqual, streamFlushSize-1)
+	})
+}
+
+func TestStream_UnsupportedFormat_Error(t *testing.T) {
+	Convey("Test stream\n", t, func() {
+		for query, expected := range map[string]error{"format=csv": ErrNotStreamable, "format=xml": ErrNotStreamable,
+			"format=yaml": ErrNotAcceptable} {
+			r, err := http.NewRequest("GET", "/passenger?"+query, nil)
+			So(err, ShouldBeNil)
+
+			w := httptest.NewRecorder()
+			_, err = NewStream(r, w, http.StatusOK)
+			So(err, ShouldEqual, expected)
+			So(w.Header().Get("Vary"), ShouldBeEmpty)
+		}
+	})
+}
+
+func TestStream_ClientGone_Error(t *testing.T) {
+	Convey("Test stream\n", t, func() {
+		c, cancel := context.WithCancel(context.Background())
+		r, err := http.NewRequestWithContext(c, "GET", "/passenger", nil)
+		So(err, ShouldBeNil)
+
+		w := httptest.NewRecorder()
+		s, err := NewStream(r, w, http.StatusOK)
+		So(err, ShouldBeNil)
+		So(s.Write(1), ShouldBeNil)
+
+		cancel()
+		So(s.Write(2), ShouldEqual, context.Canceled)
+		So(w.Body.String(), ShouldEqual, "[1")
+	})
+}
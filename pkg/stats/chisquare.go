@@ -0,0 +1,131 @@
+package stats
+
+import (
+	"math"
+)
+
+const (
+	gammaEpsilon    = 1e-15
+	gammaIterations = 1000
+	// gammaTiny keeps the continued fraction away from division by zero
+	gammaTiny = 1e-300
+)
+
+// ChiSquare is Pearson's chi-square test of independence of a contingency
+// table, without continuity correction.
+type ChiSquare struct {
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom int     `json:"degrees-of-freedom"`
+	PValue           float64 `json:"p-value"`
+	CramersV         float64 `json:"cramers-v"`
+}
+
+// ChiSquareTest tests the independence of the rows and columns of counts.
+// Empty rows and columns are ignored, the test is undefined (nil) unless at
+// least two rows and two columns are left.
+func ChiSquareTest(counts [][]int) *ChiSquare {
+	var rowTotals, colTotals []float64
+	var total float64
+	for i, row := range counts {
+		rowTotals = append(rowTotals, 0)
+		for j, count := range row {
+			if j >= len(colTotals) {
+				colTotals = append(colTotals, 0)
+			}
+			rowTotals[i] += float64(count)
+			colTotals[j] += float64(count)
+			total += float64(count)
+		}
+	}
+
+	var rows, cols int
+	for _, t := range rowTotals {
+		if t > 0 {
+			rows++
+		}
+	}
+	for _, t := range colTotals {
+		if t > 0 {
+			cols++
+		}
+	}
+	if rows < 2 || cols < 2 {
+		return nil
+	}
+
+	var statistic float64
+	for i, row := range counts {
+		for j, t := range colTotals {
+			if rowTotals[i] == 0 || t == 0 {
+				continue
+			}
+			var observed float64
+			if j < len(row) {
+				observed = float64(row[j])
+			}
+			expected := rowTotals[i] * t / total
+			statistic += (observed - expected) * (observed - expected) / expected
+		}
+	}
+
+	df := (rows - 1) * (cols - 1)
+	return &ChiSquare{
+		Statistic:        statistic,
+		DegreesOfFreedom: df,
+		PValue:           ChiSquareSurvival(statistic, df),
+		CramersV:         math.Sqrt(statistic / (total * float64(min(rows, cols)-1))),
+	}
+}
+
+// ChiSquareSurvival is the probability of a chi-square distributed value with
+// df degrees of freedom exceeding x.
+func ChiSquareSurvival(x float64, df int) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return gammaQ(float64(df)/2, x/2)
+}
+
+// gammaQ is the regularized upper incomplete gamma function, evaluated by its
+// series below a+1 and by its continued fraction above.
+func gammaQ(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgamma)
+
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1; n < gammaIterations; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*gammaEpsilon {
+				break
+			}
+		}
+		return 1 - sum*prefix
+	}
+
+	// modified Lentz's method
+	b := x + 1 - a
+	c := 1 / gammaTiny
+	d := 1 / b
+	h := d
+	for i := 1; i < gammaIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < gammaTiny {
+			d = gammaTiny
+		}
+		c = b + an/c
+		if math.Abs(c) < gammaTiny {
+			c = gammaTiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < gammaEpsilon {
+			break
+		}
+	}
+	return prefix * h
+}
@@ -0,0 +1,48 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChiSquareSurvival_ClosedForms(t *testing.T) {
+	Convey("Test chi-square survival\n", t, func() {
+		for _, x := range []float64{0.01, 0.5, 1, 3.841459, 10, 50, 263.05} {
+			// df 1, 2 and 4 have closed forms
+			So(ChiSquareSurvival(x, 1), ShouldAlmostEqual, math.Erfc(math.Sqrt(x/2)), 1e-12+1e-9*math.Erfc(math.Sqrt(x/2)))
+			So(ChiSquareSurvival(x, 2), ShouldAlmostEqual, math.Exp(-x/2), 1e-12+1e-9*math.Exp(-x/2))
+			So(ChiSquareSurvival(x, 4), ShouldAlmostEqual, math.Exp(-x/2)*(1+x/2), 1e-12+1e-9*math.Exp(-x/2))
+		}
+		So(ChiSquareSurvival(0, 3), ShouldEqual, 1)
+	})
+}
+
+func TestChiSquareTest_Table_Statistics(t *testing.T) {
+	Convey("Test chi-square test\n", t, func() {
+		Convey("Titanic Sex By Survival", func() {
+			test := ChiSquareTest([][]int{{81, 233}, {468, 109}})
+			So(test, ShouldNotBeNil)
+			So(test.Statistic, ShouldAlmostEqual, 263.050574, 1e-6)
+			So(test.DegreesOfFreedom, ShouldEqual, 1)
+			So(test.PValue, ShouldAlmostEqual, 3.711748e-59, 1e-64)
+			So(test.CramersV, ShouldAlmostEqual, 0.543351, 1e-6)
+		})
+		Convey("Independent Table", func() {
+			test := ChiSquareTest([][]int{{10, 20, 30}, {20, 40, 60}})
+			So(test.Statistic, ShouldAlmostEqual, 0, 1e-12)
+			So(test.DegreesOfFreedom, ShouldEqual, 2)
+			So(test.PValue, ShouldAlmostEqual, 1, 1e-12)
+		})
+		Convey("Empty Rows And Columns Ignored", func() {
+			test := ChiSquareTest([][]int{{81, 0, 233}, {0, 0, 0}, {468, 0, 109}})
+			So(test.DegreesOfFreedom, ShouldEqual, 1)
+			So(test.Statistic, ShouldAlmostEqual, 263.050574, 1e-6)
+		})
+		Convey("Degenerate Table", func() {
+			So(ChiSquareTest([][]int{{1, 2}}), ShouldBeNil)
+			So(ChiSquareTest(nil), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Pearson is the Pearson correlation coefficient of x and y, pairs where
+// either value is NaN are skipped. It returns the number of complete pairs,
+// the coefficient is NaN when it is undefined, i.e. for fewer than two pairs
+// or a constant variable.
+func Pearson(x, y []float64) (float64, int) {
+	x, y = complete(x, y)
+	return pearson(x, y), len(x)
+}
+
+// Spearman is the Spearman rank correlation coefficient of x and y, the
+// Pearson coefficient of their ranks with ties given the average rank. Pairs
+// are skipped and undefined coefficients reported like for Pearson.
+func Spearman(x, y []float64) (float64, int) {
+	x, y = complete(x, y)
+	return pearson(Ranks(x), Ranks(y)), len(x)
+}
+
+// Ranks ranks values from 1, tied values get the average of their ranks.
+func Ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	ranks := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i + 1
+		for j < len(order) && values[order[j]] == values[order[i]] {
+			j++
+		}
+		// positions i to j-1 hold ranks i+1 to j
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			ranks[order[k]] = rank
+		}
+		i = j
+	}
+	return ranks
+}
+
+// complete drops the pairs with a NaN value.
+func complete(x, y []float64) ([]float64, []float64) {
+	cx, cy := make([]float64, 0, len(x)), make([]float64, 0, len(y))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+			continue
+		}
+		cx, cy = append(cx, x[i]), append(cy, y[i])
+	}
+	return cx, cy
+}
+
+func pearson(x, y []float64) float64 {
+	if len(x) < 2 {
+		return math.NaN()
+	}
+
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
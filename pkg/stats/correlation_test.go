@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRanks_Ties_AverageRanks(t *testing.T) {
+	Convey("Test ranks\n", t, func() {
+		So(Ranks([]float64{10, 30, 20, 20, 5}), ShouldResemble, []float64{2, 5, 3.5, 3.5, 1})
+		So(Ranks(nil), ShouldBeEmpty)
+	})
+}
+
+func TestCorrelation_Values_Coefficients(t *testing.T) {
+	Convey("Test correlation\n", t, func() {
+		Convey("Linear Relation", func() {
+			r, n := Pearson([]float64{1, 2, 3, 4}, []float64{8, 6, 4, 2})
+			So(r, ShouldAlmostEqual, -1, 1e-12)
+			So(n, ShouldEqual, 4)
+		})
+		Convey("Monotonic Relation", func() {
+			x, y := []float64{1, 2, 3, 4, 5}, []float64{1, 4, 9, 16, 100}
+			r, _ := Pearson(x, y)
+			So(r, ShouldAlmostEqual, 0.795204, 1e-6)
+			rho, _ := Spearman(x, y)
+			So(rho, ShouldAlmostEqual, 1, 1e-12)
+		})
+		Convey("Ties", func() {
+			// Pearson coefficient of the hand computed ranks 1, 2.5, 2.5, 4, 5 and 2, 1, 3.5, 3.5, 5
+			rho, _ := Spearman([]float64{1, 2, 2, 3, 4}, []float64{2, 1, 3, 3, 5})
+			So(rho, ShouldAlmostEqual, 0.763158, 1e-6)
+		})
+		Convey("Missing Values Skipped Pairwise", func() {
+			r, n := Pearson([]float64{1, math.NaN(), 3, 4, 2}, []float64{2, 5, 6, 8, math.NaN()})
+			So(n, ShouldEqual, 3)
+			expected, _ := Pearson([]float64{1, 3, 4}, []float64{2, 6, 8})
+			So(r, ShouldAlmostEqual, expected, 1e-12)
+		})
+		Convey("Undefined Coefficients", func() {
+			r, _ := Pearson([]float64{1, 2, 3}, []float64{5, 5, 5})
+			So(math.IsNaN(r), ShouldBeTrue)
+			r, n := Spearman([]float64{1}, []float64{2})
+			So(math.IsNaN(r), ShouldBeTrue)
+			So(n, ShouldEqual, 1)
+		})
+	})
+}
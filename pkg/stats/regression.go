@@ -0,0 +1,69 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+var (
+	ErrSingular = fmt.Errorf("regression predictors are linearly dependent")
+)
+
+// LinearRegression fits y = b0 + b1*x1 + ... + bp*xp by ordinary least
+// squares, solving the normal equations, and returns b0 to bp.
+func LinearRegression(x [][]float64, y []float64) ([]float64, error) {
+	if len(x) == 0 || len(x) != len(y) {
+		return nil, fmt.Errorf("regression needs one response per sample")
+	}
+
+	// xtx | xty is the augmented normal equations matrix, with the intercept first
+	p := len(x[0]) + 1
+	a := make([][]float64, p)
+	for i := range a {
+		a[i] = make([]float64, p+1)
+	}
+	row := make([]float64, p)
+	for i, sample := range x {
+		if len(sample) != p-1 {
+			return nil, fmt.Errorf("regression samples must have the same number of predictors")
+		}
+		row[0] = 1
+		copy(row[1:], sample)
+		for j := 0; j < p; j++ {
+			for k := 0; k < p; k++ {
+				a[j][k] += row[j] * row[k]
+			}
+			a[j][p] += row[j] * y[i]
+		}
+	}
+
+	// gaussian elimination with partial pivoting
+	for col := 0; col < p; col++ {
+		pivot := col
+		for r := col + 1; r < p; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-10 {
+			return nil, ErrSingular
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for r := col + 1; r < p; r++ {
+			factor := a[r][col] / a[col][col]
+			for k := col; k <= p; k++ {
+				a[r][k] -= factor * a[col][k]
+			}
+		}
+	}
+
+	b := make([]float64, p)
+	for r := p - 1; r >= 0; r-- {
+		v := a[r][p]
+		for k := r + 1; k < p; k++ {
+			v -= a[r][k] * b[k]
+		}
+		b[r] = v / a[r][r]
+	}
+	return b, nil
+}
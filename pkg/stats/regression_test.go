@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLinearRegression_Samples_Coefficients(t *testing.T) {
+	Convey("Test linear regression\n", t, func() {
+		Convey("Exact Relation", func() {
+			x := [][]float64{{0, 1}, {1, 0}, {2, 1}, {3, 5}, {4, 2}}
+			var y []float64
+			for _, s := range x {
+				y = append(y, 1.5+2*s[0]-0.5*s[1])
+			}
+			b, err := LinearRegression(x, y)
+			So(err, ShouldBeNil)
+			So(b[0], ShouldAlmostEqual, 1.5, 1e-9)
+			So(b[1], ShouldAlmostEqual, 2, 1e-9)
+			So(b[2], ShouldAlmostEqual, -0.5, 1e-9)
+		})
+		Convey("Least Squares Line", func() {
+			// y = 0.6 + 1.1x minimises the squared residuals of these points
+			b, err := LinearRegression([][]float64{{0}, {1}, {2}, {3}}, []float64{1, 1, 3, 4})
+			So(err, ShouldBeNil)
+			So(b[0], ShouldAlmostEqual, 0.6, 1e-9)
+			So(b[1], ShouldAlmostEqual, 1.1, 1e-9)
+		})
+		Convey("Dependent Predictors", func() {
+			_, err := LinearRegression([][]float64{{1, 2}, {2, 4}, {3, 6}}, []float64{1, 2, 3})
+			So(err, ShouldEqual, ErrSingular)
+		})
+		Convey("Invalid Samples", func() {
+			_, err := LinearRegression(nil, nil)
+			So(err, ShouldNotBeNil)
+			_, err = LinearRegression([][]float64{{1}, {1, 2}}, []float64{1, 2})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Accumulator summarises a stream of values in a single pass. Moments are
+// updated with Welford's online algorithm, extended to the third and fourth
+// central moments, and values are kept for the order statistics.
+type Accumulator struct {
+	count, missing int
+	mean           float64
+	// m2, m3 and m4 are the sums of the 2nd, 3rd and 4th powers of the deviations from mean
+	m2, m3, m4 float64
+	min, max   float64
+	values     []float64
+}
+
+// Summary describes a sample. Statistics which are undefined for the sample
+// size, e.g. the std of a single value, are nil.
+type Summary struct {
+	Count    int      `json:"count"`
+	Missing  int      `json:"missing"`
+	Mean     *float64 `json:"mean"`
+	Std      *float64 `json:"std"`
+	Min      *float64 `json:"min"`
+	Q1       *float64 `json:"q1"`
+	Median   *float64 `json:"median"`
+	Q3       *float64 `json:"q3"`
+	Max      *float64 `json:"max"`
+	IQR      *float64 `json:"iqr"`
+	Skew     *float64 `json:"skew"`
+	Kurtosis *float64 `json:"kurtosis"`
+}
+
+// Add adds a value, NaN is counted as missing.
+func (a *Accumulator) Add(v float64) {
+	if math.IsNaN(v) {
+		a.missing++
+		return
+	}
+
+	n1 := float64(a.count)
+	a.count++
+	n := float64(a.count)
+	delta := v - a.mean
+	deltaN := delta / n
+	deltaN2 := deltaN * deltaN
+	term := delta * deltaN * n1
+
+	a.mean += deltaN
+	a.m4 += term*deltaN2*(n*n-3*n+3) + 6*deltaN2*a.m2 - 4*deltaN*a.m3
+	a.m3 += term*deltaN*(n-2) - 3*deltaN*a.m2
+	a.m2 += term
+
+	if a.count == 1 || v < a.min {
+		a.min = v
+	}
+	if a.count == 1 || v > a.max {
+		a.max = v
+	}
+	a.values = append(a.values, v)
+}
+
+// AddMissing counts a missing value.
+func (a *Accumulator) AddMissing() {
+	a.missing++
+}
+
+// Summary computes the summary of the values added so far. Std is the sample
+// standard deviation, skew and kurtosis are the bias adjusted sample skewness
+// and excess kurtosis. Quartiles are linearly interpolated.
+func (a *Accumulator) Summary() *Summary {
+	s := &Summary{Count: a.count, Missing: a.missing}
+	if a.count == 0 {
+		return s
+	}
+
+	sorted := make([]float64, len(a.values))
+	copy(sorted, a.values)
+	sort.Float64s(sorted)
+
+	q1, q3 := Quantile(sorted, 0.25), Quantile(sorted, 0.75)
+	s.Mean = value(a.mean)
+	s.Min, s.Max = value(a.min), value(a.max)
+	s.Q1, s.Median, s.Q3 = value(q1), value(Quantile(sorted, 0.5)), value(q3)
+	s.IQR = value(q3 - q1)
+
+	n := float64(a.count)
+	if a.count > 1 {
+		s.Std = value(math.Sqrt(a.m2 / (n - 1)))
+	}
+	// a constant sample has no defined shape
+	if a.m2 == 0 {
+		return s
+	}
+	if a.count > 2 {
+		g1 := math.Sqrt(n) * a.m3 / math.Pow(a.m2, 1.5)
+		s.Skew = value(g1 * math.Sqrt(n*(n-1)) / (n - 2))
+	}
+	if a.count > 3 {
+		g2 := n*a.m4/(a.m2*a.m2) - 3
+		s.Kurtosis = value(((n+1)*g2 + 6) * (n - 1) / ((n - 2) * (n - 3)))
+	}
+	return s
+}
+
+// Quantile linearly interpolates the q quantile of sorted values.
+func Quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+func value(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAccumulator_Values_Summary(t *testing.T) {
+	var acc Accumulator
+	for _, v := range []float64{2, 8, 4, 4, 5, 5, 7, 9, 1, 30} {
+		acc.Add(v)
+	}
+	acc.AddMissing()
+	acc.Add(math.NaN())
+
+	// when
+	s := acc.Summary()
+
+	// then
+	Convey("Test summary\n", t, func() {
+		Convey("Counts As Expected", func() {
+			So(s.Count, ShouldEqual, 10)
+			So(s.Missing, ShouldEqual, 2)
+		})
+		Convey("Order Statistics As Expected", func() {
+			So(*s.Min, ShouldEqual, 1)
+			So(*s.Max, ShouldEqual, 30)
+			So(*s.Q1, ShouldEqual, 4)
+			So(*s.Median, ShouldEqual, 5)
+			So(*s.Q3, ShouldEqual, 7.75)
+			So(*s.IQR, ShouldEqual, 3.75)
+		})
+		Convey("Moments As Expected", func() {
+			// reference values computed with the two pass formulas
+			So(*s.Mean, ShouldAlmostEqual, 7.5, 1e-9)
+			So(*s.Std, ShouldAlmostEqual, 8.289887, 1e-6)
+			So(*s.Skew, ShouldAlmostEqual, 2.641744, 1e-6)
+			So(*s.Kurtosis, ShouldAlmostEqual, 7.647201, 1e-6)
+		})
+	})
+}
+
+func TestAccumulator_SmallSamples_UndefinedStatistics(t *testing.T) {
+	Convey("Test summary\n", t, func() {
+		Convey("Empty Sample", func() {
+			var acc Accumulator
+			acc.AddMissing()
+			s := acc.Summary()
+			So(s.Count, ShouldEqual, 0)
+			So(s.Missing, ShouldEqual, 1)
+			So(s.Mean, ShouldBeNil)
+			So(s.Median, ShouldBeNil)
+		})
+		Convey("Single Value", func() {
+			var acc Accumulator
+			acc.Add(3)
+			s := acc.Summary()
+			So(*s.Mean, ShouldEqual, 3)
+			So(*s.Median, ShouldEqual, 3)
+			So(s.Std, ShouldBeNil)
+			So(s.Skew, ShouldBeNil)
+		})
+		Convey("Constant Sample", func() {
+			var acc Accumulator
+			for i := 0; i < 5; i++ {
+				acc.Add(2)
+			}
+			s := acc.Summary()
+			So(*s.Std, ShouldEqual, 0)
+			So(s.Skew, ShouldBeNil)
+			So(s.Kurtosis, ShouldBeNil)
+		})
+	})
+}
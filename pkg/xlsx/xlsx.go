@@ -0,0 +1,246 @@
+// Package xlsx writes single sheet SpreadsheetML workbooks (.xlsx) a row at a
+// time, so large tables are streamed rather than held in memory.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of xlsx workbooks.
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+const (
+	// maxSheetName is the longest sheet name spreadsheet applications accept
+	maxSheetName = 31
+	// styleHeader is the cell style of the header row, see stylesXML
+	styleHeader = 1
+)
+
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+	relsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+	// stylesXML has the default cell style and the bold header style
+	stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`
+	workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	// sheetStartXML freezes the header row so it stays visible while scrolling
+	sheetStartXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0">` +
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+		`<selection pane="bottomLeft" activeCell="A2" sqref="A2"/>` +
+		`</sheetView></sheetViews><sheetData>`
+	sheetEndXML = `</sheetData></worksheet>`
+)
+
+// Writer writes a workbook with a single sheet, the header row first. The
+// sheet is the last part of the package so rows are written as they come.
+type Writer struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	// deflate compresses the current part, flushed with the sheet rows
+	deflate *flate.Writer
+	rows    int
+}
+
+// NewWriter starts a workbook on w with a sheet of the given name and header.
+func NewWriter(w io.Writer, name string, header []string) (*Writer, error) {
+	xw := &Writer{zip: zip.NewWriter(w)}
+	xw.zip.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		var err error
+		xw.deflate, err = flate.NewWriter(out, flate.DefaultCompression)
+		return xw.deflate, err
+	})
+
+	parts := []struct{ name, content string }{
+		{name: "[Content_Types].xml", content: contentTypesXML},
+		{name: "_rels/.rels", content: relsXML},
+		{name: "xl/workbook.xml", content: fmt.Sprintf(workbookXML, escape(sheetName(name)))},
+		{name: "xl/_rels/workbook.xml.rels", content: workbookRelsXML},
+		{name: "xl/styles.xml", content: stylesXML},
+	}
+	for _, part := range parts {
+		pw, err := xw.zip.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	pw, err := xw.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw.sheet = bufio.NewWriter(pw)
+	if _, err = xw.sheet.WriteString(sheetStartXML); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(header))
+	for i, name := range header {
+		values[i] = name
+	}
+	if err = xw.writeRow(values, styleHeader); err != nil {
+		return nil, err
+	}
+	return xw, nil
+}
+
+// WriteRow writes a row of typed cells: numbers are number cells, booleans are
+// boolean cells, nil values are left out and other values are text.
+// Pointers are written as the value they point to.
+func (xw *Writer) WriteRow(values []interface{}) error {
+	return xw.writeRow(values, 0)
+}
+
+// Flush writes the rows buffered so far to the underlying writer.
+func (xw *Writer) Flush() error {
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	if err := xw.deflate.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Flush()
+}
+
+// Close ends the sheet and the workbook, it does not close the underlying writer.
+func (xw *Writer) Close() error {
+	if _, err := xw.sheet.WriteString(sheetEndXML); err != nil {
+		return err
+	}
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+func (xw *Writer) writeRow(values []interface{}, style int) error {
+	xw.rows++
+	var buf strings.Builder
+	fmt.Fprintf(&buf, `<row r="%d">`, xw.rows)
+	for i, value := range values {
+		ref := columnName(i) + strconv.Itoa(xw.rows)
+		styleAttr := ""
+		if style > 0 {
+			styleAttr = fmt.Sprintf(` s="%d"`, style)
+		}
+
+		switch value := cellValue(value).(type) {
+		case float64:
+			fmt.Fprintf(&buf, `<c r="%s"%s><v>%s</v></c>`, ref, styleAttr, strconv.FormatFloat(value, 'g', -1, 64))
+		case bool:
+			v := 0
+			if value {
+				v = 1
+			}
+			fmt.Fprintf(&buf, `<c r="%s"%s t="b"><v>%d</v></c>`, ref, styleAttr, v)
+		case string:
+			fmt.Fprintf(&buf, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr,
+				escape(value))
+		}
+	}
+	buf.WriteString(`</row>`)
+	_, err := xw.sheet.WriteString(buf.String())
+	return err
+}
+
+// cellValue converts value into a float64, a bool, a string or nil.
+func cellValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		// spreadsheets have no representation of NaN and infinities
+		if f := v.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// columnName is the letter name of a zero based column index, e.g. 27 is AB.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// sheetName removes the characters sheet names cannot hold and truncates name.
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > maxSheetName {
+		name = string(runes[:maxSheetName])
+	}
+	if len(name) == 0 {
+		return "Sheet1"
+	}
+	return name
+}
+
+func escape(s string) string {
+	var buf strings.Builder
+	// writing to a strings.Builder does not fail
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
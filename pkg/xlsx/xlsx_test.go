@@ -0,0 +1,91 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func readParts(data []byte) (map[string]string, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make(map[string]string)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		parts[f.Name] = string(content)
+	}
+	return parts, nil
+}
+
+func TestWriter_Rows_Workbook(t *testing.T) {
+	Convey("Test xlsx writer\n", t, func() {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, "passengers: 1912", []string{"id", "name", "age", "survived"})
+		So(err, ShouldBeNil)
+
+		age := 22.5
+		So(w.WriteRow([]interface{}{1, "Braund, Mr. Owen <Harris>", &age, true}), ShouldBeNil)
+		So(w.WriteRow([]interface{}{2, "Cumings", (*float64)(nil), false}), ShouldBeNil)
+		So(w.Flush(), ShouldBeNil)
+		So(w.Close(), ShouldBeNil)
+
+		parts, err := readParts(buf.Bytes())
+		So(err, ShouldBeNil)
+		So(parts, ShouldContainKey, "[Content_Types].xml")
+		So(parts, ShouldContainKey, "xl/styles.xml")
+
+		Convey("Parts Are Well Formed", func() {
+			for _, content := range parts {
+				dec := xml.NewDecoder(bytes.NewReader([]byte(content)))
+				var err error
+				for err == nil {
+					_, err = dec.Token()
+				}
+				So(err, ShouldEqual, io.EOF)
+			}
+		})
+		Convey("Sheet Name Is Valid", func() {
+			So(parts["xl/workbook.xml"], ShouldContainSubstring, `<sheet name="passengers 1912"`)
+		})
+		Convey("Header Is Bold And Frozen", func() {
+			sheet := parts["xl/worksheets/sheet1.xml"]
+			So(sheet, ShouldContainSubstring, `<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+			So(sheet, ShouldContainSubstring, `<row r="1"><c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+		})
+		Convey("Cells Are Typed", func() {
+			sheet := parts["xl/worksheets/sheet1.xml"]
+			So(sheet, ShouldContainSubstring, `<row r="2"><c r="A2"><v>1</v></c>`+
+				`<c r="B2" t="inlineStr"><is><t xml:space="preserve">Braund, Mr. Owen &lt;Harris&gt;</t></is></c>`+
+				`<c r="C2"><v>22.5</v></c><c r="D2" t="b"><v>1</v></c></row>`)
+			// missing values are left out
+			So(sheet, ShouldContainSubstring, `<c r="B3" t="inlineStr"><is><t xml:space="preserve">Cumings</t></is></c>`+
+				`<c r="D3" t="b"><v>0</v></c></row>`)
+		})
+	})
+}
+
+func TestColumnName_Index_Letters(t *testing.T) {
+	Convey("Test column name\n", t, func() {
+		So(columnName(0), ShouldEqual, "A")
+		So(columnName(25), ShouldEqual, "Z")
+		So(columnName(26), ShouldEqual, "AA")
+		So(columnName(27), ShouldEqual, "AB")
+		So(columnName(701), ShouldEqual, "ZZ")
+		So(columnName(702), ShouldEqual, "AAA")
+	})
+}